@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/config"
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/users"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/logger"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/store"
+
+	"github.com/urfave/cli/v2"
+)
+
+// newApp returns the command line of the service. Without a command it
+// serves, as the binary did before having commands.
+func newApp() *cli.App {
+	app := &cli.App{
+		Name:  "service",
+		Usage: "API de usuarios y herramientas para operar sus datos",
+		Flags: configFlags(),
+		// The errors are printed by main, with the exit code they carry.
+		ExitErrHandler: func(c *cli.Context, err error) {},
+		Action:         serve,
+		Commands: []*cli.Command{
+			{
+				Name:   "serve",
+				Usage:  "inicia el servidor HTTP",
+				Flags:  configFlags(),
+				Action: serve,
+			},
+			usersCommand(),
+			{
+				Name:      "import",
+				Usage:     "importa usuarios de un archivo CSV o NDJSON, con la validacion de POST /users/import",
+				ArgsUsage: "<archivo>",
+				Flags: append(configFlags(),
+					&cli.StringFlag{Name: "format", Usage: "csv o ndjson, por defecto segun la extension del archivo"},
+					&cli.StringFlag{Name: "mode", Value: "all_or_nothing", Usage: "all_or_nothing o skip_invalid"},
+					&cli.BoolFlag{Name: "upsert", Usage: "actualiza los usuarios cuyo email ya existe"},
+					&cli.StringFlag{Name: "mapping", Usage: `columnas del CSV a renombrar, como "First Name=nombre,Surname=apellido"`},
+				),
+				Action: importUsers,
+			},
+			{
+				Name:  "export",
+				Usage: "exporta los usuarios en CSV, NDJSON o JSON",
+				Flags: append(configFlags(),
+					&cli.StringFlag{Name: "format", Value: "ndjson", Usage: "csv, ndjson o json"},
+					&cli.StringFlag{Name: "fields", Usage: "campos a exportar separados por comas, todos por defecto"},
+					&cli.StringFlag{Name: "query", Usage: `filtro con los parametros de GET /users, como "nombre=Ana&created_after=2021-01-01"`},
+					&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "archivo de salida, la salida estandar por defecto"},
+				),
+				Action: exportUsers,
+			},
+			{
+				Name:  "migrate",
+				Usage: "migra los datos de los usuarios y, con --to-dir, copia los almacenamientos a otro backend",
+				Flags: append(configFlags(),
+					&cli.StringFlag{Name: "to-type", Value: string(store.FileType), Usage: "tipo del almacenamiento de destino"},
+					&cli.StringFlag{Name: "to-dir", Usage: "directorio del almacenamiento de destino"},
+					&cli.BoolFlag{Name: "force", Usage: "reemplaza los archivos que ya existan en el destino"},
+				),
+				Action: migrateStores,
+			},
+			{
+				Name:      "check",
+				Usage:     "revisa la integridad de un archivo de usuarios, el configurado por defecto, y con --fix lo corrige",
+				ArgsUsage: "[archivo]",
+				Flags: append(configFlags(),
+					&cli.StringFlag{Name: "format", Value: "text", Usage: "text o json"},
+					&cli.BoolFlag{Name: "fix", Usage: "corrige los problemas que tienen correccion, guardando antes una copia del archivo en storage.backups"},
+					&cli.BoolFlag{Name: "dry-run", Usage: "muestra los cambios de --fix sin escribir el archivo"},
+				),
+				Action: checkUsers,
+			},
+		},
+	}
+
+	return app
+}
+
+// exitCode prints err and returns the exit code it carries, 1 when it
+// carries none.
+func exitCode(err error, errWriter io.Writer) int {
+	if err == nil {
+		return 0
+	}
+
+	if message := err.Error(); message != "" {
+		fmt.Fprintln(errWriter, message)
+	}
+
+	var exitCoder cli.ExitCoder
+	if errors.As(err, &exitCoder) {
+		return exitCoder.ExitCode()
+	}
+
+	return 1
+}
+
+// configFlags are the flags of the configuration. Every command has them, so
+// they go before or after its name.
+func configFlags() (flags []cli.Flag) {
+	for _, flag := range config.Flags() {
+		flags = append(flags, &cli.StringFlag{Name: flag.Name, Usage: flag.Usage})
+	}
+
+	return flags
+}
+
+// configArgs returns the configuration flags set in the command line as
+// config.Load parses them. The ones set after the name of a command win over
+// the ones set before.
+func configArgs(c *cli.Context) (args []string) {
+	names := map[string]bool{}
+	for _, flag := range config.Flags() {
+		names[flag.Name] = true
+	}
+
+	lineage := c.Lineage()
+	for idx := len(lineage) - 1; idx >= 0; idx-- {
+		// The last context wraps the app and has no flags.
+		if lineage[idx].App == nil {
+			continue
+		}
+		for _, name := range lineage[idx].LocalFlagNames() {
+			if names[name] {
+				args = append(args, "--"+name+"="+lineage[idx].String(name))
+			}
+		}
+	}
+
+	return args
+}
+
+// serve runs the server until it is stopped.
+func serve(c *cli.Context) error {
+	code := run(configArgs(c))
+	if code != 0 {
+		return cli.Exit("", code)
+	}
+
+	return nil
+}
+
+// loadDataConfig loads the configuration of the commands that work on the
+// data without the server. Only the storage and log settings need to be
+// valid, so the data can be fixed on a box without the token.
+func loadDataConfig(c *cli.Context) (cfg config.Config, log *logger.Logger, err error) {
+	lookupEnv, _ := environment()
+	cfg, _, err = config.Load(configArgs(c), lookupEnv, c.App.ErrWriter)
+
+	var validation *config.ValidationError
+	if errors.As(err, &validation) {
+		err = validation.Only("storage.", "log.")
+	}
+	if err != nil {
+		return cfg, nil, cli.Exit(err, 2)
+	}
+
+	// The standard output is left for the result of the command.
+	return cfg, newLogger(cfg.Log, c.App.ErrWriter), nil
+}
+
+// openUsers returns the users service over the configured store.
+func openUsers(c *cli.Context) (service users.Service, err error) {
+	cfg, log, err := loadDataConfig(c)
+	if err != nil {
+		return nil, err
+	}
+
+	db := store.NewStorage(store.Type(cfg.Storage.Type), cfg.Storage.Users, log)
+
+	return users.CreateService(users.CreateRepository(db, log), log), nil
+}
+
+// openUsersToWrite is openUsers for the commands that change the users. It
+// holds the lock of the store until unlock is called.
+func openUsersToWrite(c *cli.Context) (service users.Service, unlock func() error, err error) {
+	cfg, log, err := loadDataConfig(c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	unlock, err = lockUsers(cfg.Storage.Users)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	db := store.NewStorage(store.Type(cfg.Storage.Type), cfg.Storage.Users, log)
+
+	return users.CreateService(users.CreateRepository(db, log), log), unlock, nil
+}
+
+// lockUsers takes the lock of the users store kept in path, which a running
+// server holds, so a command does not overwrite what the server writes.
+func lockUsers(path string) (unlock func() error, err error) {
+	unlock, err = store.Lock(path)
+	if errors.Is(err, store.ErrLocked) {
+		return nil, fmt.Errorf("%w, detenga el servidor o espere a que termine el otro comando", err)
+	}
+
+	return unlock, err
+}
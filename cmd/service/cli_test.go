@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// runCLI runs the command line with args, returning what it wrote.
+func runCLI(t *testing.T, args ...string) (output string, err error) {
+	app := newApp()
+	out := &bytes.Buffer{}
+	app.Writer = out
+	app.ErrWriter = out
+
+	err = app.Run(append([]string{"service"}, args...))
+
+	return out.String(), err
+}
+
+// seedUsers copies the users of cmd/service to a directory of the test.
+func seedUsers(t *testing.T) (path string) {
+	data, err := os.ReadFile("users.json")
+	assert.Nil(t, err)
+
+	path = filepath.Join(t.TempDir(), "users.json")
+	assert.Nil(t, os.WriteFile(path, data, 0644))
+
+	return path
+}
+
+func TestUsersCommands(t *testing.T) {
+	storage := "--storage.users=" + seedUsers(t)
+
+	// Testea que las flags de la configuracion valgan antes y despues del comando
+	output, err := runCLI(t, storage, "users", "list", "--query", "nombre=user2")
+	assert.Nil(t, err, output)
+	assert.Contains(t, output, `"email": "user2@gmail.com"`)
+	assert.NotContains(t, output, "user1@gmail.com")
+
+	output, err = runCLI(t, "users", "get", "--format", "yaml", storage, "3")
+	assert.Nil(t, err, output)
+	assert.Contains(t, output, "email: user3@gmail.com")
+
+	// Testea que se cree, modifique y elimine un usuario con las reglas del servicio
+	output, err = runCLI(t, "users", "create", storage, "--data", `{"nombre":"Ana","apellido":"Diaz","email":"ana@example.com","altura":1.6,"activo":true,"fecha_de_nacimiento":"1990-01-01"}`)
+	assert.Nil(t, err, output)
+	assert.Contains(t, output, `"id": 11`)
+
+	_, err = runCLI(t, "users", "create", storage, "--data", `{"nombre":"Ana","apellido":"Diaz","email":"ana@example.com","altura":1.6}`)
+	assert.NotNil(t, err)
+
+	output, err = runCLI(t, "users", "update", storage, "--data", `{"apellido":"Ruiz"}`, "11")
+	assert.Nil(t, err, output)
+	assert.Contains(t, output, `"apellido": "Ruiz"`)
+
+	_, err = runCLI(t, "users", "update", storage, "--data", `{"id":12}`, "11")
+	assert.Contains(t, err.Error(), "id")
+
+	output, err = runCLI(t, "users", "delete", storage, "11")
+	assert.Nil(t, err, output)
+	_, err = runCLI(t, "users", "get", storage, "11")
+	assert.NotNil(t, err)
+
+	output, err = runCLI(t, "export", storage, "--format", "csv", "--fields", "id,email", "--query", "id=2")
+	assert.Nil(t, err, output)
+	assert.Equal(t, "id,email\n2,user2@gmail.com\n", output)
+
+	// Testea que una configuracion invalida termine con el codigo 2
+	_, err = runCLI(t, "users", "list", "--storage.users=/no/existe/users.json")
+	assert.Equal(t, 2, exitCode(err, &bytes.Buffer{}))
+}
+
+func TestCheckCommand(t *testing.T) {
+	path := seedUsers(t)
+
+	output, err := runCLI(t, "check", path)
+	assert.Nil(t, err, output)
+	assert.Contains(t, output, "usuarios: 10, con problemas: 0")
+
+	// Testea que se informen los ids repetidos y los campos requeridos vacios
+	data, _ := os.ReadFile(path)
+	broken := strings.Replace(string(data), `"id": 2,`, `"id": 1,`, 1)
+	broken = strings.Replace(broken, `"nombre": "user3"`, `"nombre": ""`, 1)
+	assert.Nil(t, os.WriteFile(path, []byte(broken), 0644))
+
+	output, err = runCLI(t, "check", path)
+	assert.NotNil(t, err)
+	assert.Contains(t, output, "usuario 1 (posicion 1): el id esta repetido")
+	assert.Contains(t, output, "usuario 3 (posicion 2): el usuario no es valido, el nombre es requerido")
+	assert.Equal(t, 1, exitCode(err, &bytes.Buffer{}))
+
+	// Testea que --dry-run muestre los cambios sin escribir el archivo
+	output, err = runCLI(t, "check", "--dry-run", path)
+	assert.NotNil(t, err)
+	assert.Contains(t, output, "~ usuario 1 (posicion 1) id: 1 -> 11")
+	written, _ := os.ReadFile(path)
+	assert.Equal(t, broken, string(written))
+
+	// Testea que --fix guarde una copia, corrija el archivo e informe en JSON
+	backups := filepath.Join(t.TempDir(), "backups")
+	output, err = runCLI(t, "check", "--fix", "--format", "json", "--storage.backups="+backups, path)
+	assert.NotNil(t, err)
+	var result checkResult
+	assert.Nil(t, json.Unmarshal([]byte(output), &result), output)
+	assert.True(t, result.Written)
+	backup, _ := os.ReadFile(result.Backup)
+	assert.Equal(t, broken, string(backup))
+	assert.Equal(t, backups, filepath.Dir(result.Backup))
+
+	output, _ = runCLI(t, "check", path)
+	assert.NotContains(t, output, "el id esta repetido")
+	assert.Contains(t, output, "usuarios: 10, con problemas: 1, avisos: 0")
+}
@@ -0,0 +1,632 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/cmd/service/handler"
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/config"
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/users"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/jsonpatch"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/logger"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/store"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/web"
+
+	"github.com/gin-gonic/gin"
+	"github.com/urfave/cli/v2"
+)
+
+// outputFormats are the formats of the users commands, by the name of their
+// --format flag.
+var outputFormats = map[string]string{
+	"json": web.MIMEJSON,
+	"yaml": web.MIMEYAML,
+	"xml":  web.MIMEXML,
+	"csv":  web.MIMECSV,
+}
+
+func usersCommand() *cli.Command {
+	formatFlag := func() cli.Flag {
+		return &cli.StringFlag{Name: "format", Value: "json", Usage: "json, yaml o xml, y csv para list"}
+	}
+	dataFlag := func(usage string) cli.Flag {
+		return &cli.StringFlag{Name: "data", Aliases: []string{"d"}, Usage: usage + ", - o vacio para leerlo de la entrada estandar"}
+	}
+
+	return &cli.Command{
+		Name:  "users",
+		Usage: "consulta y modifica los usuarios del almacenamiento configurado, sin pasar por el servidor",
+		Flags: configFlags(),
+		Subcommands: []*cli.Command{
+			{
+				Name:  "list",
+				Usage: "lista los usuarios",
+				Flags: append(configFlags(), formatFlag(),
+					&cli.StringFlag{Name: "query", Usage: `filtro con los parametros de GET /users, como "nombre=Ana&created_after=2021-01-01"`},
+				),
+				Action: listUsers,
+			},
+			{
+				Name:      "get",
+				Usage:     "muestra un usuario",
+				ArgsUsage: "<id>",
+				Flags:     append(configFlags(), formatFlag()),
+				Action:    getUser,
+			},
+			{
+				Name:   "create",
+				Usage:  "crea un usuario con los campos de POST /users",
+				Flags:  append(configFlags(), formatFlag(), dataFlag("usuario en JSON")),
+				Action: createUser,
+			},
+			{
+				Name:      "update",
+				Usage:     "modifica un usuario con un JSON merge patch, como PATCH /users/:id",
+				ArgsUsage: "<id>",
+				Flags:     append(configFlags(), formatFlag(), dataFlag("merge patch en JSON")),
+				Action:    updateUser,
+			},
+			{
+				Name:      "delete",
+				Usage:     "elimina un usuario",
+				ArgsUsage: "<id>",
+				Flags:     configFlags(),
+				Action:    deleteUser,
+			},
+		},
+	}
+}
+
+func listUsers(c *cli.Context) error {
+	service, err := openUsers(c)
+	if err != nil {
+		return err
+	}
+
+	filter, err := queryFilter(c.String("query"))
+	if err != nil {
+		return err
+	}
+
+	found, err := service.Filter(c.Context, filter)
+	if err != nil {
+		return err
+	}
+
+	return printValue(c, found)
+}
+
+func getUser(c *cli.Context) error {
+	id, err := idArg(c)
+	if err != nil {
+		return err
+	}
+
+	service, err := openUsers(c)
+	if err != nil {
+		return err
+	}
+
+	user, err := service.GetUserByID(c.Context, id)
+	if err != nil {
+		return err
+	}
+
+	return printValue(c, user)
+}
+
+func createUser(c *cli.Context) error {
+	data, err := readData(c)
+	if err != nil {
+		return err
+	}
+
+	var user users.User
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&user)
+	if err != nil {
+		return fmt.Errorf("%w, el JSON no es un usuario: %v", users.ErrInvalidUser, err)
+	}
+	err = users.ValidateUser(user)
+	if err != nil {
+		return err
+	}
+
+	service, unlock, err := openUsersToWrite(c)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	user, err = service.Create(c.Context, user)
+	if err != nil {
+		return err
+	}
+
+	return printValue(c, user)
+}
+
+func updateUser(c *cli.Context) error {
+	id, err := idArg(c)
+	if err != nil {
+		return err
+	}
+
+	patch, err := readData(c)
+	if err != nil {
+		return err
+	}
+
+	service, unlock, err := openUsersToWrite(c)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	user, err := service.Patch(c.Context, id, func(user users.User) (users.User, error) {
+		err := handler.ApplyPatch(jsonpatch.MIMEMergePatch, patch, &user)
+		return user, err
+	})
+	if err != nil {
+		return err
+	}
+
+	return printValue(c, user)
+}
+
+func deleteUser(c *cli.Context) error {
+	id, err := idArg(c)
+	if err != nil {
+		return err
+	}
+
+	service, unlock, err := openUsersToWrite(c)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = service.DeleteUserByID(c.Context, id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.App.Writer, "usuario %d eliminado\n", id)
+
+	return nil
+}
+
+func importUsers(c *cli.Context) error {
+	file := c.Args().First()
+	if file == "" {
+		return fmt.Errorf("se requiere el archivo a importar")
+	}
+
+	options := users.ImportOptions{Upsert: c.Bool("upsert")}
+	switch mode := c.String("mode"); mode {
+	case "all_or_nothing":
+	case "skip_invalid":
+		options.SkipInvalid = true
+	default:
+		return fmt.Errorf("mode debe ser all_or_nothing o skip_invalid(recibido: %s)", mode)
+	}
+
+	mediaType, err := mediaTypeOf(file, c.String("format"))
+	if err != nil {
+		return err
+	}
+
+	headerMapping, err := web.ParseHeaderMapping(c.String("mapping"))
+	if err != nil {
+		return err
+	}
+
+	input, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	rows, err := users.ReadImportRows(mediaType, input, headerMapping)
+	if err != nil {
+		return err
+	}
+
+	service, unlock, err := openUsersToWrite(c)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	report, err := service.Import(c.Context, rows, options)
+	if err != nil {
+		return err
+	}
+
+	out := c.App.Writer
+	for _, row := range report.Accepted {
+		fmt.Fprintf(out, "linea %d: %s id %d\n", row.Line, row.Action, row.ID)
+	}
+	for _, row := range report.Errors {
+		fmt.Fprintf(out, "linea %d: error: %v\n", row.Line, row.Err)
+	}
+	fmt.Fprintf(out, "filas: %d, aceptadas: %d, con errores: %d\n", report.Total, len(report.Accepted), len(report.Errors))
+
+	if !report.Applied {
+		return fmt.Errorf("no se importo ningun usuario")
+	}
+
+	return nil
+}
+
+func mediaTypeOf(file string, format string) (mediaType string, err error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(file)), ".")
+	}
+
+	switch format {
+	case "csv":
+		return web.MIMECSV, nil
+	case "ndjson", "jsonl":
+		return web.MIMENDJSON, nil
+	}
+
+	return mediaType, fmt.Errorf("format debe ser csv o ndjson(recibido: %s)", format)
+}
+
+func exportUsers(c *cli.Context) (err error) {
+	mediaType, found := web.StreamFormats[c.String("format")]
+	if !found {
+		return fmt.Errorf("format debe ser csv, ndjson o json(recibido: %s)", c.String("format"))
+	}
+
+	filter, err := queryFilter(c.String("query"))
+	if err != nil {
+		return err
+	}
+
+	service, err := openUsers(c)
+	if err != nil {
+		return err
+	}
+
+	out := c.App.Writer
+	if path := c.String("output"); path != "" {
+		file, createErr := os.Create(path)
+		if createErr != nil {
+			return createErr
+		}
+		defer func() {
+			closeErr := file.Close()
+			if err == nil {
+				err = closeErr
+			}
+		}()
+		out = file
+	}
+
+	encoder, err := web.NewRowEncoder(out, mediaType, reflect.TypeOf(users.User{}), web.ParseFields(c.String("fields")))
+	if err != nil {
+		return err
+	}
+
+	err = service.Export(c.Context, filter, func(user users.User) error {
+		return encoder.Encode(user)
+	})
+	if err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
+
+// migrateStores runs the data migrations of the users. With --to-dir it
+// first copies every store, as is, into a storage of --to-type there, and
+// migrates the copy, leaving the configured stores untouched.
+func migrateStores(c *cli.Context) error {
+	cfg, log, err := loadDataConfig(c)
+	if err != nil {
+		return err
+	}
+
+	sourceType := store.Type(cfg.Storage.Type)
+	stores := []struct {
+		name string
+		path string
+	}{
+		{"users", cfg.Storage.Users}, {"idempotency", cfg.Storage.Idempotency},
+		{"jobs", cfg.Storage.Jobs}, {"erasures", cfg.Storage.Erasures},
+	}
+
+	// Without --to-dir the configured users are migrated in place.
+	if c.String("to-dir") == "" {
+		unlock, err := lockUsers(cfg.Storage.Users)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	usersDb := store.NewStorage(sourceType, cfg.Storage.Users, log)
+	if toDir := c.String("to-dir"); toDir != "" {
+		targetType := store.Type(c.String("to-type"))
+		if store.NewStorage(targetType, "", log) == nil {
+			return fmt.Errorf("el tipo de almacenamiento %s no existe, use %s", targetType, store.FileType)
+		}
+
+		for _, source := range stores {
+			target := filepath.Join(toDir, filepath.Base(source.path))
+			copied, err := copyStore(store.NewStorage(sourceType, source.path, log), store.NewStorage(targetType, target, log), source.path, target, c.Bool("force"))
+			if err != nil {
+				return fmt.Errorf("no se pudo copiar %s: %w", source.name, err)
+			}
+			if copied {
+				fmt.Fprintf(c.App.Writer, "%s: %s copiado a %s\n", source.name, source.path, target)
+			}
+			if source.name == "users" {
+				usersDb = store.NewStorage(targetType, target, log)
+			}
+		}
+	}
+
+	migrated, unparsed, err := users.MigrateTimestamps(usersDb)
+	if err != nil {
+		return fmt.Errorf("no se pudieron migrar los timestamps: %w", err)
+	}
+	fmt.Fprintf(c.App.Writer, "timestamps migrados: %d\n", migrated)
+	if len(unparsed) > 0 {
+		fmt.Fprintf(c.App.Writer, "ids con fecha_de_creacion invalida: %v\n", unparsed)
+	}
+
+	migrated, err = users.MigrateBirthDates(usersDb, users.SystemClock.Now())
+	if err != nil {
+		return fmt.Errorf("no se pudieron migrar las fechas de nacimiento: %w", err)
+	}
+	fmt.Fprintf(c.App.Writer, "fechas de nacimiento derivadas de la edad: %d\n", migrated)
+
+	return nil
+}
+
+// copyStore writes the document of source to target. A source that does not
+// exist yet is skipped, and an existing target is only replaced with force.
+func copyStore(source store.Store, target store.Store, sourcePath string, targetPath string, force bool) (copied bool, err error) {
+	if _, err = os.Stat(sourcePath); errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if _, err = os.Stat(targetPath); err == nil && !force {
+		return false, fmt.Errorf("%s ya existe, use --force para reemplazarlo", targetPath)
+	}
+
+	var document interface{}
+	err = source.Read(&document)
+	if err != nil {
+		return false, err
+	}
+
+	err = target.Write(document)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// checkResult is the report of check as --format json writes it.
+type checkResult struct {
+	users.FsckReport
+	Backup  string `json:"backup,omitempty"`
+	Written bool   `json:"written"`
+}
+
+// checkUsers checks a users file with users.Fsck. With --fix the fixable
+// issues are repaired, after copying the file to a backup, and with
+// --dry-run the changes are only shown.
+func checkUsers(c *cli.Context) error {
+	format := c.String("format")
+	if format != "text" && format != "json" {
+		return fmt.Errorf("format debe ser text o json(recibido: %s)", format)
+	}
+
+	// The configuration is loaded even for a file given as argument, since it
+	// says where the backups go.
+	cfg, log, err := loadDataConfig(c)
+	if err != nil {
+		return err
+	}
+	path := c.Args().First()
+	if path == "" {
+		path = cfg.Storage.Users
+	}
+
+	if c.Bool("fix") && !c.Bool("dry-run") {
+		unlock, err := lockUsers(path)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	now := users.SystemClock.Now()
+	report, fixed := users.Fsck(data, now)
+	result := checkResult{FsckReport: report}
+	fix := c.Bool("fix") || c.Bool("dry-run")
+
+	if c.Bool("fix") && !c.Bool("dry-run") && fixed != nil {
+		result.Backup, err = store.Backup(cfg.Storage.Backups, path, data, now)
+		if err != nil {
+			return fmt.Errorf("no se pudo crear la copia de seguridad: %w", err)
+		}
+		pruneBackups(cfg.Storage, path, now, log)
+		err = store.NewStorage(store.FileType, path, log).Write(fixed)
+		if err != nil {
+			return fmt.Errorf("no se pudo escribir %s, el original esta en %s: %w", path, result.Backup, err)
+		}
+		result.Written = true
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(c.App.Writer)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+		if err != nil {
+			return err
+		}
+	} else {
+		printCheck(c.App.Writer, result, fix, fixed != nil)
+	}
+
+	if !report.Failed() {
+		return nil
+	}
+	if result.Written {
+		written, _ := json.Marshal(fixed)
+		remaining, _ := users.Fsck(written, now)
+		if !remaining.Failed() {
+			return nil
+		}
+		return fmt.Errorf("%s tiene %d usuarios con problemas que no se pueden corregir", path, remaining.UsersWithErrors)
+	}
+
+	return fmt.Errorf("%s tiene %d usuarios con problemas", path, report.UsersWithErrors)
+}
+
+// pruneBackups removes the backups of the store kept in path older than the
+// retention of storage. A failure is only logged, the backups are pruned
+// again on the next run.
+func pruneBackups(storage config.Storage, path string, now time.Time, log *logger.Logger) {
+	removed, err := store.PruneBackups(storage.Backups, path, now.Add(-storage.BackupRetention))
+	if err != nil {
+		log.Warn("no se pudieron borrar las copias de seguridad vencidas", logger.F("dir", storage.Backups), logger.Err(err))
+	}
+	if len(removed) > 0 {
+		log.Info("copias de seguridad vencidas borradas", logger.F("backups", removed))
+	}
+}
+
+// printCheck writes the report of check as text and, with fix, the changes
+// in the format of a diff.
+func printCheck(w io.Writer, result checkResult, fix bool, fixable bool) {
+	for _, issue := range result.Issues {
+		where := "documento"
+		if issue.Position >= 0 {
+			where = fmt.Sprintf("usuario %d (posicion %d)", issue.Id, issue.Position)
+		}
+		message := issue.Message
+		if issue.Severity == users.SeverityWarning {
+			message = "aviso, " + message
+		}
+		if issue.Fixable {
+			message += " [--fix lo corrige]"
+		}
+		fmt.Fprintf(w, "%s: %s\n", where, message)
+	}
+	fmt.Fprintf(w, "usuarios: %d, con problemas: %d, avisos: %d\n", result.Users, result.UsersWithErrors, result.Warnings)
+
+	if !fix {
+		return
+	}
+	if !fixable {
+		fmt.Fprintln(w, "no hay nada que corregir, o algun problema sin correccion impide escribir el archivo")
+		return
+	}
+	for _, change := range result.Changes {
+		where := "documento"
+		if change.Position >= 0 {
+			where = fmt.Sprintf("usuario %d (posicion %d)", change.Id, change.Position)
+		}
+		old, _ := json.Marshal(change.Old)
+		if change.New == nil {
+			fmt.Fprintf(w, "- %s %s: %s\n", where, change.Field, old)
+			continue
+		}
+		updated, _ := json.Marshal(change.New)
+		if change.Old == nil {
+			fmt.Fprintf(w, "+ %s %s: %s\n", where, change.Field, updated)
+			continue
+		}
+		fmt.Fprintf(w, "~ %s %s: %s -> %s\n", where, change.Field, old, updated)
+	}
+	if result.Written {
+		fmt.Fprintf(w, "cambios aplicados: %d, copia de seguridad en %s\n", len(result.Changes), result.Backup)
+	} else {
+		fmt.Fprintf(w, "cambios a aplicar: %d, no se escribio el archivo (--dry-run)\n", len(result.Changes))
+	}
+}
+
+// idArg parses the id given as the argument of the command.
+func idArg(c *cli.Context) (id int64, err error) {
+	value := c.Args().First()
+	id, err = strconv.ParseInt(value, 10, 64)
+	if err != nil || id <= 0 {
+		return id, fmt.Errorf("id debe ser un entero mayor a cero(recibido: %s)", value)
+	}
+
+	return id, nil
+}
+
+// readData returns the --data flag, or the standard input when it is - or
+// empty.
+func readData(c *cli.Context) (data []byte, err error) {
+	data = []byte(c.String("data"))
+	if len(data) == 0 || string(data) == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	}
+
+	return data, err
+}
+
+// queryFilter builds the filter of query, written as the query params of
+// GET /users, with the same validation.
+func queryFilter(query string) (filter users.Filter, err error) {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return filter, fmt.Errorf("query no es valido: %w", err)
+	}
+
+	c := &gin.Context{Request: &http.Request{URL: &url.URL{RawQuery: values.Encode()}}}
+	err = handler.CheckQueryParams(c)
+	if err != nil {
+		return filter, err
+	}
+
+	return users.FilterFromUrlParams(c)
+}
+
+// printValue writes v in the format of the --format flag.
+func printValue(c *cli.Context, v interface{}) (err error) {
+	format, found := outputFormats[c.String("format")]
+	if !found {
+		return fmt.Errorf("format debe ser json, yaml, xml o csv(recibido: %s)", c.String("format"))
+	}
+
+	var data []byte
+	if format == web.MIMEJSON {
+		data, err = json.MarshalIndent(v, "", "  ")
+	} else {
+		data, err = web.Marshal(format, v)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(c.App.Writer, strings.TrimRight(string(data), "\n"))
+
+	return err
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConfigView returns the effective configuration, secrets redacted, and
+// where each setting came from.
+type ConfigView func() (effective map[string]interface{}, sources map[string]string)
+
+// AdminConfig shows the configuration the service is running with, so an
+// operator can tell which layer set each value. Its route requires the token.
+func AdminConfig(view ConfigView) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		effective, sources := view()
+		c.JSON(http.StatusOK, gin.H{"config": effective, "sources": sources})
+	}
+}
+
+// ConfigReload reads the configuration again and applies the settings that
+// can change while serving, returning every setting that changed.
+type ConfigReload func() (changes []config.Change, err error)
+
+// AdminReload reloads the configuration, as SIGHUP does. An invalid
+// configuration is answered with 422 and the service keeps the one it had.
+// restart_required tells that some change waits for a restart.
+func AdminReload(reload ConfigReload) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		changes, err := reload()
+		if err != nil {
+			RespondError(c, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+
+		restartRequired := false
+		for _, change := range changes {
+			if !change.Reloadable {
+				restartRequired = true
+			}
+		}
+		if changes == nil {
+			changes = []config.Change{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"changes": changes, "restart_required": restartRequired})
+	}
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// accessPolicy is how CheckAccessToken authenticates the requests.
+type accessPolicy struct {
+	required bool
+	token    string
+}
+
+// access holds the accessPolicy set by UseAuth. Until then the token is
+// required and none is accepted.
+var access atomic.Value
+
+// UseAuth sets how the requests are authenticated: with required the token
+// header must be token, without it every request is let through. It may be
+// called while serving.
+func UseAuth(required bool, token string) {
+	access.Store(accessPolicy{required: required, token: token})
+}
+
+func currentAccess() accessPolicy {
+	policy, ok := access.Load().(accessPolicy)
+	if !ok {
+		return accessPolicy{required: true}
+	}
+
+	return policy
+}
+
+func CheckAccessToken(c *gin.Context) (err error) {
+	policy := currentAccess()
+	if !policy.required {
+		return nil
+	}
+
+	token := c.GetHeader("token")
+	if token == "" {
+		err = errors.New("el token de acceso no fue proporcionado")
+		c.Set(authFailureKey, "missing")
+	} else if policy.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(policy.token)) != 1 {
+		err = errors.New("el token enviado no es correcto")
+		c.Set(authFailureKey, "invalid")
+	}
+
+	return err
+}
+
+// isAccessToken tells whether token is the access token, set or not the
+// auth mode.
+func isAccessToken(token string) bool {
+	policy := currentAccess()
+
+	return policy.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(policy.token)) == 1
+}
+
+// RequireAccessToken rejects with 403 the requests that CheckAccessToken
+// does not accept, for the routes without a controller of their own.
+func RequireAccessToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := CheckAccessToken(c)
+		if err != nil {
+			RespondError(c, http.StatusForbidden, err.Error())
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/users"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/web"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaxBatchOperations caps the operations of a single batch request.
+const MaxBatchOperations = 10000
+
+// BatchMediaTypes are the body formats of batch requests, the ones that can
+// hold a list of operations.
+var BatchMediaTypes = []string{web.MIMEJSON, web.MIMEYAML, web.MIMEMsgPack}
+
+// BatchOperation is an item of the body of POST /users/batch. The user is
+// validated by the service for each operation, deletes don't send it.
+type BatchOperation struct {
+	Op   string     `json:"op" yaml:"op"`
+	ID   int64      `json:"id" yaml:"id"`
+	User users.User `json:"user" yaml:"user" binding:"-"`
+}
+
+// BindBatch reads the atomic query param and the operations of a batch
+// request into the slice pointed by operations. It responds with the error
+// and returns false when they are invalid.
+func BindBatch(c *gin.Context, operations interface{}) (atomic bool, ok bool) {
+	if !CheckContentType(c, BatchMediaTypes...) {
+		return false, false
+	}
+
+	if value := c.Query("atomic"); value != "" {
+		var err error
+		atomic, err = strconv.ParseBool(value)
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, fmt.Sprintf("atomic debe ser true o false(recibido: %s)", value))
+			return false, false
+		}
+	}
+
+	err := c.ShouldBindBodyWith(operations, web.BodyBinding(c.ContentType()))
+	if err != nil {
+		RespondBindingError(c, err)
+		return false, false
+	}
+
+	count := reflect.ValueOf(operations).Elem().Len()
+	switch {
+	case count == 0:
+		RespondError(c, validationStatus(c), "el lote debe tener al menos una operacion")
+		return false, false
+	case count > MaxBatchOperations:
+		RespondError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("el lote no puede tener mas de %d operaciones", MaxBatchOperations))
+		return false, false
+	}
+
+	return atomic, true
+}
+
+// NewBatchResult converts the result of users.Service.Batch, data is the
+// user in the representation of the API version.
+func NewBatchResult(c *gin.Context, result users.BatchResult, data interface{}) web.BatchResult {
+	batchResult := web.BatchResult{Index: result.Index, Op: result.Op, ID: result.ID}
+
+	switch {
+	case errors.Is(result.Err, users.ErrBatchRolledBack):
+		batchResult.Status = http.StatusBadRequest
+		if RequestedVersion(c) >= 2 {
+			batchResult.Status = http.StatusFailedDependency
+		}
+		batchResult.Error = result.Err.Error()
+	case result.Err != nil:
+		batchResult.Status = ErrorStatus(c, result.Err)
+		batchResult.Error = result.Err.Error()
+	case RequestedVersion(c) < 2:
+		batchResult.Status = http.StatusOK
+		batchResult.Data = data
+	case result.Op == users.BatchCreate:
+		batchResult.Status = http.StatusCreated
+		batchResult.Data = data
+	case result.Op == users.BatchDelete:
+		batchResult.Status = http.StatusNoContent
+	default:
+		batchResult.Status = http.StatusOK
+		batchResult.Data = data
+	}
+
+	return batchResult
+}
+
+// RespondBatch answers 200 when every operation was applied. Version 2
+// answers 207 when some of them failed, version 1 always answers 200.
+func RespondBatch(c *gin.Context, results []web.BatchResult) {
+	statusCode := http.StatusOK
+	for _, result := range results {
+		if result.Error != "" && RequestedVersion(c) >= 2 {
+			statusCode = http.StatusMultiStatus
+		}
+	}
+
+	Respond(c, statusCode, web.BatchResults{Results: results})
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/users"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/web"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConfirmCountHeader carries the number of users the client expects a bulk
+// change to touch, usually taken from a previous dry run.
+const ConfirmCountHeader = "X-Confirm-Count"
+
+// BulkOptions reads the dry_run query param and the confirmation header of
+// the updates and deletes by filter. It responds with the error and returns
+// false when they are invalid.
+func BulkOptions(c *gin.Context) (options users.BulkOptions, ok bool) {
+	if value := c.Query("dry_run"); value != "" {
+		dryRun, err := strconv.ParseBool(value)
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, fmt.Sprintf("dry_run debe ser true o false(recibido: %s)", value))
+			return options, false
+		}
+		options.DryRun = dryRun
+	}
+
+	if options.DryRun {
+		return options, true
+	}
+
+	value := c.GetHeader(ConfirmCountHeader)
+	if value == "" {
+		err := fmt.Errorf("%w, envie el header %s con la cantidad de usuarios esperada", users.ErrConfirmationNeeded, ConfirmCountHeader)
+		RespondError(c, ErrorStatus(c, err), err.Error())
+		return options, false
+	}
+
+	confirmCount, err := strconv.Atoi(value)
+	if err != nil || confirmCount < 0 {
+		RespondError(c, http.StatusBadRequest, fmt.Sprintf("%s debe ser un entero mayor o igual a cero(recibido: %s)", ConfirmCountHeader, value))
+		return options, false
+	}
+	options.ConfirmCount = confirmCount
+
+	return options, true
+}
+
+// NewBulkResult lists the matched ids and the fields changed on each user,
+// compared on the representation of the API version returned by represent.
+func NewBulkResult(changes []users.BulkChange, dryRun bool, represent func(user users.User) interface{}) (result web.BulkResult, err error) {
+	result = web.BulkResult{DryRun: dryRun, Matched: len(changes), IDs: []int64{}, Changes: []web.BulkChange{}}
+
+	for _, change := range changes {
+		result.IDs = append(result.IDs, change.Before.Id)
+
+		bulkChange := web.BulkChange{ID: change.Before.Id, Deleted: change.After == nil}
+		if change.After != nil {
+			bulkChange.Changes, err = DiffFields(represent(change.Before), represent(*change.After))
+			if err != nil {
+				return result, err
+			}
+		}
+
+		result.Changes = append(result.Changes, bulkChange)
+	}
+
+	return result, nil
+}
+
+// DiffFields compares the JSON fields of before and after, sorted by name.
+func DiffFields(before interface{}, after interface{}) (changes []web.FieldChange, err error) {
+	beforeFields, err := jsonFields(before)
+	if err != nil {
+		return changes, err
+	}
+
+	afterFields, err := jsonFields(after)
+	if err != nil {
+		return changes, err
+	}
+
+	for name, value := range afterFields {
+		if !reflect.DeepEqual(beforeFields[name], value) {
+			changes = append(changes, web.FieldChange{Field: name, From: beforeFields[name], To: value})
+		}
+	}
+	for name, value := range beforeFields {
+		if _, found := afterFields[name]; !found {
+			changes = append(changes, web.FieldChange{Field: name, From: value})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+
+	return changes, nil
+}
+
+func jsonFields(v interface{}) (fields map[string]interface{}, err error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fields, err
+	}
+
+	err = json.Unmarshal(data, &fields)
+
+	return fields, err
+}
@@ -4,10 +4,16 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"os"
+	"path"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/privacy"
 	"github.com/EdigiraldoML/go-web-arquitecture/internal/users"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/logger"
 	"github.com/EdigiraldoML/go-web-arquitecture/pkg/web"
 
 	"github.com/gin-gonic/gin"
@@ -15,11 +21,15 @@ import (
 
 type User struct {
 	service users.Service
+	privacy privacy.Service
+	logger  *logger.Logger
 }
 
-func CreateUser(u users.Service) *User {
+func CreateUser(u users.Service, p privacy.Service, log *logger.Logger) *User {
 	newUser := &User{
 		service: u,
+		privacy: p,
+		logger:  log,
 	}
 
 	return newUser
@@ -30,28 +40,30 @@ func CreateUser(u users.Service) *User {
 // @Tags Users
 // @Description List all users that are recorder in database
 // @Accept json
-// @Produce json
+// @Produce json,xml,application/yaml,text/csv,application/msgpack
 // @Param token header string true "token"
+// @Param X-API-Version header int false "API contract version, 1 (default) or 2"
 // @Success 200 {object} web.Response
 // @Failure 400 {object} web.Response
 // @Failure 403 {object} web.Response
+// @Failure 406 {object} web.Response
 // @Router /users/GetAll [get]
 func (u *User) GetAll() gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		err := CheckAccessToken(c)
 		if err != nil {
-			c.JSON(403, web.NewResponse(403, nil, err.Error()))
+			RespondError(c, 403, err.Error())
 			return
 		}
 
-		users, err := u.service.GetAll()
+		users, err := u.service.GetAll(c.Request.Context())
 		if err != nil {
-			c.JSON(400, web.NewResponse(400, nil, err.Error()))
+			RespondError(c, 400, err.Error())
 			return
 		}
 
-		c.JSON(http.StatusOK, web.NewResponse(http.StatusOK, users, ""))
+		Respond(c, http.StatusOK, users)
 	}
 }
 
@@ -60,40 +72,46 @@ func (u *User) GetAll() gin.HandlerFunc {
 // @Tags Users
 // @Description List users satisfying received url params
 // @Accept json
-// @Produce json
+// @Produce json,xml,application/yaml,text/csv,application/msgpack
 // @Param token header string true "token"
+// @Param X-API-Version header int false "API contract version, 1 (default) or 2"
 // @Param id query int false "user id"
 // @Param nombre query string false "user name"
 // @Param apellido query string false "user last name"
 // @Param email query string false "user email"
-// @Param edad query int false "user age"
+// @Param edad query int false "user age, computed from fecha_de_nacimiento"
 // @Param altura query number false "user height"
+// @Param activo query bool false "user active"
 // @Param fecha_de_creacion query string false "user sign up date"
+// @Param fecha_de_nacimiento query string false "user birth date (yyyy-mm-dd)"
+// @Param created_after query string false "created after this RFC 3339 timestamp or yyyy-mm-dd date"
+// @Param created_before query string false "created before this RFC 3339 timestamp or yyyy-mm-dd date"
 // @Success 200 {object} web.Response
 // @Failure 400 {object} web.Response
 // @Failure 403 {object} web.Response
+// @Failure 406 {object} web.Response
 // @Router /users/ [get]
 func (u *User) FilterByUrlParams() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		err := CheckAccessToken(c)
 		if err != nil {
-			c.JSON(403, web.NewResponse(403, nil, err.Error()))
+			RespondError(c, 403, err.Error())
 			return
 		}
 
 		err = CheckQueryParams(c)
 		if err != nil {
-			c.JSON(400, web.NewResponse(400, nil, err.Error()))
+			RespondError(c, 400, err.Error())
 			return
 		}
 
 		filteredUsers, err := u.service.FilterByUrlParams(c)
 		if err != nil {
-			c.JSON(400, web.NewResponse(400, nil, err.Error()))
+			RespondError(c, 400, err.Error())
 			return
 		}
 
-		c.JSON(http.StatusOK, web.NewResponse(http.StatusOK, filteredUsers, ""))
+		Respond(c, http.StatusOK, filteredUsers)
 	}
 }
 
@@ -102,34 +120,44 @@ func (u *User) FilterByUrlParams() gin.HandlerFunc {
 // @Tags Users
 // @Description List user given the id as a param in url
 // @Accept json
-// @Produce json
+// @Produce json,xml,application/yaml,application/msgpack
 // @Param token header string true "token"
+// @Param X-API-Version header int false "API contract version, 1 (default) or 2"
 // @Param id path int true "user id"
 // @Success 200 {object} web.Response
 // @Failure 400 {object} web.Response
 // @Failure 403 {object} web.Response
+// @Failure 406 {object} web.Response
+// @Failure 404 {object} web.Response
 // @Router /users/{id} [get]
 func (u *User) GetUserByID() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		err := CheckAccessToken(c)
 		if err != nil {
-			c.JSON(403, web.NewResponse(403, nil, err.Error()))
+			RespondError(c, 403, err.Error())
 			return
 		}
 
 		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 		if err != nil {
-			c.JSON(400, web.NewResponse(400, nil, err.Error()))
+			RespondError(c, 400, err.Error())
 			return
 		}
 
-		filteredUsers, err := u.service.GetUserByID(id)
+		filteredUsers, err := u.service.GetUserByID(c.Request.Context(), id)
 		if err != nil {
-			c.JSON(400, web.NewResponse(400, nil, err.Error()))
+			if errors.Is(err, users.ErrUserNotFound) && RequestedVersion(c) < 2 {
+				// Version 1 answered unknown ids with an empty user
+				Respond(c, http.StatusOK, filteredUsers)
+				return
+			}
+
+			statusCode := ErrorStatus(c, err)
+			RespondError(c, statusCode, err.Error())
 			return
 		}
 
-		c.JSON(http.StatusOK, web.NewResponse(http.StatusOK, filteredUsers, ""))
+		Respond(c, http.StatusOK, filteredUsers)
 	}
 }
 
@@ -137,42 +165,62 @@ func (u *User) GetUserByID() gin.HandlerFunc {
 // @Summary Creates a new user
 // @Tags Users
 // @Description Creates a new user given params in body
-// @Accept json
-// @Produce json
+// @Accept json,xml,application/yaml,text/csv,application/msgpack
+// @Produce json,xml,application/yaml,application/msgpack
 // @Param token header string true "token"
+// @Param X-API-Version header int false "API contract version, 1 (default) or 2"
+// @Param Idempotency-Key header string false "key to safely retry the request"
 // @Param id body int true "user id, ignored param."
 // @Param nombre body string true "user name"
 // @Param apellido body string true "user last name"
 // @Param email body string true "user email"
-// @Param edad body int true "user edad"
+// @Param edad body int false "user edad, used to derive an approximate fecha_de_nacimiento when it is not sent"
+// @Param fecha_de_nacimiento body string false "user birth date (yyyy-mm-dd), required unless edad is sent"
 // @Param altura body number true "user height"
 // @Param activo body bool true "ignored, always true"
-// @Param fecha_de_creacion body string true "user sign up date, use todays date. 'dd/mm/yyyy'"
+// @Param fecha_de_creacion body string false "ignored, the server assigns the sign up date"
 // @Success 200 {object} web.Response
+// @Success 201 {object} web.Response
 // @Failure 400 {object} web.Response
 // @Failure 403 {object} web.Response
+// @Failure 406 {object} web.Response
+// @Failure 409 {object} web.Response
+// @Failure 415 {object} web.Response
+// @Failure 422 {object} web.Response
 // @Router /users/ [post]
 func (u *User) NewUser() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		err := CheckAccessToken(c)
 		if err != nil {
-			c.JSON(403, web.NewResponse(403, nil, err.Error()))
+			RespondError(c, 403, err.Error())
 			return
 		}
 
-		err = c.Bind(&users.User{})
+		if !CheckContentType(c, web.BodyMediaTypes()...) {
+			return
+		}
+
+		err = c.ShouldBindBodyWith(&users.User{}, web.BodyBinding(c.ContentType()))
 		if err != nil {
-			c.JSON(400, web.NewResponse(400, nil, err.Error()))
+			RespondBindingError(c, err)
 			return
 		}
 
+		allowLegacyUsers(c)
 		newUser, err := u.service.NewUser(c)
 		if err != nil {
-			c.JSON(400, web.NewResponse(400, nil, err.Error()))
+			statusCode := ErrorStatus(c, err)
+			RespondError(c, statusCode, err.Error())
 			return
 		}
 
-		c.JSON(http.StatusOK, web.NewResponse(http.StatusOK, newUser, ""))
+		if RequestedVersion(c) >= 2 {
+			c.Header("Location", path.Join(c.Request.URL.Path, strconv.FormatInt(newUser.Id, 10)))
+			Respond(c, http.StatusCreated, newUser)
+			return
+		}
+
+		Respond(c, http.StatusOK, newUser)
 	}
 }
 
@@ -180,80 +228,82 @@ func (u *User) NewUser() gin.HandlerFunc {
 // @Summary Full update to an existing user
 // @Tags Users
 // @Description Full update to an existing user with body params
-// @Accept json
-// @Produce json
+// @Accept json,xml,application/yaml,text/csv,application/msgpack
+// @Produce json,xml,application/yaml,application/msgpack
 // @Param token header string true "token"
+// @Param X-API-Version header int false "API contract version, 1 (default) or 2"
 // @Param id body int true "user id, ignored param."
 // @Param nombre body string true "user name"
 // @Param apellido body string true "user last name"
 // @Param email body string true "user email"
-// @Param edad body int true "user edad"
+// @Param edad body int false "user edad, used to derive an approximate fecha_de_nacimiento when it is not sent"
+// @Param fecha_de_nacimiento body string false "user birth date (yyyy-mm-dd), required unless edad is sent"
 // @Param altura body number true "user height"
 // @Param activo body bool true "ignored, always true"
-// @Param fecha_de_creacion body string true "user sign up date, use todays date. 'dd/mm/yyyy'"
+// @Param fecha_de_creacion body string false "ignored, the server assigns the sign up date"
 // @Success 200 {object} web.Response
 // @Failure 400 {object} web.Response
 // @Failure 403 {object} web.Response
+// @Failure 406 {object} web.Response
+// @Failure 404 {object} web.Response
+// @Failure 409 {object} web.Response
+// @Failure 415 {object} web.Response
+// @Failure 422 {object} web.Response
 // @Router /users/{id} [put]
 func (u *User) FullUpdate() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		err := CheckAccessToken(c)
 		if err != nil {
-			c.JSON(403, web.NewResponse(403, nil, err.Error()))
+			RespondError(c, 403, err.Error())
 			return
 		}
 
 		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 		if err != nil {
-			c.JSON(400, web.NewResponse(400, nil, err.Error()))
+			RespondError(c, 400, err.Error())
+			return
+		}
+
+		if !CheckContentType(c, web.BodyMediaTypes()...) {
 			return
 		}
 
 		var user users.User
 
-		err = c.Bind(&user)
+		err = c.ShouldBindBodyWith(&user, web.BodyBinding(c.ContentType()))
 		if err != nil {
-			c.JSON(400, web.NewResponse(400, nil, err.Error()))
+			RespondBindingError(c, err)
 			return
 		}
-		if user.Nombre == "" {
-			errMsg := "el nuevo nombre del usuario es requerido"
-			c.JSON(400, web.NewResponse(400, nil, errMsg))
-			return
-		}
-		if user.Apellido == "el nuevo apellido del usuario es requerido" {
-			errMsg := ""
-			c.JSON(400, web.NewResponse(400, nil, errMsg))
-			return
-		}
-		if user.Email == "" {
-			errMsg := "el nuevo email del usuario es requerido"
-			c.JSON(400, web.NewResponse(400, nil, errMsg))
-			return
-		}
-		if user.Edad == 0 {
-			errMsg := "la nueva edad del usuario es requerida"
-			c.JSON(400, web.NewResponse(400, nil, errMsg))
-			return
+
+		field, errMsg := "", ""
+		switch {
+		case user.Nombre == "":
+			field, errMsg = "nombre", "el nuevo nombre del usuario es requerido"
+		case user.Apellido == "":
+			field, errMsg = "apellido", "el nuevo apellido del usuario es requerido"
+		case user.Email == "":
+			field, errMsg = "email", "el nuevo email del usuario es requerido"
+		case user.Edad == 0 && user.FechaDeNacimiento == "":
+			field, errMsg = "fecha_de_nacimiento", "la nueva fecha_de_nacimiento o edad del usuario es requerida"
+		case user.Altura == 0.0:
+			field, errMsg = "altura", "la nueva altura del usuario es requerida"
 		}
-		if user.Altura == 0.0 {
-			errMsg := "la nueva altura del usuario es requerida"
-			c.JSON(400, web.NewResponse(400, nil, errMsg))
+		if errMsg != "" {
+			statusCode := validationStatus(c)
+			RespondError(c, statusCode, errMsg, web.FieldError{Field: field, Message: errMsg})
 			return
 		}
 
-		user, err = u.service.FullUpdate(id, user.Nombre, user.Apellido, user.Email, user.Edad, user.Altura)
+		allowLegacyUsers(c)
+		user, err = u.service.FullUpdate(c.Request.Context(), id, user.Nombre, user.Apellido, user.Email, user.Edad, user.Altura)
 		if err != nil {
-			statusCode := 400
-			if err.Error() == "el usuario no fue encontrado" {
-				statusCode = 404
-			}
-
-			c.JSON(statusCode, web.NewResponse(statusCode, nil, err.Error()))
+			statusCode := ErrorStatus(c, err)
+			RespondError(c, statusCode, err.Error())
 			return
 		}
 
-		c.JSON(http.StatusOK, web.NewResponse(http.StatusOK, user, ""))
+		Respond(c, http.StatusOK, user)
 	}
 }
 
@@ -262,128 +312,600 @@ func (u *User) FullUpdate() gin.HandlerFunc {
 // @Tags Users
 // @Description Delete an existing user given the id as an url param
 // @Accept json
-// @Produce json
+// @Produce json,xml,application/yaml,application/msgpack
 // @Param token header string true "token"
+// @Param X-API-Version header int false "API contract version, 1 (default) or 2"
 // @Param id path int true "user id"
 // @Success 200 {object} web.Response
+// @Success 204 "no content, version 2"
 // @Failure 400 {object} web.Response
 // @Failure 403 {object} web.Response
+// @Failure 406 {object} web.Response
 // @Failure 404 {object} web.Response
 // @Router /users/{id} [delete]
 func (u *User) DeleteUserByID() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		err := CheckAccessToken(c)
 		if err != nil {
-			c.JSON(403, web.NewResponse(403, nil, err.Error()))
+			RespondError(c, 403, err.Error())
 			return
 		}
 
 		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 		if err != nil {
-			c.JSON(400, web.NewResponse(400, nil, err.Error()))
+			RespondError(c, 400, err.Error())
 			return
 		}
 
-		err = u.service.DeleteUserByID(id)
+		err = u.service.DeleteUserByID(c.Request.Context(), id)
 		if err != nil {
-			statusCode := 400
-			if err.Error() == "el usuario no fue encontrado" {
-				statusCode = 404
-			}
+			statusCode := ErrorStatus(c, err)
+			RespondError(c, statusCode, err.Error())
+			return
+		}
 
-			c.JSON(statusCode, web.NewResponse(statusCode, nil, err.Error()))
+		if RequestedVersion(c) >= 2 {
+			c.Status(http.StatusNoContent)
 			return
 		}
 
 		data := "el usuario fue eliminado satisfactoriamente"
-		c.JSON(http.StatusOK, web.NewResponse(http.StatusOK, data, ""))
+		Respond(c, http.StatusOK, data)
 	}
 }
 
 // PartialUpdateToUser godoc
 // @Summary Partial update to an existing user
 // @Tags Users
-// @Description Partial update to an existing user with body params
-// @Accept json
-// @Produce json
+// @Description Partial update to an existing user. Accepts a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902)
+// @Description over any mutable field, or a plain body with apellido, edad and/or fecha_de_nacimiento.
+// @Description The whole patch is applied in a single write or not at all.
+// @Accept json,xml,application/yaml,text/csv,application/msgpack,application/merge-patch+json,application/json-patch+json
+// @Produce json,xml,application/yaml,application/msgpack
 // @Param token header string true "token"
+// @Param X-API-Version header int false "API contract version, 1 (default) or 2"
 // @Param apellido body string false "user last name"
 // @Param edad body int false "user edad"
+// @Param fecha_de_nacimiento body string false "user birth date (yyyy-mm-dd)"
 // @Success 200 {object} web.Response
 // @Failure 400 {object} web.Response
 // @Failure 403 {object} web.Response
+// @Failure 406 {object} web.Response
 // @Failure 404 {object} web.Response
+// @Failure 409 {object} web.Response
+// @Failure 415 {object} web.Response
+// @Failure 422 {object} web.Response
 // @Router /users/{id} [patch]
 func (u *User) PartialUpdateToUser() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		type partialUser struct {
-			Apellido string `json:"apellido"`
-			Edad     int64  `json:"edad"`
+		err := CheckAccessToken(c)
+		if err != nil {
+			RespondError(c, 403, err.Error())
+			return
+		}
+
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			RespondError(c, 400, err.Error())
+			return
+		}
+
+		patch, ok := bindPatch(c)
+		if !ok {
+			return
+		}
+
+		user, err := u.service.Patch(c.Request.Context(), id, patch)
+		if err != nil {
+			statusCode := ErrorStatus(c, err)
+			RespondError(c, statusCode, err.Error())
+			return
+		}
+
+		Respond(c, http.StatusOK, user)
+	}
+}
+
+// Batch godoc
+// @Summary Create, update and delete users in bulk
+// @Tags Users
+// @Description Runs a list of create, update and delete operations in order and saves them in a single write.
+// @Description Each operation gets its own status, failed ones are skipped unless atomic=true, which rolls back everything.
+// @Accept json,application/yaml,application/msgpack
+// @Produce json,xml,application/yaml,application/msgpack
+// @Param token header string true "token"
+// @Param X-API-Version header int false "API contract version, 1 (default) or 2"
+// @Param Idempotency-Key header string false "key to safely retry the request"
+// @Param atomic query bool false "roll back every operation if any of them fails"
+// @Param operations body []BatchOperation true "operations, id is ignored for create"
+// @Success 200 {object} web.Response{data=web.BatchResults}
+// @Success 207 {object} web.Response{data=web.BatchResults}
+// @Failure 400 {object} web.Response
+// @Failure 403 {object} web.Response
+// @Failure 406 {object} web.Response
+// @Failure 413 {object} web.Response
+// @Failure 415 {object} web.Response
+// @Failure 422 {object} web.Response
+// @Router /users/batch [post]
+func (u *User) Batch() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := CheckAccessToken(c)
+		if err != nil {
+			RespondError(c, 403, err.Error())
+			return
+		}
+
+		var operations []BatchOperation
+		atomic, ok := BindBatch(c, &operations)
+		if !ok {
+			return
+		}
+
+		batch := make([]users.BatchOperation, len(operations))
+		for idx, operation := range operations {
+			batch[idx] = users.BatchOperation{Op: operation.Op, ID: operation.ID, User: operation.User}
+		}
+
+		results, err := u.service.Batch(c.Request.Context(), batch, atomic)
+		if err != nil {
+			RespondError(c, 500, err.Error())
+			return
+		}
+
+		batchResults := make([]web.BatchResult, len(results))
+		for idx, result := range results {
+			var data interface{}
+			if result.User != nil {
+				data = *result.User
+			}
+			batchResults[idx] = NewBatchResult(c, result, data)
+		}
+
+		RespondBatch(c, batchResults)
+	}
+}
+
+// BulkUpdate godoc
+// @Summary Update every user matching the filters
+// @Tags Users
+// @Description Applies the same patch (merge patch, JSON patch or plain body, as in PATCH /users/{id}) to every user
+// @Description matching the filters of GET /users/, in a single write. If it fails for any user nothing is saved.
+// @Description Requires the X-Confirm-Count header with the number of matched users, unless dry_run=true.
+// @Accept json,xml,application/yaml,text/csv,application/msgpack,application/merge-patch+json,application/json-patch+json
+// @Produce json,xml,application/yaml,application/msgpack
+// @Param token header string true "token"
+// @Param X-API-Version header int false "API contract version, 1 (default) or 2"
+// @Param X-Confirm-Count header int false "number of users expected to match, required unless dry_run=true"
+// @Param dry_run query bool false "return the matched ids and changes without saving them"
+// @Param id query int false "user id"
+// @Param nombre query string false "user name"
+// @Param apellido query string false "user last name"
+// @Param email query string false "user email"
+// @Param edad query int false "user age, computed from fecha_de_nacimiento"
+// @Param altura query number false "user height"
+// @Param activo query bool false "user active"
+// @Param fecha_de_creacion query string false "user sign up date"
+// @Param fecha_de_nacimiento query string false "user birth date (yyyy-mm-dd)"
+// @Param created_after query string false "created after this RFC 3339 timestamp or yyyy-mm-dd date"
+// @Param created_before query string false "created before this RFC 3339 timestamp or yyyy-mm-dd date"
+// @Success 200 {object} web.Response{data=web.BulkResult}
+// @Failure 400 {object} web.Response
+// @Failure 403 {object} web.Response
+// @Failure 406 {object} web.Response
+// @Failure 409 {object} web.Response
+// @Failure 412 {object} web.Response
+// @Failure 415 {object} web.Response
+// @Failure 422 {object} web.Response
+// @Failure 428 {object} web.Response
+// @Router /users/ [patch]
+func (u *User) BulkUpdate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := CheckAccessToken(c)
+		if err != nil {
+			RespondError(c, 403, err.Error())
+			return
+		}
+
+		filter, ok := bulkFilter(c, "dry_run")
+		if !ok {
+			return
+		}
+
+		options, ok := BulkOptions(c)
+		if !ok {
+			return
 		}
 
-		var newPartialUser partialUser
+		patch, ok := bindPatch(c)
+		if !ok {
+			return
+		}
 
+		changes, err := u.service.UpdateWhere(c.Request.Context(), filter, patch, options)
+		if err != nil {
+			statusCode := ErrorStatus(c, err)
+			RespondError(c, statusCode, err.Error())
+			return
+		}
+
+		respondBulk(c, changes, options)
+	}
+}
+
+// BulkDelete godoc
+// @Summary Delete every user matching the filters
+// @Tags Users
+// @Description Deletes every user matching the filters of GET /users/ in a single write.
+// @Description Requires the X-Confirm-Count header with the number of matched users, unless dry_run=true.
+// @Accept json
+// @Produce json,xml,application/yaml,application/msgpack
+// @Param token header string true "token"
+// @Param X-API-Version header int false "API contract version, 1 (default) or 2"
+// @Param X-Confirm-Count header int false "number of users expected to match, required unless dry_run=true"
+// @Param dry_run query bool false "return the matched ids without deleting them"
+// @Param id query int false "user id"
+// @Param nombre query string false "user name"
+// @Param apellido query string false "user last name"
+// @Param email query string false "user email"
+// @Param edad query int false "user age, computed from fecha_de_nacimiento"
+// @Param altura query number false "user height"
+// @Param activo query bool false "user active"
+// @Param fecha_de_creacion query string false "user sign up date"
+// @Param fecha_de_nacimiento query string false "user birth date (yyyy-mm-dd)"
+// @Param created_after query string false "created after this RFC 3339 timestamp or yyyy-mm-dd date"
+// @Param created_before query string false "created before this RFC 3339 timestamp or yyyy-mm-dd date"
+// @Success 200 {object} web.Response{data=web.BulkResult}
+// @Failure 400 {object} web.Response
+// @Failure 403 {object} web.Response
+// @Failure 406 {object} web.Response
+// @Failure 412 {object} web.Response
+// @Failure 422 {object} web.Response
+// @Failure 428 {object} web.Response
+// @Router /users/ [delete]
+func (u *User) BulkDelete() gin.HandlerFunc {
+	return func(c *gin.Context) {
 		err := CheckAccessToken(c)
 		if err != nil {
-			c.JSON(403, web.NewResponse(403, nil, err.Error()))
+			RespondError(c, 403, err.Error())
+			return
+		}
+
+		filter, ok := bulkFilter(c, "dry_run")
+		if !ok {
+			return
+		}
+
+		options, ok := BulkOptions(c)
+		if !ok {
+			return
+		}
+
+		changes, err := u.service.DeleteWhere(c.Request.Context(), filter, options)
+		if err != nil {
+			statusCode := ErrorStatus(c, err)
+			RespondError(c, statusCode, err.Error())
+			return
+		}
+
+		respondBulk(c, changes, options)
+	}
+}
+
+// Import godoc
+// @Summary Import users from a CSV or NDJSON file
+// @Tags Users
+// @Description Validates every row of a CSV (header row with the json names of the fields) or NDJSON file and saves
+// @Description the accepted ones in a single write. The report lists the accepted rows and the errors by line.
+// @Description mode=all_or_nothing (default) saves nothing if any row is invalid, mode=skip_invalid saves the valid
+// @Description rows. upsert=true updates the user with the same email instead of rejecting the row.
+// @Accept text/csv,application/x-ndjson
+// @Produce json,xml,application/yaml,application/msgpack
+// @Param token header string true "token"
+// @Param X-API-Version header int false "API contract version, 1 (default) or 2"
+// @Param mode query string false "all_or_nothing (default) or skip_invalid"
+// @Param upsert query bool false "update the users whose email already exists"
+// @Param mapping query string false "CSV columns to rename, like First Name=nombre,Surname=apellido"
+// @Success 200 {object} web.Response{data=web.ImportReport}
+// @Failure 400 {object} web.Response{data=web.ImportReport}
+// @Failure 403 {object} web.Response
+// @Failure 406 {object} web.Response
+// @Failure 413 {object} web.Response
+// @Failure 415 {object} web.Response
+// @Failure 422 {object} web.ImportProblem
+// @Router /users/import [post]
+func (u *User) Import() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := CheckAccessToken(c)
+		if err != nil {
+			RespondError(c, 403, err.Error())
+			return
+		}
+
+		options, mapping, ok := ImportRequest(c)
+		if !ok {
+			return
+		}
+
+		rows, err := users.ReadImportRows(c.ContentType(), c.Request.Body, mapping)
+		if err != nil {
+			RespondImport(c, users.ImportReport{}, err)
+			return
+		}
+
+		report, err := u.service.Import(c.Request.Context(), rows, options)
+		RespondImport(c, report, err)
+	}
+}
+
+// Export godoc
+// @Summary Export users as CSV, NDJSON or JSON
+// @Tags Users
+// @Description Streams the users matching the listing filters as a download, writing each user as it is read
+// @Description instead of building the whole response. fields selects the columns, all of them by default.
+// @Produce text/csv,application/x-ndjson,json
+// @Param token header string true "token"
+// @Param X-API-Version header int false "API contract version, 1 (default) or 2"
+// @Param format query string false "csv, ndjson or json (default)"
+// @Param fields query string false "comma separated fields to export, like id,nombre,email"
+// @Param id query int false "user id"
+// @Param nombre query string false "user name"
+// @Param apellido query string false "user last name"
+// @Param email query string false "user email"
+// @Param edad query int false "user age, computed from fecha_de_nacimiento"
+// @Param altura query number false "user height"
+// @Param activo query bool false "user active"
+// @Param fecha_de_creacion query string false "user sign up date"
+// @Param fecha_de_nacimiento query string false "user birth date (yyyy-mm-dd)"
+// @Param created_after query string false "created after this RFC 3339 timestamp or yyyy-mm-dd date"
+// @Param created_before query string false "created before this RFC 3339 timestamp or yyyy-mm-dd date"
+// @Success 200 {array} users.User
+// @Failure 400 {object} web.Response
+// @Failure 403 {object} web.Response
+// @Router /users/export [get]
+func (u *User) Export() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := CheckAccessToken(c)
+		if err != nil {
+			RespondError(c, 403, err.Error())
+			return
+		}
+
+		filter, ok := bulkFilter(c, "format", "fields")
+		if !ok {
+			return
+		}
+
+		StreamExport(c, "users", reflect.TypeOf(users.User{}), func(write func(v interface{}) error) error {
+			return u.service.Export(c.Request.Context(), filter, func(user users.User) error {
+				return write(user)
+			})
+		})
+	}
+}
+
+// ErasureResult is the response of an erasure: the tombstone left in place
+// of the user and the log entry of the erasure.
+type ErasureResult struct {
+	User    users.User      `json:"user" xml:"user" yaml:"user"`
+	Erasure privacy.Erasure `json:"erasure" xml:"erasure" yaml:"erasure"`
+}
+
+// LogUnverifiedErasure warns when the scan made after an erasure still found
+// personal data of the user, which has to be removed by hand.
+func LogUnverifiedErasure(log *logger.Logger, erasure privacy.Erasure) {
+	if erasure.Verified {
+		return
+	}
+
+	log.Warn("el borrado no pudo verificarse", logger.F("erasure_id", erasure.ID), logger.F("remaining", erasure.Remaining))
+}
+
+// SubjectExport godoc
+// @Summary Export the data the service stores about a user
+// @Tags Privacy
+// @Description Returns the record of the user, the stored idempotency responses and the jobs that mention it and the
+// @Description log of its erasures. The request logs and the change history of the user are not included, the service
+// @Description does not keep them; not_included says so in the response.
+// @Produce json,xml,application/yaml,application/msgpack
+// @Param token header string true "token"
+// @Param X-API-Version header int false "API contract version, 1 (default) or 2"
+// @Param id path int true "user id"
+// @Success 200 {object} web.Response{data=privacy.SubjectData}
+// @Failure 400 {object} web.Response
+// @Failure 403 {object} web.Response
+// @Failure 404 {object} web.Response
+// @Failure 406 {object} web.Response
+// @Router /users/{id}/export [get]
+func (u *User) SubjectExport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := CheckAccessToken(c)
+		if err != nil {
+			RespondError(c, 403, err.Error())
 			return
 		}
 
 		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 		if err != nil {
-			c.JSON(400, web.NewResponse(400, nil, err.Error()))
+			RespondError(c, 400, err.Error())
 			return
 		}
 
-		err = c.ShouldBindJSON(&newPartialUser)
+		data, err := u.privacy.Export(c.Request.Context(), id)
 		if err != nil {
-			c.JSON(400, web.NewResponse(400, nil, err.Error()))
+			RespondError(c, ErrorStatus(c, err), err.Error())
 			return
 		}
 
-		apellido := newPartialUser.Apellido
-		edad := newPartialUser.Edad
-		var user users.User
+		Respond(c, http.StatusOK, data)
+	}
+}
 
-		if apellido != "" {
-			user, err = u.service.UpdateUserLastName(id, apellido)
-			if err != nil {
-				statusCode := 400
-				if err.Error() == "el usuario no fue encontrado" {
-					statusCode = 404
-				}
-				c.JSON(statusCode, web.NewResponse(statusCode, nil, err.Error()))
-				return
-			}
+// Erase godoc
+// @Summary Erase the personal data of a user
+// @Tags Privacy
+// @Description Irreversibly replaces nombre, apellido and email with anonymous values, deletes the idempotency
+// @Description records and job files holding them and redacts them from job results. The record stays as an inactive
+// @Description tombstone that can no longer be changed. The erasure is logged with a scan verifying nothing is left.
+// @Produce json,xml,application/yaml,application/msgpack
+// @Param token header string true "token"
+// @Param X-API-Version header int false "API contract version, 1 (default) or 2"
+// @Param id path int true "user id"
+// @Success 200 {object} web.Response{data=ErasureResult}
+// @Failure 400 {object} web.Response
+// @Failure 403 {object} web.Response
+// @Failure 404 {object} web.Response
+// @Failure 406 {object} web.Response
+// @Failure 409 {object} web.Response
+// @Router /users/{id}/erase [post]
+func (u *User) Erase() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := CheckAccessToken(c)
+		if err != nil {
+			RespondError(c, 403, err.Error())
+			return
 		}
 
-		if edad != 0 {
-			user, err = u.service.UpdateUserAge(id, edad)
-			if err != nil {
-				statusCode := 400
-				if err.Error() == "el usuario no fue encontrado" {
-					statusCode = 404
-				}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			RespondError(c, 400, err.Error())
+			return
+		}
 
-				c.JSON(statusCode, web.NewResponse(statusCode, nil, err.Error()))
-				return
-			}
+		erasure, tombstone, err := u.privacy.Erase(c.Request.Context(), id)
+		if err != nil {
+			RespondError(c, ErrorStatus(c, err), err.Error())
+			return
+		}
+		LogUnverifiedErasure(Logger(c, u.logger), erasure)
+
+		Respond(c, http.StatusOK, ErasureResult{User: tombstone, Erasure: erasure})
+	}
+}
+
+// bindPatch returns the patch in the body of a PATCH request: a merge patch,
+// a JSON patch or a plain body with apellido, edad and/or fecha_de_nacimiento.
+func bindPatch(c *gin.Context) (patch users.PatchFunc, ok bool) {
+	SetAcceptPatch(c)
+	if !CheckContentType(c, append(web.BodyMediaTypes(), PatchMediaTypes...)...) {
+		return nil, false
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		RespondError(c, 400, err.Error())
+		return nil, false
+	}
+
+	patch, err = NewPatch(c.ContentType(), body)
+	if err != nil {
+		RespondBindingError(c, err)
+		return nil, false
+	}
+
+	return patch, true
+}
+
+// NewPatch decodes the body of a version 1 PATCH request sent with the given
+// media type.
+func NewPatch(mediaType string, body []byte) (patch users.PatchFunc, err error) {
+	type partialUser struct {
+		Apellido string `json:"apellido" xml:"apellido" yaml:"apellido"`
+		Edad     int64  `json:"edad" xml:"edad" yaml:"edad"`
+		// FechaDeNacimiento wins over Edad when both are sent.
+		FechaDeNacimiento string `json:"fecha_de_nacimiento" xml:"fecha_de_nacimiento" yaml:"fecha_de_nacimiento"`
+	}
+
+	if IsPatchMediaType(mediaType) {
+		patch = func(user users.User) (users.User, error) {
+			err := ApplyPatch(mediaType, body, &user)
+			return user, err
+		}
+
+		return patch, nil
+	}
+
+	var newPartialUser partialUser
+
+	err = web.BodyBinding(mediaType).BindBody(body, &newPartialUser)
+	if err != nil {
+		return nil, err
+	}
+
+	// Zero values mean the field was not sent in these bodies.
+	patch = func(user users.User) (users.User, error) {
+		if newPartialUser.Apellido != "" {
+			user.Apellido = newPartialUser.Apellido
 		}
+		if newPartialUser.FechaDeNacimiento != "" {
+			user.FechaDeNacimiento = newPartialUser.FechaDeNacimiento
+		} else if newPartialUser.Edad != 0 {
+			user.Edad = newPartialUser.Edad
+		}
+
+		return user, nil
+	}
+
+	return patch, nil
+}
+
+// bulkFilter builds the filter of the routes that change or export users,
+// which also take the given params. Unknown params are rejected, so a typo
+// does not widen the filter to more users than asked.
+func bulkFilter(c *gin.Context, params ...string) (filter users.Filter, ok bool) {
+	err := CheckKnownParams(c, append(users.FilterParams(), params...))
+	if err == nil {
+		err = CheckQueryParams(c)
+	}
+	if err != nil {
+		RespondError(c, 400, err.Error())
+		return filter, false
+	}
+
+	filter, err = users.FilterFromUrlParams(c)
+	if err != nil {
+		RespondError(c, 400, err.Error())
+		return filter, false
+	}
+
+	return filter, true
+}
 
-		c.JSON(http.StatusOK, web.NewResponse(http.StatusOK, user, ""))
+func respondBulk(c *gin.Context, changes []users.BulkChange, options users.BulkOptions) {
+	result, err := NewBulkResult(changes, options.DryRun, func(user users.User) interface{} {
+		return user
+	})
+	if err != nil {
+		RespondError(c, 500, err.Error())
+		return
 	}
+
+	Respond(c, http.StatusOK, result)
 }
 
-func CheckAccessToken(c *gin.Context) (err error) {
-	token := c.GetHeader("token")
-	acceptedToken := os.Getenv("TOKEN")
-	if token == "" {
-		err = errors.New("el token de acceso no fue proporcionado")
-	} else if token != acceptedToken {
-		err = errors.New("el token enviado no es correcto")
+// CheckKnownParams returns an error naming the first query param, in
+// alphabetical order, that is not in known.
+func CheckKnownParams(c *gin.Context, known []string) (err error) {
+	queryParams := c.Request.URL.Query()
+	keys := make([]string, 0, len(queryParams))
+	for key := range queryParams {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		found := false
+		for _, param := range known {
+			if key == param {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("el parametro %s no es conocido, use %s", key, strings.Join(known, ", "))
+		}
 	}
 
-	return err
+	return nil
 }
 
 func CheckQueryParams(c *gin.Context) (err error) {
@@ -395,7 +917,7 @@ func CheckQueryParams(c *gin.Context) (err error) {
 			return
 		}
 
-		// "id", "nombre", "apellido", "email", "edad", "altura", "fecha_de_creacion"
+		// "id", "nombre", "apellido", "email", "edad", "altura", "activo", "fecha_de_creacion", "fecha_de_nacimiento"
 		switch key {
 		case "id":
 			id, err := strconv.ParseInt(value, 10, 64)
@@ -415,6 +937,21 @@ func CheckQueryParams(c *gin.Context) (err error) {
 				err = fmt.Errorf("altura debe ser un float mayor a cero(recibido: %s)", value)
 				return err
 			}
+		case "activo":
+			_, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("activo debe ser true o false(recibido: %s)", value)
+			}
+		case "fecha_de_nacimiento":
+			_, err := time.Parse(users.FechaDeNacimientoLayout, value)
+			if err != nil {
+				return fmt.Errorf("fecha_de_nacimiento debe tener formato aaaa-mm-dd(recibido: %s)", value)
+			}
+		case "created_after", "created_before":
+			_, err := users.ParseDateParam(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
 		}
 	}
 
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/idempotency"
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/users"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/logger"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/web"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryStore keeps the document in memory, encoded as the file store does.
+type memoryStore struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+func (db *memoryStore) Read(data interface{}) (err error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.data == nil {
+		return nil
+	}
+
+	return json.Unmarshal(db.data, data)
+}
+
+func (db *memoryStore) Write(data interface{}) (err error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.data, err = json.Marshal(data)
+
+	return err
+}
+
+// testUsers are the users the routes of the tests start with.
+var testUsers = []users.User{
+	{Id: 1, Nombre: "Juan", Apellido: "Perez", Email: "juan@email.com", FechaDeNacimiento: "1990-01-01", Altura: 1.7, Activo: true, CreatedAt: time.Date(2021, 12, 22, 0, 0, 0, 0, time.UTC)},
+	{Id: 2, Nombre: "Ana", Apellido: "Perez", Email: "ana@email.com", FechaDeNacimiento: "1992-05-10", Altura: 1.6, Activo: false, CreatedAt: time.Date(2021, 12, 22, 0, 0, 0, 0, time.UTC)},
+	{Id: 3, Nombre: "Juan", Apellido: "Gomez", Email: "jgomez@email.com", FechaDeNacimiento: "1985-03-15", Altura: 1.8, Activo: true, CreatedAt: time.Date(2021, 12, 22, 0, 0, 0, 0, time.UTC)},
+}
+
+// newUsersRouter serves the users routes of version 1 on /users, with the
+// X-API-Version switch, over the testUsers kept in db.
+func newUsersRouter(t *testing.T) (router *gin.Engine, db *memoryStore) {
+	UseAuth(true, "token123")
+	web.UseJSONFieldNames()
+
+	db = &memoryStore{}
+	assert.Nil(t, db.Write(&users.Users{Users: testUsers}))
+	service := users.CreateService(users.CreateRepository(db, logger.Nop()), logger.Nop())
+	controller := CreateUser(service, nil, logger.Nop())
+	idempotent := Idempotency(idempotency.CreateRepository(&memoryStore{}), idempotency.CreateKeyLocker(), time.Hour)
+
+	router = gin.New()
+	router.HandleMethodNotAllowed = true
+	router.NoMethod(MethodNotAllowed(router, 1))
+
+	listFormats := Negotiate(web.ListFormats...)
+	recordFormats := Negotiate(web.RecordFormats...)
+	usrs := router.Group("/users")
+	usrs.Use(APIVersion(1))
+	usrs.OPTIONS("/", Options(router))
+	usrs.OPTIONS("/:id", Options(router))
+	usrs.GET("/", listFormats, controller.FilterByUrlParams())
+	usrs.GET("/export", controller.Export())
+	usrs.GET("/:id", recordFormats, controller.GetUserByID())
+	usrs.POST("/", recordFormats, idempotent, controller.NewUser())
+	usrs.POST("/import", recordFormats, idempotent, controller.Import())
+	usrs.PUT("/:id", recordFormats, controller.FullUpdate())
+	usrs.DELETE("/:id", recordFormats, controller.DeleteUserByID())
+	usrs.PATCH("/:id", recordFormats, controller.PartialUpdateToUser())
+	usrs.PATCH("/", recordFormats, controller.BulkUpdate())
+	usrs.DELETE("/", recordFormats, controller.BulkDelete())
+
+	return router, db
+}
+
+// storedUsers returns the users kept in db.
+func storedUsers(t *testing.T, db *memoryStore) []users.User {
+	var document users.Users
+	assert.Nil(t, db.Read(&document))
+
+	return document.Users
+}
+
+func TestBulkUpdate(t *testing.T) {
+	router, db := newUsersRouter(t)
+	headers := map[string]string{"token": "token123", "Content-Type": "application/json", "X-Confirm-Count": "1"}
+
+	// Testea que el filtro por activo deje afuera a los usuarios inactivos
+	response := serve(router, http.MethodPatch, "/users/?apellido=Perez&activo=true", `{"apellido":"Ruiz"}`, headers)
+	assert.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	stored := storedUsers(t, db)
+	assert.Equal(t, "Ruiz", stored[0].Apellido)
+	assert.Equal(t, "Perez", stored[1].Apellido)
+
+	// Testea que un parametro desconocido se rechace en vez de ampliar el filtro
+	for _, target := range []string{"/users/?apelido=Gomez&nombre=Juan", "/users/?activo=si"} {
+		response = serve(router, http.MethodPatch, target, `{"apellido":"Ruiz"}`, headers)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		response = serve(router, http.MethodDelete, target, "", headers)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	}
+	assert.Equal(t, "Gomez", storedUsers(t, db)[2].Apellido)
+	assert.Len(t, storedUsers(t, db), 3)
+}
+
+func TestImportRejected(t *testing.T) {
+	router, db := newUsersRouter(t)
+	headers := map[string]string{"token": "token123", "Content-Type": "text/csv"}
+	body := "nombre,apellido,email,fecha_de_nacimiento,altura\n" +
+		"Eva,Lopez,eva@email.com,1990-01-01,1.6\n" +
+		"Luis,Diaz,juan@email.com,1990-01-01,1.7\n"
+
+	// Testea que una importacion rechazada responda con el reporte de las filas
+	response := serve(router, http.MethodPost, "/users/import", body, headers)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	var v1 struct {
+		Code  string           `json:"code"`
+		Data  web.ImportReport `json:"data"`
+		Error string           `json:"error"`
+	}
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &v1))
+	assert.Equal(t, "400", v1.Code)
+	assert.NotEmpty(t, v1.Error)
+	assert.False(t, v1.Data.Applied)
+	assert.Equal(t, 2, v1.Data.Total)
+	assert.Len(t, v1.Data.Accepted, 1)
+	assert.Equal(t, 3, v1.Data.Errors[0].Line)
+
+	headers["X-API-Version"] = "2"
+	response = serve(router, http.MethodPost, "/users/import", body, headers)
+	assert.Equal(t, http.StatusUnprocessableEntity, response.Code)
+	assert.Equal(t, web.ProblemMediaType, response.Header().Get("Content-Type"))
+	var v2 web.ImportProblem
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &v2))
+	assert.Equal(t, http.StatusUnprocessableEntity, v2.Status)
+	assert.Len(t, v2.Report.Errors, 1)
+	assert.Equal(t, 3, v2.Report.Errors[0].Line)
+
+	assert.Len(t, storedUsers(t, db), 3)
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// corsOrigins holds the origins set by UseCORSOrigins.
+var corsOrigins atomic.Value
+
+// UseCORSOrigins sets the origins whose browsers may call the API, * for any
+// of them. Without origins no CORS header is sent. It may be called while
+// serving.
+func UseCORSOrigins(origins []string) {
+	allowed := make([]string, len(origins))
+	copy(allowed, origins)
+	corsOrigins.Store(allowed)
+}
+
+func corsAllowed(origin string) bool {
+	allowed, _ := corsOrigins.Load().([]string)
+	for _, candidate := range allowed {
+		if candidate == "*" || strings.EqualFold(candidate, origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// corsAllowHeaders are the request headers the API reads.
+var corsAllowHeaders = strings.Join([]string{
+	"Accept", "Content-Type", "token", "Idempotency-Key", "X-API-Version",
+	ConfirmCountHeader, RequestIDHeader, TraceparentHeader,
+}, ", ")
+
+// corsExposeHeaders are the response headers a browser script may read.
+var corsExposeHeaders = strings.Join([]string{
+	"Location", "Link", "Deprecation", "Idempotent-Replayed", "Retry-After",
+	"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", RequestIDHeader,
+}, ", ")
+
+// CORS lets the browsers of the allowed origins call the API, answering
+// their preflight requests before the routes.
+func CORS() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" || !corsAllowed(origin) {
+			c.Next()
+			return
+		}
+
+		c.Header("Access-Control-Allow-Origin", origin)
+		c.Writer.Header().Add("Vary", "Origin")
+
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			c.Header("Access-Control-Allow-Headers", corsAllowHeaders)
+			c.Header("Access-Control-Max-Age", "600")
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Header("Access-Control-Expose-Headers", corsExposeHeaders)
+		c.Next()
+	}
+}
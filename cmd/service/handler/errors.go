@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/web"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RespondError writes an error as problem+json when the client asks for it or
+// uses version 2 of the API, and as a web.Response otherwise.
+func RespondError(c *gin.Context, statusCode int, errMsg string, fieldErrors ...web.FieldError) {
+	c.Abort()
+	// Kept for the line RequestLogger writes.
+	_ = c.Error(errors.New(errMsg))
+
+	if !wantsProblem(c) {
+		c.JSON(statusCode, web.NewResponse(statusCode, nil, errMsg))
+		return
+	}
+
+	problem := web.NewProblem(statusCode, errMsg, c.Request.URL.RequestURI())
+	problem.Errors = fieldErrors
+
+	c.Header("Content-Type", web.ProblemMediaType)
+	c.JSON(statusCode, problem)
+}
+
+// RespondBindingError reports a body that could not be bound, listing the
+// offending fields when the body parsed but failed validation.
+func RespondBindingError(c *gin.Context, err error) {
+	statusCode := bindingStatus(c, err)
+	fieldErrors := web.FieldErrors(err)
+
+	errMsg := err.Error()
+	if len(fieldErrors) > 0 && wantsProblem(c) {
+		errMsg = "uno o mas campos de la solicitud no son validos"
+	}
+
+	RespondError(c, statusCode, errMsg, fieldErrors...)
+}
+
+func wantsProblem(c *gin.Context) bool {
+	return RequestedVersion(c) >= 2 || web.AcceptsProblem(c.GetHeader("Accept"))
+}
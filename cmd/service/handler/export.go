@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/web"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportFlushRows is how many rows are written between flushes, so that
+// proxies see data flowing on long exports.
+const ExportFlushRows = 500
+
+// ExportFunc calls write with each exported value.
+type ExportFunc func(write func(v interface{}) error) error
+
+// StreamExport writes the values of export in the format and with the fields
+// given in the format and fields query params, as a download named
+// filename. Values are encoded as they come, the list is never held whole.
+func StreamExport(c *gin.Context, filename string, t reflect.Type, export ExportFunc) {
+	format, mediaType, fields, ok := ExportFormat(c, t)
+	if !ok {
+		return
+	}
+
+	encoder, err := web.NewRowEncoder(c.Writer, mediaType, t, fields)
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.Header("Content-Type", web.ContentType(mediaType))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+"."+format))
+	c.Status(http.StatusOK)
+
+	rows := 0
+	err = export(func(v interface{}) error {
+		err := encoder.Encode(v)
+		if err != nil {
+			return err
+		}
+
+		rows++
+		if rows%ExportFlushRows == 0 {
+			err = encoder.Flush()
+			if err != nil {
+				return err
+			}
+			c.Writer.Flush()
+		}
+
+		return nil
+	})
+	if err == nil {
+		err = encoder.Close()
+	}
+	if err != nil {
+		// Once rows went out the status can no longer change, the connection
+		// is cut instead so the download does not look complete.
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Disposition")
+			RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		_ = c.Error(err)
+		AbortConnection(c)
+		return
+	}
+
+	c.Writer.Flush()
+}
+
+// ExportFormat reads the format and fields query params of an export of
+// values of type t. It responds with the error and returns false when they
+// are invalid.
+func ExportFormat(c *gin.Context, t reflect.Type) (format string, mediaType string, fields []string, ok bool) {
+	format = c.DefaultQuery("format", "json")
+	mediaType, found := web.StreamFormats[format]
+	if !found {
+		var names []string
+		for name := range web.StreamFormats {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		RespondError(c, http.StatusBadRequest, fmt.Sprintf("format debe ser %s(recibido: %s)", strings.Join(names, ", "), format))
+		return format, mediaType, nil, false
+	}
+
+	fields = web.ParseFields(c.Query("fields"))
+	_, err := web.NewRowEncoder(ioutil.Discard, mediaType, t, fields)
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, err.Error())
+		return format, mediaType, nil, false
+	}
+
+	return format, mediaType, fields, true
+}
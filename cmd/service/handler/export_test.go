@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamExport(t *testing.T) {
+	type row struct {
+		ID int `json:"id"`
+	}
+	router := gin.New()
+	router.Use(AbortConnections(), gin.Recovery())
+	router.GET("/export", func(c *gin.Context) {
+		// Each request sets the row the storage fails on with fail.
+		failAfter, _ := strconv.Atoi(c.DefaultQuery("fail", "-1"))
+		StreamExport(c, "rows", reflect.TypeOf(row{}), func(write func(v interface{}) error) error {
+			for idx := 0; idx < 2*ExportFlushRows; idx++ {
+				if idx == failAfter {
+					return errors.New("el almacenamiento no responde")
+				}
+				err := write(row{ID: idx})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	// Testea que un error antes de enviar filas se responda con un 500
+	response, err := http.Get(server.URL + "/export?format=ndjson&fail=0")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusInternalServerError, response.StatusCode)
+	response.Body.Close()
+
+	// Testea que un error con filas ya enviadas corte la conexion, asi la
+	// descarga no parece completa
+	response, err = http.Get(fmt.Sprintf("%s/export?format=ndjson&fail=%d", server.URL, ExportFlushRows+1))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	_, err = ioutil.ReadAll(response.Body)
+	assert.NotNil(t, err)
+	response.Body.Close()
+
+	response, err = http.Get(server.URL + "/export?format=ndjson")
+	assert.Nil(t, err)
+	_, err = ioutil.ReadAll(response.Body)
+	assert.Nil(t, err)
+	response.Body.Close()
+}
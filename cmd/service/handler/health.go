@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Healthz answers while the process is able to serve requests at all, the
+// liveness probe. It checks no dependency, so a broken disk does not get the
+// instance restarted in a loop.
+func Healthz() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": health.StatusOK})
+	}
+}
+
+// Readyz runs the checks and answers 503 when any failed, so the orchestrator
+// stops routing traffic to the instance. The body reports every check.
+func Readyz(checker *health.Checker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := checker.Run(c.Request.Context())
+
+		statusCode := http.StatusOK
+		if report.Status != health.StatusOK {
+			statusCode = http.StatusServiceUnavailable
+		}
+
+		c.JSON(statusCode, report)
+	}
+}
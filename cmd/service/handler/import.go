@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/users"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/web"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaxImportBytes caps the size of the imported files.
+const MaxImportBytes = 32 << 20
+
+const (
+	ImportModeAllOrNothing = "all_or_nothing"
+	ImportModeSkipInvalid  = "skip_invalid"
+)
+
+// ImportRequest reads the mode, upsert and mapping query params of an import.
+// It responds with the error and returns false when they are invalid.
+func ImportRequest(c *gin.Context) (options users.ImportOptions, mapping map[string]string, ok bool) {
+	if !CheckContentType(c, web.RowMediaTypes...) {
+		return options, nil, false
+	}
+
+	options, mapping, ok = importParams(c)
+	if !ok {
+		return options, nil, false
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxImportBytes)
+
+	return options, mapping, true
+}
+
+func importParams(c *gin.Context) (options users.ImportOptions, mapping map[string]string, ok bool) {
+	switch mode := c.DefaultQuery("mode", ImportModeAllOrNothing); mode {
+	case ImportModeAllOrNothing:
+	case ImportModeSkipInvalid:
+		options.SkipInvalid = true
+	default:
+		errMsg := fmt.Sprintf("mode debe ser %s o %s(recibido: %s)", ImportModeAllOrNothing, ImportModeSkipInvalid, mode)
+		RespondError(c, http.StatusBadRequest, errMsg)
+		return options, nil, false
+	}
+
+	if value := c.Query("upsert"); value != "" {
+		upsert, err := strconv.ParseBool(value)
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, fmt.Sprintf("upsert debe ser true o false(recibido: %s)", value))
+			return options, nil, false
+		}
+		options.Upsert = upsert
+	}
+
+	mapping, err := web.ParseHeaderMapping(c.Query("mapping"))
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, err.Error())
+		return options, nil, false
+	}
+
+	return options, mapping, true
+}
+
+// RespondImport answers with the import report, 200 when the accepted rows
+// were saved and 400 (422 on version 2) when nothing was saved.
+func RespondImport(c *gin.Context, report users.ImportReport, err error) {
+	if err != nil {
+		statusCode := http.StatusBadRequest
+		switch {
+		case errors.Is(err, users.ErrImportEmpty):
+			statusCode = validationStatus(c)
+		case strings.Contains(err.Error(), "request body too large"):
+			statusCode = http.StatusRequestEntityTooLarge
+			err = fmt.Errorf("el archivo no puede superar los %d MB", MaxImportBytes>>20)
+		}
+		RespondError(c, statusCode, err.Error())
+		return
+	}
+
+	response := NewImportReport(report)
+	if !report.Applied {
+		respondRejectedImport(c, validationStatus(c), response)
+		return
+	}
+
+	Respond(c, http.StatusOK, response)
+}
+
+// respondRejectedImport writes the report of an import that saved nothing,
+// the error shapes would leave it out.
+func respondRejectedImport(c *gin.Context, statusCode int, report web.ImportReport) {
+	errMsg := fmt.Sprintf("no se importo ninguna fila, %d filas con errores", len(report.Errors))
+	c.Abort()
+	// Kept for the line RequestLogger writes.
+	_ = c.Error(errors.New(errMsg))
+
+	if !wantsProblem(c) {
+		render(c, statusCode, web.Response{Code: strconv.Itoa(statusCode), Data: report, Error: errMsg})
+		return
+	}
+
+	problem := web.ImportProblem{Problem: web.NewProblem(statusCode, errMsg, c.Request.URL.RequestURI()), Report: report}
+
+	c.Header("Content-Type", web.ProblemMediaType)
+	c.JSON(statusCode, problem)
+}
+
+// NewImportReport represents the report of an import.
+func NewImportReport(report users.ImportReport) (response web.ImportReport) {
+	response = web.ImportReport{Total: report.Total, Applied: report.Applied, Accepted: []web.ImportedRow{}, Errors: []web.RowError{}}
+	for _, row := range report.Accepted {
+		response.Accepted = append(response.Accepted, web.ImportedRow{Line: row.Line, ID: row.ID, Action: row.Action})
+	}
+	for _, row := range report.Errors {
+		response.Errors = append(response.Errors, web.RowError{Line: row.Line, Error: row.Err.Error()})
+	}
+
+	return response
+}
@@ -0,0 +1,463 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/jobs"
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/users"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/web"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	JobImport     = "import"
+	JobExport     = "export"
+	JobBulkUpdate = "bulk_update"
+	JobBulkDelete = "bulk_delete"
+)
+
+var ErrImportRejected = errors.New("no se importo ningun usuario, el reporte lista los errores")
+
+// ImportJobParams are the params of an import job, its input file holds the
+// body of the request.
+type ImportJobParams struct {
+	MediaType string              `json:"media_type"`
+	Mapping   map[string]string   `json:"mapping"`
+	Options   users.ImportOptions `json:"options"`
+}
+
+// ExportJobParams are the params of an export job.
+type ExportJobParams struct {
+	Format string       `json:"format"`
+	Fields []string     `json:"fields"`
+	Filter users.Filter `json:"filter"`
+}
+
+// BulkJobParams are the params of the bulk update and delete jobs.
+type BulkJobParams struct {
+	Filter         users.Filter      `json:"filter"`
+	Options        users.BulkOptions `json:"options"`
+	PatchMediaType string            `json:"patch_media_type,omitempty"`
+	Patch          []byte            `json:"patch,omitempty"`
+}
+
+// RegisterJobs sets the handlers of the job types run on the users service.
+func RegisterJobs(runner *jobs.Runner, service users.Service) {
+	runner.Register(JobImport, func(ctx context.Context, execution *jobs.Execution) (result interface{}, err error) {
+		var params ImportJobParams
+		err = execution.Params(&params)
+		if err != nil {
+			return nil, err
+		}
+
+		input, err := os.Open(execution.InputPath())
+		if err != nil {
+			return nil, err
+		}
+		defer input.Close()
+
+		rows, err := users.ReadImportRows(params.MediaType, input, params.Mapping)
+		if err != nil {
+			return nil, err
+		}
+		execution.SetTotal(len(rows))
+
+		// The import is saved in a single write, so a run interrupted by a
+		// restart saved all of its rows or none. Running it again takes the
+		// rows it already saved as unchanged instead of as repeated emails.
+		params.Options.Resume = execution.Job().Attempts > 1
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		report, err := service.Import(ctx, rows, params.Options)
+		if err != nil {
+			return nil, err
+		}
+		execution.Advance(len(report.Accepted), len(report.Errors))
+
+		if !report.Applied {
+			return NewImportReport(report), ErrImportRejected
+		}
+
+		return NewImportReport(report), nil
+	})
+
+	runner.Register(JobExport, func(ctx context.Context, execution *jobs.Execution) (result interface{}, err error) {
+		var params ExportJobParams
+		err = execution.Params(&params)
+		if err != nil {
+			return nil, err
+		}
+
+		artifact, err := execution.CreateArtifact(params.Format)
+		if err != nil {
+			return nil, err
+		}
+		defer artifact.Close()
+
+		encoder, err := web.NewRowEncoder(artifact, web.StreamFormats[params.Format], reflect.TypeOf(users.User{}), params.Fields)
+		if err != nil {
+			return nil, err
+		}
+
+		// The users are filtered before writing any, so the total is known.
+		filtered, err := service.Filter(ctx, params.Filter)
+		if err != nil {
+			return nil, err
+		}
+		execution.SetTotal(len(filtered.Users))
+
+		for _, user := range filtered.Users {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+
+			execution.Advance(1, 0)
+
+			err = encoder.Encode(user)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return nil, encoder.Close()
+	})
+
+	bulk := func(ctx context.Context, execution *jobs.Execution) (result interface{}, err error) {
+		var params BulkJobParams
+		err = execution.Params(&params)
+		if err != nil {
+			return nil, err
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		var changes []users.BulkChange
+		if execution.Job().Type == JobBulkDelete {
+			changes, err = service.DeleteWhere(ctx, params.Filter, params.Options)
+		} else {
+			var patch users.PatchFunc
+			patch, err = NewPatch(params.PatchMediaType, params.Patch)
+			if err != nil {
+				return nil, err
+			}
+			changes, err = service.UpdateWhere(ctx, params.Filter, patch, params.Options)
+		}
+		if err != nil {
+			return nil, err
+		}
+		execution.SetTotal(len(changes))
+		execution.Advance(len(changes), 0)
+
+		return NewBulkResult(changes, params.Options.DryRun, func(user users.User) interface{} {
+			return user
+		})
+	}
+	runner.Register(JobBulkUpdate, bulk)
+	runner.Register(JobBulkDelete, bulk)
+}
+
+type Jobs struct {
+	runner *jobs.Runner
+}
+
+func CreateJobs(runner *jobs.Runner) *Jobs {
+	newJobs := &Jobs{
+		runner: runner,
+	}
+
+	return newJobs
+}
+
+// Create godoc
+// @Summary Start a job
+// @Tags Jobs
+// @Description Queues a long-running import, export, bulk_update or bulk_delete and answers right away with the job
+// @Description to poll. The params are those of the matching users route: an import sends the CSV or NDJSON file as
+// @Description the body with mode, upsert and mapping; an export sends format, fields and the listing filters; the
+// @Description bulk jobs send the filters, dry_run and X-Confirm-Count, plus the patch as the body of a bulk_update.
+// @Accept text/csv,application/x-ndjson,json,application/merge-patch+json,application/json-patch+json
+// @Produce json,xml,application/yaml,application/msgpack
+// @Param token header string true "token"
+// @Param type query string true "import, export, bulk_update or bulk_delete"
+// @Param X-Confirm-Count header int false "number of users the bulk job is expected to touch"
+// @Success 202 {object} web.Response{data=jobs.Job}
+// @Failure 400 {object} web.Response
+// @Failure 403 {object} web.Response
+// @Failure 406 {object} web.Response
+// @Failure 413 {object} web.Response
+// @Failure 415 {object} web.Response
+// @Failure 428 {object} web.Response
+// @Router /jobs [post]
+func (j *Jobs) Create() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := CheckAccessToken(c)
+		if err != nil {
+			RespondError(c, 403, err.Error())
+			return
+		}
+
+		jobType := c.Query("type")
+
+		var params interface{}
+		var input string
+		switch jobType {
+		case JobImport:
+			params, input, err = j.importJob(c)
+		case JobExport:
+			params, err = exportJob(c)
+		case JobBulkUpdate, JobBulkDelete:
+			params, err = bulkJob(c, jobType)
+		default:
+			RespondError(c, 400, fmt.Sprintf("%v, use %s, %s, %s o %s(recibido: %s)", jobs.ErrUnknownJobType, JobImport, JobExport, JobBulkUpdate, JobBulkDelete, jobType))
+			return
+		}
+		if c.IsAborted() {
+			return
+		}
+		if err != nil {
+			RespondError(c, 500, err.Error())
+			return
+		}
+
+		job, err := j.runner.Enqueue(jobType, params, input)
+		if err != nil {
+			RespondError(c, 500, err.Error())
+			return
+		}
+
+		c.Header("Location", path.Join(c.Request.URL.Path, strconv.FormatInt(job.ID, 10)))
+		Respond(c, http.StatusAccepted, jobView(job))
+	}
+}
+
+// importJob saves the body of an import in the input file of the job.
+func (j *Jobs) importJob(c *gin.Context) (params ImportJobParams, input string, err error) {
+	mediaType := c.ContentType()
+	if !web.IsRowMediaType(mediaType) {
+		RespondError(c, http.StatusUnsupportedMediaType, fmt.Sprintf("el tipo de contenido %s no es soportado, use text/csv o %s", mediaType, web.MIMENDJSON))
+		return params, input, nil
+	}
+
+	options, mapping, ok := importParams(c)
+	if !ok {
+		return params, input, nil
+	}
+
+	file, err := j.runner.InputFile(JobImport)
+	if err != nil {
+		return params, input, err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, http.MaxBytesReader(c.Writer, c.Request.Body, MaxImportBytes))
+	if err != nil {
+		os.Remove(file.Name())
+		if strings.Contains(err.Error(), "request body too large") {
+			RespondError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("el archivo no puede superar los %d MB", MaxImportBytes>>20))
+			return params, input, nil
+		}
+		return params, input, err
+	}
+
+	params = ImportJobParams{MediaType: mediaType, Mapping: mapping, Options: options}
+
+	return params, filepath.Base(file.Name()), nil
+}
+
+func exportJob(c *gin.Context) (params ExportJobParams, err error) {
+	format, _, fields, ok := ExportFormat(c, reflect.TypeOf(users.User{}))
+	if !ok {
+		return params, nil
+	}
+
+	filter, ok := bulkFilter(c, "type", "format", "fields")
+	if !ok {
+		return params, nil
+	}
+
+	return ExportJobParams{Format: format, Fields: fields, Filter: filter}, nil
+}
+
+func bulkJob(c *gin.Context, jobType string) (params BulkJobParams, err error) {
+	filter, ok := bulkFilter(c, "type", "dry_run")
+	if !ok {
+		return params, nil
+	}
+	if filter.IsEmpty() {
+		RespondError(c, ErrorStatus(c, users.ErrEmptyFilter), users.ErrEmptyFilter.Error())
+		return params, nil
+	}
+
+	options, ok := BulkOptions(c)
+	if !ok {
+		return params, nil
+	}
+
+	params = BulkJobParams{Filter: filter, Options: options}
+	if jobType == JobBulkDelete {
+		return params, nil
+	}
+
+	params.PatchMediaType = c.ContentType()
+	params.Patch, err = c.GetRawData()
+	if err != nil {
+		return params, err
+	}
+
+	// The patch is checked now so that a malformed body is not queued.
+	_, err = NewPatch(params.PatchMediaType, params.Patch)
+	if err != nil {
+		RespondBindingError(c, err)
+		return params, nil
+	}
+
+	return params, nil
+}
+
+// Get godoc
+// @Summary Get a job
+// @Tags Jobs
+// @Description Reports the status, progress, counts and errors of a job. Finished imports and bulk jobs carry their
+// @Description report in result, finished exports the artifact to download from /jobs/{id}/artifact.
+// @Produce json,xml,application/yaml,application/msgpack
+// @Param token header string true "token"
+// @Param id path int true "job id"
+// @Success 200 {object} web.Response{data=jobs.Job}
+// @Failure 400 {object} web.Response
+// @Failure 403 {object} web.Response
+// @Failure 404 {object} web.Response
+// @Failure 406 {object} web.Response
+// @Router /jobs/{id} [get]
+func (j *Jobs) Get() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, ok := j.job(c)
+		if !ok {
+			return
+		}
+
+		Respond(c, http.StatusOK, jobView(job))
+	}
+}
+
+// Cancel godoc
+// @Summary Cancel a job
+// @Tags Jobs
+// @Description Cancels a queued job right away and a running one as soon as it reaches a safe point.
+// @Produce json,xml,application/yaml,application/msgpack
+// @Param token header string true "token"
+// @Param id path int true "job id"
+// @Success 200 {object} web.Response{data=jobs.Job}
+// @Failure 400 {object} web.Response
+// @Failure 403 {object} web.Response
+// @Failure 404 {object} web.Response
+// @Failure 406 {object} web.Response
+// @Failure 409 {object} web.Response
+// @Router /jobs/{id}/cancel [post]
+func (j *Jobs) Cancel() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, ok := j.job(c)
+		if !ok {
+			return
+		}
+
+		job, err := j.runner.Cancel(job.ID)
+		if err != nil {
+			RespondError(c, jobErrorStatus(err), err.Error())
+			return
+		}
+
+		Respond(c, http.StatusOK, jobView(job))
+	}
+}
+
+// Artifact godoc
+// @Summary Download the file of a job
+// @Tags Jobs
+// @Description Downloads the file written by a finished export until it expires.
+// @Produce text/csv,application/x-ndjson,json
+// @Param token header string true "token"
+// @Param id path int true "job id"
+// @Success 200 {file} file
+// @Failure 400 {object} web.Response
+// @Failure 403 {object} web.Response
+// @Failure 404 {object} web.Response
+// @Failure 410 {object} web.Response
+// @Router /jobs/{id}/artifact [get]
+func (j *Jobs) Artifact() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, ok := j.job(c)
+		if !ok {
+			return
+		}
+
+		if job.ArtifactExpiresAt == nil {
+			RespondError(c, http.StatusNotFound, fmt.Sprintf("el trabajo no tiene un archivo para descargar(estado: %s)", job.Status))
+			return
+		}
+		if job.Artifact == "" || !time.Now().Before(*job.ArtifactExpiresAt) {
+			RespondError(c, http.StatusGone, jobs.ErrArtifactExpired.Error())
+			return
+		}
+
+		c.FileAttachment(j.runner.Path(job.Artifact), job.Artifact)
+	}
+}
+
+func (j *Jobs) job(c *gin.Context) (job jobs.Job, ok bool) {
+	err := CheckAccessToken(c)
+	if err != nil {
+		RespondError(c, 403, err.Error())
+		return job, false
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		RespondError(c, 400, err.Error())
+		return job, false
+	}
+
+	job, err = j.runner.Get(id)
+	if err != nil {
+		RespondError(c, jobErrorStatus(err), err.Error())
+		return job, false
+	}
+
+	return job, true
+}
+
+// jobView hides the params and the input file, they are only meant for the
+// handler of the job.
+func jobView(job jobs.Job) jobs.Job {
+	job.Params = nil
+	job.Input = ""
+
+	return job
+}
+
+func jobErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, jobs.ErrJobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, jobs.ErrJobFinished):
+		return http.StatusConflict
+	}
+
+	return http.StatusInternalServerError
+}
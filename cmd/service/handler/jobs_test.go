@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/jobs"
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/users"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportJob(t *testing.T) {
+	db := &memoryStore{}
+	assert.Nil(t, db.Write(&users.Users{Users: testUsers}))
+	service := users.CreateService(users.CreateRepository(db, logger.Nop()), logger.Nop())
+
+	runner := jobs.CreateRunner(jobs.CreateRepository(&memoryStore{}), t.TempDir(), 1, time.Hour, logger.Nop())
+	RegisterJobs(runner, service)
+	_, err := runner.Start()
+	assert.Nil(t, err)
+	defer runner.Stop(context.Background())
+
+	filter := users.Filter{Params: []string{"apellido"}, Searched: users.User{Apellido: "Perez"}}
+	job, err := runner.Enqueue(JobExport, ExportJobParams{Format: "ndjson", Filter: filter}, "")
+	assert.Nil(t, err)
+
+	// Testea que la exportacion informe el total de usuarios antes de escribirlos
+	for idx := 0; idx < 100 && job.Status != jobs.StatusSucceeded; idx++ {
+		time.Sleep(10 * time.Millisecond)
+		job, err = runner.Get(job.ID)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, jobs.StatusSucceeded, job.Status)
+	assert.Equal(t, jobs.Progress{Total: 2, Processed: 2}, job.Progress)
+}
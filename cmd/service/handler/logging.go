@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/logger"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/trace"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the id that correlates the log lines of a request.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the ids accepted from clients.
+const maxRequestIDLength = 128
+
+// probeRoutes are polled every few seconds by the orchestrator and the
+// metrics scraper, their successful requests are logged at debug level.
+var probeRoutes = map[string]bool{"/healthz": true, "/readyz": true, "/metrics": true}
+
+// RequestLogger propagates the X-Request-ID of the request, or generates one,
+// and logs the request once it is served with its id, route, user id, status
+// and latency, plus the trace id when Tracing runs before it. Handlers get the
+// logger with those fields through Logger.
+func RequestLogger(log *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		c.Header(RequestIDHeader, requestID)
+
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+
+		requestLog := log.With(logger.F("request_id", requestID), logger.F("method", c.Request.Method), logger.F("route", route))
+		if span := trace.FromContext(c.Request.Context()); span != nil {
+			requestLog = requestLog.With(logger.F("trace_id", span.SpanContext().TraceID.String()))
+		}
+		c.Request = c.Request.WithContext(logger.NewContext(c.Request.Context(), requestLog))
+
+		c.Next()
+
+		size := c.Writer.Size()
+		if size < 0 {
+			size = 0
+		}
+
+		fields := []logger.Field{
+			logger.F("status", c.Writer.Status()),
+			logger.F("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			logger.F("bytes", size),
+			logger.F("client_ip", c.ClientIP()),
+		}
+		if id := c.Param("id"); id != "" {
+			// The other resources, like jobs, have ids of their own.
+			key := "id"
+			if strings.Contains(route, "/users/") {
+				key = "user_id"
+			}
+			fields = append(fields, logger.F(key, id))
+		}
+		if lastErr := c.Errors.Last(); lastErr != nil {
+			fields = append(fields, logger.Err(lastErr.Err))
+		}
+
+		level := logger.InfoLevel
+		if probeRoutes[route] {
+			level = logger.DebugLevel
+		}
+		switch {
+		case c.Writer.Status() >= 500:
+			level = logger.ErrorLevel
+		case c.Writer.Status() >= 400:
+			level = logger.WarnLevel
+		}
+
+		requestLog.Log(level, "solicitud atendida", fields...)
+	}
+}
+
+// Logger returns the logger of the request, carrying its id and route, or
+// fallback when the request went through no RequestLogger.
+func Logger(c *gin.Context, fallback *logger.Logger) *logger.Logger {
+	return logger.FromContext(c.Request.Context(), fallback)
+}
+
+// validRequestID accepts the printable ASCII ids of a reasonable length, so a
+// client can't inject line breaks or huge values in the logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, r := range id {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	_, err := rand.Read(id)
+	if err != nil {
+		return time.Now().UTC().Format("20060102T150405.000000000")
+	}
+
+	return hex.EncodeToString(id)
+}
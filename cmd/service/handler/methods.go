@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Options answers OPTIONS requests with the methods registered for the path.
+func Options(router *gin.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Allow", strings.Join(allowedMethods(router, c.Request.URL.Path), ", "))
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// MethodNotAllowed is meant to be registered with router.NoMethod, it adds the
+// Allow header that gin leaves out of its 405 responses. The route groups
+// don't run for them, so the error takes the shape of the version of the path,
+// or of the X-API-Version header and defaultVersion on the unversioned paths.
+func MethodNotAllowed(router *gin.Engine, defaultVersion int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		version := unroutedVersion(c, defaultVersion)
+		c.Set(apiVersionKey, version)
+		c.Header("X-API-Version", strconv.Itoa(version))
+
+		methods := allowedMethods(router, c.Request.URL.Path)
+		c.Header("Allow", strings.Join(methods, ", "))
+
+		errMsg := "el metodo " + c.Request.Method + " no esta permitido, metodos permitidos: " + strings.Join(methods, ", ")
+		RespondError(c, http.StatusMethodNotAllowed, errMsg)
+	}
+}
+
+func allowedMethods(router *gin.Engine, path string) (methods []string) {
+	found := map[string]bool{}
+	for _, route := range router.Routes() {
+		if matchRoute(route.Path, path) {
+			found[route.Method] = true
+		}
+	}
+	found[http.MethodOptions] = true
+
+	for method := range found {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	return methods
+}
+
+// matchRoute compares a request path against a gin route pattern,
+// treating :params as single segments and *params as the rest of the path.
+func matchRoute(pattern string, path string) bool {
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+
+	for idx, segment := range patternSegments {
+		if strings.HasPrefix(segment, "*") {
+			return true
+		}
+		if idx >= len(pathSegments) {
+			return false
+		}
+		if !strings.HasPrefix(segment, ":") && segment != pathSegments[idx] {
+			return false
+		}
+	}
+
+	return len(patternSegments) == len(pathSegments)
+}
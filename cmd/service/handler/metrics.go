@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// authFailureKey holds why CheckAccessToken rejected the request.
+const authFailureKey = "auth_failure"
+
+// Metrics counts the requests and records their latency by route and status,
+// along with the requests rejected by CheckAccessToken. Requests to unknown
+// routes share the route label "unmatched".
+func Metrics(registry *metrics.Registry) gin.HandlerFunc {
+	requests := registry.Counter("http_requests_total", "Requests served.", "method", "route", "status")
+	duration := registry.Histogram("http_request_duration_seconds", "Latency of the requests.", metrics.DurationBuckets, "method", "route", "status")
+	authFailures := registry.Counter("auth_failures_total", "Requests rejected for a missing or wrong access token.", "reason")
+
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+
+		requests.Inc(c.Request.Method, route, status)
+		duration.Observe(time.Since(start).Seconds(), c.Request.Method, route, status)
+
+		if reason := c.GetString(authFailureKey); reason != "" {
+			authFailures.Inc(reason)
+		}
+	}
+}
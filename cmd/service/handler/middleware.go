@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/idempotency"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/logger"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// abortConnectionKey marks the requests whose connection is cut once they
+// are done.
+const abortConnectionKey = "abort_connection"
+
+// AbortConnection aborts a request whose response is already under way and
+// can no longer carry an error, so that AbortConnections cuts its connection.
+func AbortConnection(c *gin.Context) {
+	c.Set(abortConnectionKey, true)
+	c.Abort()
+}
+
+// AbortConnections cuts the connection of the requests marked by
+// AbortConnection, panicking with http.ErrAbortHandler so the server closes it
+// without ending the response, and the client sees it fail instead of taking
+// it as complete. It goes before every other middleware, so the request is
+// still logged and measured and gin.Recovery does not take it for a bug.
+func AbortConnections() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if c.GetBool(abortConnectionKey) {
+			panic(http.ErrAbortHandler)
+		}
+	}
+}
+
+// RateLimit limits requests per client, identified by its token when the
+// service knows it and by its IP otherwise. Reads and writes use separate
+// buckets.
+func RateLimit(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := rateLimitKey(c, limiter)
+
+		class := ratelimit.Write
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			class = ratelimit.Read
+		}
+
+		result := limiter.Allow(key, class)
+		if result.Limit > 0 {
+			c.Header("RateLimit-Limit", strconv.FormatInt(result.Limit, 10))
+			c.Header("RateLimit-Remaining", strconv.FormatInt(result.Remaining, 10))
+			c.Header("RateLimit-Reset", strconv.FormatInt(int64(math.Ceil(result.Reset.Seconds())), 10))
+		}
+
+		if !result.Allowed {
+			retryAfter := int64(math.Ceil(result.RetryAfter.Seconds()))
+			c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+
+			errMsg := fmt.Sprintf("se excedio el limite de solicitudes, intente de nuevo en %d segundos", retryAfter)
+			RespondError(c, http.StatusTooManyRequests, errMsg)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// rateLimitKey returns the token sent when it is the access token or a key
+// with a tier, and the IP of the client otherwise. The limit runs before the
+// token is checked, so an unknown token would get a new bucket on every
+// request.
+func rateLimitKey(c *gin.Context, limiter *ratelimit.Limiter) string {
+	token := c.GetHeader("token")
+	if token != "" && (limiter.HasKey(token) || isAccessToken(token)) {
+		return token
+	}
+
+	return c.ClientIP()
+}
+
+// Idempotency replays the stored response when a request is retried with the
+// same Idempotency-Key, and rejects the key if it is reused with another body.
+// The keys are scoped to the client that sent them, so a client can neither
+// replay nor collide with the responses of another one.
+func Idempotency(repository idempotency.Repository, locker *idempotency.KeyLocker, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		// The body is read before the handler, which would cap it too late.
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxImportBytes)
+		body, err := ioutil.ReadAll(c.Request.Body)
+		if err != nil {
+			statusCode := http.StatusBadRequest
+			if strings.Contains(err.Error(), "request body too large") {
+				statusCode = http.StatusRequestEntityTooLarge
+				err = fmt.Errorf("el cuerpo de la solicitud no puede superar los %d MB", MaxImportBytes>>20)
+			}
+			RespondError(c, statusCode, err.Error())
+			return
+		}
+		c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		fingerprint := idempotency.Fingerprint(c.Request.Method, c.FullPath(), body)
+		scopedKey := idempotencyScope(c) + ":" + key
+
+		unlock := locker.Lock(scopedKey)
+		defer unlock()
+
+		record, found, err := repository.Get(scopedKey)
+		if err != nil {
+			RespondError(c, 500, err.Error())
+			return
+		}
+
+		if found {
+			if record.Fingerprint != fingerprint {
+				errMsg := "la clave de idempotencia ya fue usada con una solicitud diferente"
+				RespondError(c, http.StatusUnprocessableEntity, errMsg)
+				return
+			}
+
+			c.Header("Idempotent-Replayed", "true")
+			if record.Location != "" {
+				c.Header("Location", record.Location)
+			}
+			c.Data(record.StatusCode, record.ContentType, record.Body)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		c.Next()
+
+		// Server errors, timeouts included, and requests whose client left are
+		// not stored so the client can retry them.
+		if recorder.Status() >= 500 || recorder.Status() == StatusClientClosedRequest {
+			return
+		}
+
+		now := time.Now()
+		record = idempotency.Record{
+			Key:         scopedKey,
+			Fingerprint: fingerprint,
+			StatusCode:  recorder.Status(),
+			ContentType: recorder.Header().Get("Content-Type"),
+			Location:    recorder.Header().Get("Location"),
+			Body:        recorder.body.Bytes(),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(ttl),
+		}
+
+		err = repository.Save(record)
+		if err != nil {
+			Logger(c, nil).Error("no se pudo guardar la respuesta idempotente", logger.F("idempotency_key", key), logger.Err(err))
+		}
+	}
+}
+
+// idempotencyScope identifies the client of an idempotent request by its
+// token, hashed so that the stored keys don't reveal it, or by its IP when it
+// sends none.
+func idempotencyScope(c *gin.Context) string {
+	token := c.GetHeader("token")
+	if token == "" {
+		return "ip:" + c.ClientIP()
+	}
+	sum := sha256.Sum256([]byte(token))
+
+	return "token:" + hex.EncodeToString(sum[:8])
+}
+
+// responseRecorder keeps a copy of the body written by the handlers.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
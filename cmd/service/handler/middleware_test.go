@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// serve sends a request to router with the given headers.
+func serve(router http.Handler, method string, target string, body string, headers map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	return response
+}
+
+func TestRateLimit(t *testing.T) {
+	UseAuth(true, "token123")
+	tiers := []ratelimit.Tier{
+		{Name: ratelimit.DefaultTier, Write: ratelimit.Limit{Requests: 1, Period: time.Minute}},
+		{Name: "batch", Write: ratelimit.Limit{Requests: 5, Period: time.Minute}},
+	}
+	limiter := ratelimit.CreateLimiter(tiers, map[string]string{"token-batch": "batch"})
+
+	router := gin.New()
+	router.POST("/users", RateLimit(limiter), func(c *gin.Context) { c.Status(http.StatusCreated) })
+
+	// Testea que cambiar de token en cada solicitud no de un bucket nuevo
+	for idx, status := range []int{http.StatusCreated, http.StatusTooManyRequests} {
+		response := serve(router, http.MethodPost, "/users", "", map[string]string{"token": fmt.Sprintf("random-%d", idx)})
+		assert.Equal(t, status, response.Code)
+	}
+
+	// Testea que los tokens conocidos tengan su propio bucket
+	response := serve(router, http.MethodPost, "/users", "", map[string]string{"token": "token123"})
+	assert.Equal(t, http.StatusCreated, response.Code)
+	response = serve(router, http.MethodPost, "/users", "", map[string]string{"token": "token-batch"})
+	assert.Equal(t, http.StatusCreated, response.Code)
+	assert.Equal(t, "5", response.Header().Get("RateLimit-Limit"))
+}
+
+func TestIdempotency(t *testing.T) {
+	router, db := newUsersRouter(t)
+	headers := map[string]string{"token": "token123", "X-API-Version": "2", "Content-Type": "application/json", "Idempotency-Key": "alta-eva"}
+	body := `{"nombre":"Eva","apellido":"Diaz","email":"eva@email.com","altura":1.6,"activo":true,"fecha_de_nacimiento":"1990-01-01"}`
+
+	created := serve(router, http.MethodPost, "/users/", body, headers)
+	assert.Equal(t, http.StatusCreated, created.Code)
+	assert.Equal(t, "/users/4", created.Header().Get("Location"))
+
+	// Testea que un reintento reciba la misma respuesta, con su Location, sin crear otro usuario
+	replayed := serve(router, http.MethodPost, "/users/", body, headers)
+	assert.Equal(t, http.StatusCreated, replayed.Code)
+	assert.Equal(t, "true", replayed.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, "/users/4", replayed.Header().Get("Location"))
+	assert.Equal(t, created.Body.String(), replayed.Body.String())
+	assert.Len(t, storedUsers(t, db), 4)
+
+	// Testea que la clave no se pueda usar con otro cuerpo
+	other := serve(router, http.MethodPost, "/users/", strings.Replace(body, "Eva", "Eve", 1), headers)
+	assert.Equal(t, http.StatusUnprocessableEntity, other.Code)
+
+	// Testea que las claves de un cliente no las pueda usar otro
+	headers["token"] = "otro-token"
+	foreign := serve(router, http.MethodPost, "/users/", body, headers)
+	assert.Equal(t, http.StatusForbidden, foreign.Code)
+	assert.Empty(t, foreign.Header().Get("Idempotent-Replayed"))
+	headers["token"] = "token123"
+
+	// Testea que los duplicados concurrentes creen un solo usuario
+	headers["Idempotency-Key"] = "alta-leo"
+	body = strings.NewReplacer("Eva", "Leo", "eva@", "leo@").Replace(body)
+	responses := make([]string, 8)
+	var wg sync.WaitGroup
+	for idx := range responses {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			responses[idx] = serve(router, http.MethodPost, "/users/", body, headers).Body.String()
+		}(idx)
+	}
+	wg.Wait()
+	for _, response := range responses {
+		assert.Equal(t, responses[0], response)
+	}
+	assert.Len(t, storedUsers(t, db), 5)
+
+	// Testea que el limite del cuerpo valga antes de leerlo para la huella
+	headers["Idempotency-Key"] = "importacion-grande"
+	headers["Content-Type"] = "text/csv"
+	large := serve(router, http.MethodPost, "/users/import", strings.Repeat("a", MaxImportBytes+1), headers)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, large.Code)
+}
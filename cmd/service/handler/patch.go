@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/users"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/jsonpatch"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PatchMediaTypes are the patch formats accepted by PATCH besides the plain
+// bodies with the fields to change.
+var PatchMediaTypes = []string{jsonpatch.MIMEMergePatch, jsonpatch.MIMEJSONPatch}
+
+// IsPatchMediaType tells whether the body is a merge patch or a JSON patch.
+func IsPatchMediaType(contentType string) bool {
+	for _, mediaType := range PatchMediaTypes {
+		if contentType == mediaType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetAcceptPatch advertises the patch formats, as RFC 5789 recommends.
+func SetAcceptPatch(c *gin.Context) {
+	c.Header("Accept-Patch", strings.Join(PatchMediaTypes, ", "))
+}
+
+// ApplyPatch applies the patch to the JSON representation of v and decodes
+// the result back into it. Members removed by the patch are left at their
+// zero value and members unknown to v are rejected.
+func ApplyPatch(mediaType string, patch []byte, v interface{}) (err error) {
+	document, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	patched, err := jsonpatch.Apply(mediaType, document, patch)
+	if err != nil {
+		return err
+	}
+
+	value := reflect.ValueOf(v).Elem()
+	value.Set(reflect.Zero(value.Type()))
+
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+
+	err = decoder.Decode(v)
+	if err != nil {
+		return fmt.Errorf("%w, el resultado del patch no es un usuario: %v", users.ErrInvalidUser, err)
+	}
+
+	return nil
+}
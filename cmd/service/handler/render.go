@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/web"
+
+	"github.com/gin-gonic/gin"
+)
+
+const responseFormatKey = "response_format"
+
+// Negotiate resolves the response format from the Accept header before the
+// handler runs, so that unacceptable requests get a 406 before any change is made.
+func Negotiate(offered ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format, err := web.NegotiateFormat(c.GetHeader("Accept"), offered)
+		if err != nil {
+			RespondError(c, http.StatusNotAcceptable, err.Error())
+			return
+		}
+
+		c.Set(responseFormatKey, format)
+		c.Header("Vary", "Accept")
+		c.Next()
+	}
+}
+
+// Respond writes data wrapped in a web.Response using the negotiated format.
+func Respond(c *gin.Context, statusCode int, data interface{}) {
+	render(c, statusCode, web.NewResponse(statusCode, data, ""))
+}
+
+// render writes the response using the negotiated format. CSV has no
+// envelope, it only carries the records.
+func render(c *gin.Context, statusCode int, response web.Response) {
+	format := c.GetString(responseFormatKey)
+	if format == "" {
+		format = web.MIMEJSON
+	}
+
+	var body interface{} = response
+	if format == web.MIMECSV {
+		body = response.Data
+	}
+
+	encoded, err := web.Marshal(format, body)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.Data(statusCode, web.ContentType(format), encoded)
+}
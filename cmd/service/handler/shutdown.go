@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+var ErrShuttingDown = errors.New("el servidor se esta apagando, reintente en otra instancia")
+
+// Drainer marks the server as shutting down, from the signal until the
+// in-flight requests finish.
+type Drainer struct {
+	draining int32
+}
+
+func (d *Drainer) Start() {
+	atomic.StoreInt32(&d.draining, 1)
+}
+
+func (d *Drainer) Draining() bool {
+	return atomic.LoadInt32(&d.draining) == 1
+}
+
+// Check fails the readiness probe while draining, so the orchestrator stops
+// routing traffic to the instance.
+func (d *Drainer) Check() (err error) {
+	if d.Draining() {
+		return ErrShuttingDown
+	}
+
+	return nil
+}
+
+// RejectWhileDraining answers 503 to the mutations that arrive once the
+// shutdown started, so none starts a write the process may not live to
+// finish. Reads are still served, and the mutations already in flight go on.
+func RejectWhileDraining(d *Drainer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !d.Draining() {
+			c.Next()
+			return
+		}
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		c.Header("Connection", "close")
+		c.Header("Retry-After", "1")
+		RespondError(c, http.StatusServiceUnavailable, ErrShuttingDown.Error())
+	}
+}
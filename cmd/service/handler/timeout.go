@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StatusClientClosedRequest answers the requests whose client left before
+// they were served, as nginx does. Nobody reads it, but the access log and
+// the metrics tell them apart from the failures of the service.
+const StatusClientClosedRequest = 499
+
+// Timeout gives each request a deadline of timeout, after which the service
+// and the stores give up and the request ends in 503. A timeout of zero sets
+// no deadline. The routes in streaming, given by their full path, get none
+// either: they write as they read, for as long as the client keeps reading.
+// The large imports are meant to go through jobs, which are bound to no
+// request.
+func Timeout(timeout time.Duration, streaming ...string) gin.HandlerFunc {
+	skipped := map[string]bool{}
+	for _, fullPath := range streaming {
+		skipped[fullPath] = true
+	}
+
+	return func(c *gin.Context) {
+		if timeout <= 0 || skipped[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeout(t *testing.T) {
+	router := gin.New()
+	router.Use(Timeout(time.Minute, "/users/export"))
+	deadline := func(c *gin.Context) {
+		if _, found := c.Request.Context().Deadline(); found {
+			c.Status(http.StatusOK)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+	router.GET("/users/export", deadline)
+	router.GET("/users/:id", deadline)
+
+	// Testea que las exportaciones, que se transmiten, no tengan el limite de tiempo
+	response := serve(router, http.MethodGet, "/users/export", "", nil)
+	assert.Equal(t, http.StatusNoContent, response.Code)
+
+	response = serve(router, http.MethodGet, "/users/1", "", nil)
+	assert.Equal(t, http.StatusOK, response.Code)
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/trace"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TraceparentHeader carries the W3C trace context of the caller.
+const TraceparentHeader = "traceparent"
+
+// Tracing starts the span of each request, child of the traceparent sent by
+// the client when there is a valid one, and puts it in the context of the
+// request so the service, repository and store spans hang from it. Requests
+// served with a 5xx mark their span as failed. The probes are not traced.
+func Tracing(tracer *trace.Tracer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// An invalid traceparent starts a new trace, as the W3C spec asks.
+		remote, _ := trace.ParseTraceparent(c.GetHeader(TraceparentHeader))
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		if probeRoutes[route] {
+			c.Next()
+			return
+		}
+
+		ctx, span := tracer.StartRoot(c.Request.Context(), c.Request.Method+" "+route, trace.KindServer, remote)
+		span.SetAttribute("http.method", c.Request.Method)
+		span.SetAttribute("http.route", route)
+		span.SetAttribute("http.target", c.Request.URL.Path)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttribute("http.status_code", status)
+		if status >= 500 {
+			err := fmt.Errorf("respuesta con estado %d", status)
+			if lastErr := c.Errors.Last(); lastErr != nil {
+				err = lastErr.Err
+			}
+			span.RecordError(err)
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,744 @@
+package v2
+
+import (
+	"net/http"
+	"path"
+	"reflect"
+	"strconv"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/cmd/service/handler"
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/privacy"
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/users"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/logger"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/web"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @title MeLi Bootcamp API
+// @version 2.0
+// @description API built to manage users. Version 2 uses English field names, ISO-8601 dates and resource-style routes.
+// @termsOfService https://developers.mercadolibre.com.co/es_ar/terminos-y-condiciones
+
+// @contact.name API Support
+// @contact.url https://developers.mercadolibre.com.ar/support
+
+// @license.name Apache 2.0
+// @license.url https://www.apache.org/licenses/LICENSE-2.0
+
+// @BasePath /v2
+
+// Controller serves version 2 of the users API from the same users.Service
+// as version 1, mapping between users.User and User.
+type Controller struct {
+	service users.Service
+	privacy privacy.Service
+	logger  *logger.Logger
+}
+
+func CreateController(s users.Service, p privacy.Service, log *logger.Logger) *Controller {
+	newController := &Controller{
+		service: s,
+		privacy: p,
+		logger:  log,
+	}
+
+	return newController
+}
+
+// List godoc
+// @Summary List users
+// @Tags Users
+// @Description List users, optionally filtered by the given query params
+// @Accept json
+// @Produce json,xml,application/yaml,text/csv,application/msgpack
+// @Param token header string true "token"
+// @Param id query int false "user id"
+// @Param first_name query string false "user first name"
+// @Param last_name query string false "user last name"
+// @Param email query string false "user email"
+// @Param age query int false "user age, computed from birth_date"
+// @Param birth_date query string false "user birth date (yyyy-mm-dd)"
+// @Param height query number false "user height"
+// @Param active query bool false "user active"
+// @Param created_after query string false "created after this RFC 3339 timestamp or yyyy-mm-dd date"
+// @Param created_before query string false "created before this RFC 3339 timestamp or yyyy-mm-dd date"
+// @Success 200 {object} web.Response{data=Users}
+// @Failure 400 {object} web.Problem
+// @Failure 403 {object} web.Problem
+// @Failure 406 {object} web.Problem
+// @Router /users [get]
+func (u *Controller) List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := handler.CheckAccessToken(c)
+		if err != nil {
+			handler.RespondError(c, http.StatusForbidden, err.Error())
+			return
+		}
+
+		filter, err := filterFromQuery(c)
+		if err != nil {
+			handler.RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		filteredUsers, err := u.service.Filter(c.Request.Context(), filter)
+		if err != nil {
+			handler.RespondError(c, handler.ErrorStatus(c, err), err.Error())
+			return
+		}
+
+		handler.Respond(c, http.StatusOK, toUsers(filteredUsers))
+	}
+}
+
+// Get godoc
+// @Summary Get a user
+// @Tags Users
+// @Description Get the user with the given id
+// @Accept json
+// @Produce json,xml,application/yaml,application/msgpack
+// @Param token header string true "token"
+// @Param id path int true "user id"
+// @Success 200 {object} web.Response{data=User}
+// @Failure 400 {object} web.Problem
+// @Failure 403 {object} web.Problem
+// @Failure 404 {object} web.Problem
+// @Failure 406 {object} web.Problem
+// @Router /users/{id} [get]
+func (u *Controller) Get() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := handler.CheckAccessToken(c)
+		if err != nil {
+			handler.RespondError(c, http.StatusForbidden, err.Error())
+			return
+		}
+
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			handler.RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		user, err := u.service.GetUserByID(c.Request.Context(), id)
+		if err != nil {
+			handler.RespondError(c, handler.ErrorStatus(c, err), err.Error())
+			return
+		}
+
+		handler.Respond(c, http.StatusOK, toUser(user))
+	}
+}
+
+// Create godoc
+// @Summary Create a user
+// @Tags Users
+// @Description Create a user, created_at and updated_at are assigned by the server
+// @Accept json,xml,application/yaml,text/csv,application/msgpack
+// @Produce json,xml,application/yaml,application/msgpack
+// @Param token header string true "token"
+// @Param Idempotency-Key header string false "key to safely retry the request"
+// @Param user body User true "user to create, id, active and timestamps are ignored"
+// @Success 201 {object} web.Response{data=User}
+// @Failure 400 {object} web.Problem
+// @Failure 403 {object} web.Problem
+// @Failure 406 {object} web.Problem
+// @Failure 409 {object} web.Problem
+// @Failure 415 {object} web.Problem
+// @Failure 422 {object} web.Problem
+// @Router /users [post]
+func (u *Controller) Create() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := handler.CheckAccessToken(c)
+		if err != nil {
+			handler.RespondError(c, http.StatusForbidden, err.Error())
+			return
+		}
+
+		if !handler.CheckContentType(c, web.BodyMediaTypes()...) {
+			return
+		}
+
+		var userV2 User
+		err = c.ShouldBindBodyWith(&userV2, web.BodyBinding(c.ContentType()))
+		if err != nil {
+			handler.RespondBindingError(c, err)
+			return
+		}
+
+		user, err := u.service.Create(c.Request.Context(), fromUser(userV2))
+		if err != nil {
+			handler.RespondError(c, handler.ErrorStatus(c, err), err.Error())
+			return
+		}
+
+		c.Header("Location", path.Join(c.Request.URL.Path, strconv.FormatInt(user.Id, 10)))
+		handler.Respond(c, http.StatusCreated, toUser(user))
+	}
+}
+
+// Replace godoc
+// @Summary Replace a user
+// @Tags Users
+// @Description Replace first_name, last_name, email, birth_date (or age) and height of a user
+// @Accept json,xml,application/yaml,text/csv,application/msgpack
+// @Produce json,xml,application/yaml,application/msgpack
+// @Param token header string true "token"
+// @Param id path int true "user id"
+// @Param user body User true "new values, id, active and timestamps are ignored"
+// @Success 200 {object} web.Response{data=User}
+// @Failure 400 {object} web.Problem
+// @Failure 403 {object} web.Problem
+// @Failure 404 {object} web.Problem
+// @Failure 406 {object} web.Problem
+// @Failure 409 {object} web.Problem
+// @Failure 415 {object} web.Problem
+// @Failure 422 {object} web.Problem
+// @Router /users/{id} [put]
+func (u *Controller) Replace() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := handler.CheckAccessToken(c)
+		if err != nil {
+			handler.RespondError(c, http.StatusForbidden, err.Error())
+			return
+		}
+
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			handler.RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if !handler.CheckContentType(c, web.BodyMediaTypes()...) {
+			return
+		}
+
+		var userV2 User
+		err = c.ShouldBindBodyWith(&userV2, web.BodyBinding(c.ContentType()))
+		if err != nil {
+			handler.RespondBindingError(c, err)
+			return
+		}
+
+		user, err := u.service.Replace(c.Request.Context(), id, fromUser(userV2))
+		if err != nil {
+			handler.RespondError(c, handler.ErrorStatus(c, err), err.Error())
+			return
+		}
+
+		handler.Respond(c, http.StatusOK, toUser(user))
+	}
+}
+
+// Update godoc
+// @Summary Partially update a user
+// @Tags Users
+// @Description Update any mutable field of a user with a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902),
+// @Description or update last_name and/or birth_date (or age) with a plain body.
+// @Description The whole patch is applied in a single write or not at all, a failed test op returns 409.
+// @Accept json,xml,application/yaml,text/csv,application/msgpack,application/merge-patch+json,application/json-patch+json
+// @Produce json,xml,application/yaml,application/msgpack
+// @Param token header string true "token"
+// @Param id path int true "user id"
+// @Param user body PartialUser true "fields to update"
+// @Success 200 {object} web.Response{data=User}
+// @Failure 400 {object} web.Problem
+// @Failure 403 {object} web.Problem
+// @Failure 404 {object} web.Problem
+// @Failure 406 {object} web.Problem
+// @Failure 409 {object} web.Problem
+// @Failure 415 {object} web.Problem
+// @Failure 422 {object} web.Problem
+// @Router /users/{id} [patch]
+func (u *Controller) Update() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := handler.CheckAccessToken(c)
+		if err != nil {
+			handler.RespondError(c, http.StatusForbidden, err.Error())
+			return
+		}
+
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			handler.RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		patch, ok := bindPatch(c)
+		if !ok {
+			return
+		}
+
+		user, err := u.service.Patch(c.Request.Context(), id, patch)
+		if err != nil {
+			handler.RespondError(c, handler.ErrorStatus(c, err), err.Error())
+			return
+		}
+
+		handler.Respond(c, http.StatusOK, toUser(user))
+	}
+}
+
+// Batch godoc
+// @Summary Create, update and delete users in bulk
+// @Tags Users
+// @Description Runs a list of create, update and delete operations in order and saves them in a single write.
+// @Description Each operation gets the status it would have had as a single request and the response is 207 when any
+// @Description of them failed. With atomic=true nothing is saved if any operation fails and the others report 424.
+// @Accept json,application/yaml,application/msgpack
+// @Produce json,xml,application/yaml,application/msgpack
+// @Param token header string true "token"
+// @Param Idempotency-Key header string false "key to safely retry the request"
+// @Param atomic query bool false "roll back every operation if any of them fails"
+// @Param operations body []BatchOperation true "operations, id is ignored for create"
+// @Success 200 {object} web.Response{data=web.BatchResults}
+// @Success 207 {object} web.Response{data=web.BatchResults}
+// @Failure 400 {object} web.Problem
+// @Failure 403 {object} web.Problem
+// @Failure 406 {object} web.Problem
+// @Failure 413 {object} web.Problem
+// @Failure 415 {object} web.Problem
+// @Failure 422 {object} web.Problem
+// @Router /users/batch [post]
+func (u *Controller) Batch() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := handler.CheckAccessToken(c)
+		if err != nil {
+			handler.RespondError(c, http.StatusForbidden, err.Error())
+			return
+		}
+
+		var operations []BatchOperation
+		atomic, ok := handler.BindBatch(c, &operations)
+		if !ok {
+			return
+		}
+
+		batch := make([]users.BatchOperation, len(operations))
+		for idx, operation := range operations {
+			batch[idx] = users.BatchOperation{Op: operation.Op, ID: operation.ID, User: fromUser(operation.User)}
+		}
+
+		results, err := u.service.Batch(c.Request.Context(), batch, atomic)
+		if err != nil {
+			handler.RespondError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		batchResults := make([]web.BatchResult, len(results))
+		for idx, result := range results {
+			var data interface{}
+			if result.User != nil {
+				data = toUser(*result.User)
+			}
+			batchResults[idx] = handler.NewBatchResult(c, result, data)
+		}
+
+		handler.RespondBatch(c, batchResults)
+	}
+}
+
+// BulkUpdate godoc
+// @Summary Update every user matching the filters
+// @Tags Users
+// @Description Applies the same patch (merge patch, JSON patch or plain body, as in PATCH /users/{id}) to every user
+// @Description matching the filters of GET /users, in a single write. If it fails for any user nothing is saved.
+// @Description Requires the X-Confirm-Count header with the number of matched users (428 when missing, 412 when
+// @Description it doesn't match), unless dry_run=true.
+// @Accept json,xml,application/yaml,text/csv,application/msgpack,application/merge-patch+json,application/json-patch+json
+// @Produce json,xml,application/yaml,application/msgpack
+// @Param token header string true "token"
+// @Param X-Confirm-Count header int false "number of users expected to match, required unless dry_run=true"
+// @Param dry_run query bool false "return the matched ids and changes without saving them"
+// @Param id query int false "user id"
+// @Param first_name query string false "user first name"
+// @Param last_name query string false "user last name"
+// @Param email query string false "user email"
+// @Param age query int false "user age, computed from birth_date"
+// @Param birth_date query string false "user birth date (yyyy-mm-dd)"
+// @Param height query number false "user height"
+// @Param active query bool false "user active"
+// @Param created_after query string false "created after this RFC 3339 timestamp or yyyy-mm-dd date"
+// @Param created_before query string false "created before this RFC 3339 timestamp or yyyy-mm-dd date"
+// @Param user body PartialUser true "fields to update"
+// @Success 200 {object} web.Response{data=web.BulkResult}
+// @Failure 400 {object} web.Problem
+// @Failure 403 {object} web.Problem
+// @Failure 406 {object} web.Problem
+// @Failure 409 {object} web.Problem
+// @Failure 412 {object} web.Problem
+// @Failure 415 {object} web.Problem
+// @Failure 422 {object} web.Problem
+// @Failure 428 {object} web.Problem
+// @Router /users [patch]
+func (u *Controller) BulkUpdate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := handler.CheckAccessToken(c)
+		if err != nil {
+			handler.RespondError(c, http.StatusForbidden, err.Error())
+			return
+		}
+
+		filter, err := strictFilterFromQuery(c, "dry_run")
+		if err != nil {
+			handler.RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		options, ok := handler.BulkOptions(c)
+		if !ok {
+			return
+		}
+
+		patch, ok := bindPatch(c)
+		if !ok {
+			return
+		}
+
+		changes, err := u.service.UpdateWhere(c.Request.Context(), filter, patch, options)
+		if err != nil {
+			handler.RespondError(c, handler.ErrorStatus(c, err), err.Error())
+			return
+		}
+
+		respondBulk(c, changes, options)
+	}
+}
+
+// BulkDelete godoc
+// @Summary Delete every user matching the filters
+// @Tags Users
+// @Description Deletes every user matching the filters of GET /users in a single write.
+// @Description Requires the X-Confirm-Count header with the number of matched users (428 when missing, 412 when
+// @Description it doesn't match), unless dry_run=true.
+// @Accept json
+// @Produce json,xml,application/yaml,application/msgpack
+// @Param token header string true "token"
+// @Param X-Confirm-Count header int false "number of users expected to match, required unless dry_run=true"
+// @Param dry_run query bool false "return the matched ids without deleting them"
+// @Param id query int false "user id"
+// @Param first_name query string false "user first name"
+// @Param last_name query string false "user last name"
+// @Param email query string false "user email"
+// @Param age query int false "user age, computed from birth_date"
+// @Param birth_date query string false "user birth date (yyyy-mm-dd)"
+// @Param height query number false "user height"
+// @Param active query bool false "user active"
+// @Param created_after query string false "created after this RFC 3339 timestamp or yyyy-mm-dd date"
+// @Param created_before query string false "created before this RFC 3339 timestamp or yyyy-mm-dd date"
+// @Success 200 {object} web.Response{data=web.BulkResult}
+// @Failure 400 {object} web.Problem
+// @Failure 403 {object} web.Problem
+// @Failure 406 {object} web.Problem
+// @Failure 412 {object} web.Problem
+// @Failure 422 {object} web.Problem
+// @Failure 428 {object} web.Problem
+// @Router /users [delete]
+func (u *Controller) BulkDelete() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := handler.CheckAccessToken(c)
+		if err != nil {
+			handler.RespondError(c, http.StatusForbidden, err.Error())
+			return
+		}
+
+		filter, err := strictFilterFromQuery(c, "dry_run")
+		if err != nil {
+			handler.RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		options, ok := handler.BulkOptions(c)
+		if !ok {
+			return
+		}
+
+		changes, err := u.service.DeleteWhere(c.Request.Context(), filter, options)
+		if err != nil {
+			handler.RespondError(c, handler.ErrorStatus(c, err), err.Error())
+			return
+		}
+
+		respondBulk(c, changes, options)
+	}
+}
+
+// Import godoc
+// @Summary Import users from a CSV or NDJSON file
+// @Tags Users
+// @Description Validates every row of a CSV (header row with the json names of the fields) or NDJSON file and saves
+// @Description the accepted ones in a single write. The report lists the accepted rows and the errors by line.
+// @Description mode=all_or_nothing (default) saves nothing if any row is invalid, mode=skip_invalid saves the valid
+// @Description rows. upsert=true updates the user with the same email instead of rejecting the row.
+// @Accept text/csv,application/x-ndjson
+// @Produce json,xml,application/yaml,application/msgpack
+// @Param token header string true "token"
+// @Param mode query string false "all_or_nothing (default) or skip_invalid"
+// @Param upsert query bool false "update the users whose email already exists"
+// @Param mapping query string false "CSV columns to rename, like First Name=first_name,Surname=last_name"
+// @Success 200 {object} web.Response{data=web.ImportReport}
+// @Failure 400 {object} web.Problem
+// @Failure 403 {object} web.Problem
+// @Failure 406 {object} web.Problem
+// @Failure 413 {object} web.Problem
+// @Failure 415 {object} web.Problem
+// @Failure 422 {object} web.ImportProblem
+// @Router /users/import [post]
+func (u *Controller) Import() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := handler.CheckAccessToken(c)
+		if err != nil {
+			handler.RespondError(c, http.StatusForbidden, err.Error())
+			return
+		}
+
+		options, mapping, ok := handler.ImportRequest(c)
+		if !ok {
+			return
+		}
+
+		var rows []users.ImportRow
+		newUser := func() interface{} {
+			return &User{}
+		}
+		err = web.DecodeRows(c.ContentType(), c.Request.Body, mapping, newUser, func(line int, value interface{}, err error) {
+			row := users.ImportRow{Line: line, Err: err}
+			if userV2, ok := value.(*User); ok && err == nil {
+				row.User = fromUser(*userV2)
+			}
+			rows = append(rows, row)
+		})
+		if err != nil {
+			handler.RespondImport(c, users.ImportReport{}, err)
+			return
+		}
+
+		report, err := u.service.Import(c.Request.Context(), rows, options)
+		handler.RespondImport(c, report, err)
+	}
+}
+
+// Export godoc
+// @Summary Export users as CSV, NDJSON or JSON
+// @Tags Users
+// @Description Streams the users matching the listing filters as a download, writing each user as it is read
+// @Description instead of building the whole response. fields selects the columns, all of them by default.
+// @Produce text/csv,application/x-ndjson,json
+// @Param token header string true "token"
+// @Param format query string false "csv, ndjson or json (default)"
+// @Param fields query string false "comma separated fields to export, like id,first_name,email"
+// @Param id query int false "user id"
+// @Param first_name query string false "user first name"
+// @Param last_name query string false "user last name"
+// @Param email query string false "user email"
+// @Param age query int false "user age, computed from birth_date"
+// @Param birth_date query string false "user birth date (yyyy-mm-dd)"
+// @Param height query number false "user height"
+// @Param active query bool false "user active"
+// @Param created_after query string false "created after this RFC 3339 timestamp or yyyy-mm-dd date"
+// @Param created_before query string false "created before this RFC 3339 timestamp or yyyy-mm-dd date"
+// @Success 200 {array} User
+// @Failure 400 {object} web.Problem
+// @Failure 403 {object} web.Problem
+// @Router /users/export [get]
+func (u *Controller) Export() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := handler.CheckAccessToken(c)
+		if err != nil {
+			handler.RespondError(c, http.StatusForbidden, err.Error())
+			return
+		}
+
+		filter, err := strictFilterFromQuery(c, "format", "fields")
+		if err != nil {
+			handler.RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		handler.StreamExport(c, "users", reflect.TypeOf(User{}), func(write func(v interface{}) error) error {
+			return u.service.Export(c.Request.Context(), filter, func(user users.User) error {
+				return write(toUser(user))
+			})
+		})
+	}
+}
+
+// SubjectExport godoc
+// @Summary Export the data the service stores about a user
+// @Tags Privacy
+// @Description Returns the record of the user, the stored idempotency responses and the jobs that mention it and the
+// @Description log of its erasures. The request logs and the change history of the user are not included, the service
+// @Description does not keep them; not_included says so in the response.
+// @Produce json,xml,application/yaml,application/msgpack
+// @Param token header string true "token"
+// @Param id path int true "user id"
+// @Success 200 {object} web.Response{data=SubjectData}
+// @Failure 400 {object} web.Problem
+// @Failure 403 {object} web.Problem
+// @Failure 404 {object} web.Problem
+// @Failure 406 {object} web.Problem
+// @Router /users/{id}/export [get]
+func (u *Controller) SubjectExport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := handler.CheckAccessToken(c)
+		if err != nil {
+			handler.RespondError(c, http.StatusForbidden, err.Error())
+			return
+		}
+
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			handler.RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		data, err := u.privacy.Export(c.Request.Context(), id)
+		if err != nil {
+			handler.RespondError(c, handler.ErrorStatus(c, err), err.Error())
+			return
+		}
+
+		handler.Respond(c, http.StatusOK, SubjectData{User: toUser(data.User), IdempotencyRecords: data.IdempotencyRecords, Jobs: data.Jobs, Erasures: data.Erasures, NotIncluded: data.NotIncluded})
+	}
+}
+
+// Erase godoc
+// @Summary Erase the personal data of a user
+// @Tags Privacy
+// @Description Irreversibly replaces first_name, last_name and email with anonymous values, deletes the idempotency
+// @Description records and job files holding them and redacts them from job results. The record stays as an inactive
+// @Description tombstone that can no longer be changed. The erasure is logged with a scan verifying nothing is left.
+// @Produce json,xml,application/yaml,application/msgpack
+// @Param token header string true "token"
+// @Param id path int true "user id"
+// @Success 200 {object} web.Response{data=ErasureResult}
+// @Failure 400 {object} web.Problem
+// @Failure 403 {object} web.Problem
+// @Failure 404 {object} web.Problem
+// @Failure 406 {object} web.Problem
+// @Failure 409 {object} web.Problem
+// @Router /users/{id}/erase [post]
+func (u *Controller) Erase() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := handler.CheckAccessToken(c)
+		if err != nil {
+			handler.RespondError(c, http.StatusForbidden, err.Error())
+			return
+		}
+
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			handler.RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		erasure, tombstone, err := u.privacy.Erase(c.Request.Context(), id)
+		if err != nil {
+			handler.RespondError(c, handler.ErrorStatus(c, err), err.Error())
+			return
+		}
+		handler.LogUnverifiedErasure(handler.Logger(c, u.logger), erasure)
+
+		handler.Respond(c, http.StatusOK, ErasureResult{User: toUser(tombstone), Erasure: erasure})
+	}
+}
+
+// bindPatch returns the patch in the body of a PATCH request: a merge patch
+// or a JSON patch over the version 2 representation, or a plain PartialUser.
+func bindPatch(c *gin.Context) (patch users.PatchFunc, ok bool) {
+	handler.SetAcceptPatch(c)
+	if !handler.CheckContentType(c, append(web.BodyMediaTypes(), handler.PatchMediaTypes...)...) {
+		return nil, false
+	}
+
+	if mediaType := c.ContentType(); handler.IsPatchMediaType(mediaType) {
+		body, err := c.GetRawData()
+		if err != nil {
+			handler.RespondError(c, http.StatusBadRequest, err.Error())
+			return nil, false
+		}
+
+		patch = func(user users.User) (users.User, error) {
+			userV2 := toUser(user)
+			err := handler.ApplyPatch(mediaType, body, &userV2)
+			if err != nil {
+				return user, err
+			}
+
+			return mergeUser(user, userV2), nil
+		}
+
+		return patch, true
+	}
+
+	var partialUser PartialUser
+	err := c.ShouldBindBodyWith(&partialUser, web.BodyBinding(c.ContentType()))
+	if err != nil {
+		handler.RespondBindingError(c, err)
+		return nil, false
+	}
+
+	patch = func(user users.User) (users.User, error) {
+		if partialUser.LastName != "" {
+			user.Apellido = partialUser.LastName
+		}
+		if partialUser.BirthDate != "" {
+			user.FechaDeNacimiento = partialUser.BirthDate
+		} else if partialUser.Age != 0 {
+			user.Edad = partialUser.Age
+		}
+
+		return user, nil
+	}
+
+	return patch, true
+}
+
+func respondBulk(c *gin.Context, changes []users.BulkChange, options users.BulkOptions) {
+	result, err := handler.NewBulkResult(changes, options.DryRun, func(user users.User) interface{} {
+		return toUser(user)
+	})
+	if err != nil {
+		handler.RespondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	handler.Respond(c, http.StatusOK, result)
+}
+
+// Delete godoc
+// @Summary Delete a user
+// @Tags Users
+// @Description Delete the user with the given id
+// @Accept json
+// @Produce json
+// @Param token header string true "token"
+// @Param id path int true "user id"
+// @Success 204 "no content"
+// @Failure 400 {object} web.Problem
+// @Failure 403 {object} web.Problem
+// @Failure 404 {object} web.Problem
+// @Router /users/{id} [delete]
+func (u *Controller) Delete() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := handler.CheckAccessToken(c)
+		if err != nil {
+			handler.RespondError(c, http.StatusForbidden, err.Error())
+			return
+		}
+
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			handler.RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		err = u.service.DeleteUserByID(c.Request.Context(), id)
+		if err != nil {
+			handler.RespondError(c, handler.ErrorStatus(c, err), err.Error())
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
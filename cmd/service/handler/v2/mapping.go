@@ -0,0 +1,222 @@
+package v2
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/cmd/service/handler"
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/jobs"
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/privacy"
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/users"
+
+	"github.com/gin-gonic/gin"
+)
+
+// User is the representation of users.User served by version 2 of the API,
+// with English field names and RFC 3339 timestamps.
+type User struct {
+	ID        int64  `json:"id" xml:"id" yaml:"id"`
+	FirstName string `json:"first_name" xml:"first_name" yaml:"first_name" binding:"required"`
+	LastName  string `json:"last_name" xml:"last_name" yaml:"last_name" binding:"required"`
+	Email     string `json:"email" xml:"email" yaml:"email" binding:"required"`
+	// Age is computed from BirthDate, it is only read when BirthDate is not sent.
+	Age       int64     `json:"age" xml:"age" yaml:"age"`
+	BirthDate string    `json:"birth_date" xml:"birth_date" yaml:"birth_date"`
+	Height    float64   `json:"height" xml:"height" yaml:"height" binding:"required"`
+	Active    bool      `json:"active" xml:"active" yaml:"active"`
+	CreatedAt time.Time `json:"created_at" xml:"created_at" yaml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" xml:"updated_at" yaml:"updated_at"`
+	// ErasedAt is set when the personal data of the user was erased.
+	ErasedAt *time.Time `json:"erased_at,omitempty" xml:"erased_at,omitempty" yaml:"erased_at,omitempty"`
+}
+
+type Users struct {
+	Users []User `json:"users" xml:"user" yaml:"users"`
+}
+
+// PartialUser holds the fields that can be changed through PATCH.
+type PartialUser struct {
+	LastName string `json:"last_name" xml:"last_name" yaml:"last_name"`
+	Age      int64  `json:"age" xml:"age" yaml:"age"`
+	// BirthDate wins over Age when both are sent.
+	BirthDate string `json:"birth_date" xml:"birth_date" yaml:"birth_date"`
+}
+
+// BatchOperation is an item of the body of POST /users/batch. The user is
+// validated by the service for each operation, deletes don't send it.
+type BatchOperation struct {
+	Op   string `json:"op" yaml:"op"`
+	ID   int64  `json:"id" yaml:"id"`
+	User User   `json:"user" yaml:"user" binding:"-"`
+}
+
+// SubjectData is the version 2 representation of privacy.SubjectData.
+type SubjectData struct {
+	User               User                     `json:"user" xml:"user" yaml:"user"`
+	IdempotencyRecords []privacy.StoredResponse `json:"idempotency_records" xml:"idempotency_record" yaml:"idempotency_records"`
+	Jobs               []jobs.Job               `json:"jobs" xml:"job" yaml:"jobs"`
+	Erasures           []privacy.Erasure        `json:"erasures" xml:"erasure" yaml:"erasures"`
+	NotIncluded        []string                 `json:"not_included" xml:"not_included" yaml:"not_included"`
+}
+
+// ErasureResult is the response of an erasure: the tombstone left in place
+// of the user and the log entry of the erasure.
+type ErasureResult struct {
+	User    User            `json:"user" xml:"user" yaml:"user"`
+	Erasure privacy.Erasure `json:"erasure" xml:"erasure" yaml:"erasure"`
+}
+
+func toUser(user users.User) User {
+	return User{
+		ID:        user.Id,
+		FirstName: user.Nombre,
+		LastName:  user.Apellido,
+		Email:     user.Email,
+		Age:       user.Edad,
+		BirthDate: user.FechaDeNacimiento,
+		Height:    user.Altura,
+		Active:    user.Activo,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+		ErasedAt:  user.ErasedAt,
+	}
+}
+
+func toUsers(list users.Users) (usersV2 Users) {
+	usersV2.Users = []User{}
+	for _, user := range list.Users {
+		usersV2.Users = append(usersV2.Users, toUser(user))
+	}
+
+	return usersV2
+}
+
+// fromUser maps a version 2 body to users.User. The timestamps are assigned
+// by the service, so the ones in the body are ignored.
+func fromUser(userV2 User) (user users.User) {
+	user = users.User{
+		Id:                userV2.ID,
+		Nombre:            userV2.FirstName,
+		Apellido:          userV2.LastName,
+		Email:             userV2.Email,
+		Edad:              userV2.Age,
+		FechaDeNacimiento: userV2.BirthDate,
+		Altura:            userV2.Height,
+		Activo:            userV2.Active,
+	}
+
+	return user
+}
+
+// mergeUser sets on the stored user the fields of its version 2
+// representation, including the read only ones so users.Service.Patch can
+// reject the patches that change them.
+func mergeUser(stored users.User, userV2 User) (user users.User) {
+	user = stored
+	user.Id = userV2.ID
+	user.Nombre = userV2.FirstName
+	user.Apellido = userV2.LastName
+	user.Email = userV2.Email
+	user.Edad = userV2.Age
+	user.FechaDeNacimiento = userV2.BirthDate
+	user.Altura = userV2.Height
+	user.Activo = userV2.Active
+	user.CreatedAt = userV2.CreatedAt
+	user.UpdatedAt = userV2.UpdatedAt
+	user.ErasedAt = userV2.ErasedAt
+
+	return user
+}
+
+// filterParams are the query params understood by filterFromQuery.
+var filterParams = []string{"id", "first_name", "last_name", "email", "age", "birth_date", "height", "active", "created_after", "created_before"}
+
+// strictFilterFromQuery is filterFromQuery for the routes that change or
+// export users, which also take the given params. Unknown params are
+// rejected, so a typo does not widen the filter to more users than asked.
+func strictFilterFromQuery(c *gin.Context, params ...string) (filter users.Filter, err error) {
+	err = handler.CheckKnownParams(c, append(append([]string{}, filterParams...), params...))
+	if err != nil {
+		return filter, err
+	}
+
+	return filterFromQuery(c)
+}
+
+// filterFromQuery translates the version 2 query params to the filter
+// understood by users.Service.Filter.
+func filterFromQuery(c *gin.Context) (filter users.Filter, err error) {
+	availableParams, searchedUser := []string{}, users.User{}
+
+	for key, values := range c.Request.URL.Query() {
+		value := values[0]
+		if value == "" {
+			err = fmt.Errorf("el valor del parametro %s no puede ser nulo", key)
+			return filter, err
+		}
+
+		switch key {
+		case "id":
+			searchedUser.Id, err = strconv.ParseInt(value, 10, 64)
+			if err != nil || searchedUser.Id <= 0 {
+				err = fmt.Errorf("id debe ser un entero mayor a cero(recibido: %s)", value)
+				return filter, err
+			}
+			availableParams = append(availableParams, "id")
+		case "first_name":
+			searchedUser.Nombre = value
+			availableParams = append(availableParams, "nombre")
+		case "last_name":
+			searchedUser.Apellido = value
+			availableParams = append(availableParams, "apellido")
+		case "email":
+			searchedUser.Email = value
+			availableParams = append(availableParams, "email")
+		case "age":
+			searchedUser.Edad, err = strconv.ParseInt(value, 10, 64)
+			if err != nil || searchedUser.Edad <= 0 {
+				err = fmt.Errorf("age debe ser un entero mayor a cero(recibido: %s)", value)
+				return filter, err
+			}
+			availableParams = append(availableParams, "edad")
+		case "birth_date":
+			_, err = time.Parse(users.FechaDeNacimientoLayout, value)
+			if err != nil {
+				err = fmt.Errorf("birth_date debe tener formato aaaa-mm-dd(recibido: %s)", value)
+				return filter, err
+			}
+			searchedUser.FechaDeNacimiento = value
+			availableParams = append(availableParams, "fecha_de_nacimiento")
+		case "height":
+			searchedUser.Altura, err = strconv.ParseFloat(value, 64)
+			if err != nil || searchedUser.Altura <= 0.0 {
+				err = fmt.Errorf("height debe ser un float mayor a cero(recibido: %s)", value)
+				return filter, err
+			}
+			availableParams = append(availableParams, "altura")
+		case "active":
+			searchedUser.Activo, err = strconv.ParseBool(value)
+			if err != nil {
+				err = fmt.Errorf("active debe ser true o false(recibido: %s)", value)
+				return filter, err
+			}
+			availableParams = append(availableParams, "activo")
+		case "created_after":
+			filter.CreatedAfter, err = users.ParseDateParam(value)
+			if err != nil {
+				return filter, err
+			}
+		case "created_before":
+			filter.CreatedBefore, err = users.ParseDateParam(value)
+			if err != nil {
+				return filter, err
+			}
+		}
+	}
+
+	filter.Params = availableParams
+	filter.Searched = searchedUser
+
+	return filter, nil
+}
@@ -0,0 +1,39 @@
+package v2
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/users"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserMapping(t *testing.T) {
+	createdAt := time.Date(2021, 12, 13, 0, 0, 0, 0, time.UTC)
+	updatedAt := time.Date(2021, 12, 22, 10, 30, 0, 0, time.UTC)
+	user := users.User{Id: 1, Nombre: "user1 name", Apellido: "user1 last name", Email: "user1@email.com", Edad: 35, Altura: 1.58, Activo: true, FechaDeCreacion: "13/12/2021", CreatedAt: createdAt, UpdatedAt: updatedAt}
+	expectedUser := User{ID: 1, FirstName: "user1 name", LastName: "user1 last name", Email: "user1@email.com", Age: 35, Height: 1.58, Active: true, CreatedAt: createdAt, UpdatedAt: updatedAt}
+
+	assert.Equal(t, expectedUser, toUser(user))
+
+	// Testea que los timestamps recibidos se ignoren
+	expectedUser2 := users.User{Id: 1, Nombre: "user1 name", Apellido: "user1 last name", Email: "user1@email.com", Edad: 35, Altura: 1.58, Activo: true}
+	assert.Equal(t, expectedUser2, fromUser(expectedUser))
+}
+
+func TestStrictFilterFromQuery(t *testing.T) {
+	c := &gin.Context{Request: httptest.NewRequest(http.MethodPatch, "/users?last_name=Perez&active=true&dry_run=true", nil)}
+	filter, err := strictFilterFromQuery(c, "dry_run")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"apellido", "activo"}, filter.Params)
+	assert.True(t, filter.Searched.Activo)
+
+	// Testea que un parametro desconocido se rechace en vez de ampliar el filtro
+	c = &gin.Context{Request: httptest.NewRequest(http.MethodPatch, "/users?lastname=Perez&first_name=Juan", nil)}
+	_, err = strictFilterFromQuery(c, "dry_run")
+	assert.Contains(t, err.Error(), "el parametro lastname no es conocido")
+}
@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/privacy"
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/users"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/jsonpatch"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+const apiVersionKey = "api_version"
+
+// APIVersion resolves the contract version requested through the X-API-Version
+// header. Version 1 keeps the original status codes so old clients keep working
+// while they migrate, version 2 follows HTTP semantics.
+func APIVersion(defaultVersion int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		version := defaultVersion
+
+		header := c.GetHeader("X-API-Version")
+		if header != "" {
+			requested, err := strconv.Atoi(header)
+			if err != nil || requested < 1 || requested > 2 {
+				errMsg := "la version de la API solicitada no es soportada(recibido: " + header + ")"
+				RespondError(c, http.StatusBadRequest, errMsg)
+				return
+			}
+			version = requested
+		}
+
+		c.Set(apiVersionKey, version)
+		c.Header("X-API-Version", strconv.Itoa(version))
+		c.Next()
+	}
+}
+
+// FixedVersion pins the API version of a route group, like /v1 and /v2.
+func FixedVersion(version int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(apiVersionKey, version)
+		c.Header("X-API-Version", strconv.Itoa(version))
+		c.Next()
+	}
+}
+
+// unroutedVersion resolves the API version of a request no route group took,
+// like the 405s: the /v1 and /v2 paths pin it and the others keep the
+// X-API-Version switch.
+func unroutedVersion(c *gin.Context, defaultVersion int) int {
+	for _, version := range []int{1, 2} {
+		if strings.HasPrefix(c.Request.URL.Path, "/v"+strconv.Itoa(version)+"/") {
+			return version
+		}
+	}
+
+	requested, err := strconv.Atoi(c.GetHeader("X-API-Version"))
+	if err == nil && requested >= 1 && requested <= 2 {
+		return requested
+	}
+
+	return defaultVersion
+}
+
+// Deprecated flags the responses of a deprecated route group and points
+// clients to the routes that replace it.
+func Deprecated(successor string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		c.Header("Link", "<"+successor+">; rel=\"successor-version\"")
+		c.Next()
+	}
+}
+
+// RequestedVersion returns the API version resolved for the request.
+func RequestedVersion(c *gin.Context) int {
+	version, ok := c.Get(apiVersionKey)
+	if !ok {
+		return 1
+	}
+
+	return version.(int)
+}
+
+// allowLegacyUsers lets the version 1 creates and replaces register an email
+// that another user has and leave out the edad, as they could before version 2.
+func allowLegacyUsers(c *gin.Context) {
+	if RequestedVersion(c) < 2 {
+		c.Request = c.Request.WithContext(users.AllowLegacyUsers(c.Request.Context()))
+	}
+}
+
+// ErrorStatus maps the errors returned by the users service to a status code.
+func ErrorStatus(c *gin.Context, err error) int {
+	switch {
+	// Version 1 already answered 404 to the updates and deletes of unknown
+	// ids, only its GET answered otherwise and keeps doing so.
+	case errors.Is(err, users.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, store.ErrTimeout):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, store.ErrCanceled):
+		return StatusClientClosedRequest
+	case errors.Is(err, users.ErrUserErased), errors.Is(err, privacy.ErrPendingJobs):
+		return http.StatusConflict
+	case errors.Is(err, users.ErrEmailAlreadyExists) && RequestedVersion(c) >= 2:
+		return http.StatusConflict
+	case errors.Is(err, jsonpatch.ErrTestFailed) && RequestedVersion(c) >= 2:
+		return http.StatusConflict
+	case errors.Is(err, users.ErrConfirmationNeeded) && RequestedVersion(c) >= 2:
+		return http.StatusPreconditionRequired
+	case errors.Is(err, users.ErrCountMismatch) && RequestedVersion(c) >= 2:
+		return http.StatusPreconditionFailed
+	case errors.Is(err, users.ErrBirthDateRequired), errors.Is(err, users.ErrInvalidBirthDate),
+		errors.Is(err, users.ErrInvalidUser), errors.Is(err, users.ErrImmutableField),
+		errors.Is(err, jsonpatch.ErrPathNotFound), errors.Is(err, users.ErrInvalidBatchOperation),
+		errors.Is(err, users.ErrEmptyFilter):
+		return validationStatus(c)
+	}
+
+	return http.StatusBadRequest
+}
+
+// bindingStatus tells apart malformed bodies (400) from well-formed bodies
+// that fail validation (422 on version 2).
+func bindingStatus(c *gin.Context, err error) int {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		return validationStatus(c)
+	}
+
+	return http.StatusBadRequest
+}
+
+// validationStatus is the status for bodies that parse but carry invalid values.
+func validationStatus(c *gin.Context) int {
+	if RequestedVersion(c) >= 2 {
+		return http.StatusUnprocessableEntity
+	}
+
+	return http.StatusBadRequest
+}
+
+// CheckContentType rejects, on version 2, bodies sent with a media type the
+// route doesn't understand.
+func CheckContentType(c *gin.Context, accepted ...string) bool {
+	if RequestedVersion(c) < 2 {
+		return true
+	}
+
+	contentType := c.ContentType()
+	for _, mediaType := range accepted {
+		if contentType == mediaType {
+			return true
+		}
+	}
+
+	errMsg := "el tipo de contenido " + contentType + " no es soportado"
+	RespondError(c, http.StatusUnsupportedMediaType, errMsg)
+
+	return false
+}
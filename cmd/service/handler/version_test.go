@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/web"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersion1StatusCodes(t *testing.T) {
+	router, db := newUsersRouter(t)
+	headers := map[string]string{"token": "token123", "Content-Type": "application/json"}
+	body := `{"nombre":"Eva","apellido":"Diaz","email":"juan@email.com","altura":1.6,"activo":true,"fecha_de_nacimiento":"1990-01-01"}`
+
+	// Testea que version 1 siga aceptando emails repetidos, como antes de version 2
+	response := serve(router, http.MethodPost, "/users/", body, headers)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Empty(t, response.Header().Get("Location"))
+	assert.Len(t, storedUsers(t, db), 4)
+
+	response = serve(router, http.MethodPut, "/users/3", body, headers)
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	// Testea que version 1 siga creando usuarios sin edad ni fecha de nacimiento
+	response = serve(router, http.MethodPost, "/users/", `{"nombre":"Luis","apellido":"Diaz","email":"luis@email.com","altura":1.7,"activo":true}`, headers)
+	assert.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	assert.Len(t, storedUsers(t, db), 5)
+
+	// Testea que los errores mantengan los codigos de version 1
+	response = serve(router, http.MethodPost, "/users/", `{"nombre":"Eva"}`, headers)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	response = serve(router, http.MethodPost, "/users/", body, map[string]string{"Content-Type": "application/json"})
+	assert.Equal(t, http.StatusForbidden, response.Code)
+	response = serve(router, http.MethodGet, "/users/99", "", headers)
+	assert.Equal(t, http.StatusOK, response.Code)
+	response = serve(router, http.MethodPut, "/users/99", body, headers)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+	response = serve(router, http.MethodDelete, "/users/99", "", headers)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+
+	response = serve(router, http.MethodDelete, "/users/1", "", headers)
+	assert.Equal(t, http.StatusOK, response.Code)
+}
+
+func TestVersion2StatusCodes(t *testing.T) {
+	router, _ := newUsersRouter(t)
+	headers := map[string]string{"token": "token123", "Content-Type": "application/json", "X-API-Version": "2"}
+	body := `{"nombre":"Eva","apellido":"Diaz","email":"eva@email.com","altura":1.6,"activo":true,"fecha_de_nacimiento":"1990-01-01"}`
+
+	response := serve(router, http.MethodPost, "/users/", body, headers)
+	assert.Equal(t, http.StatusCreated, response.Code)
+	assert.Equal(t, "/users/4", response.Header().Get("Location"))
+
+	// Testea que los conflictos y los cuerpos invalidos tengan su propio codigo
+	response = serve(router, http.MethodPost, "/users/", body, headers)
+	assert.Equal(t, http.StatusConflict, response.Code)
+	response = serve(router, http.MethodPut, "/users/1", body, headers)
+	assert.Equal(t, http.StatusConflict, response.Code)
+	response = serve(router, http.MethodPost, "/users/", `{"nombre":"Eva"}`, headers)
+	assert.Equal(t, http.StatusUnprocessableEntity, response.Code)
+	response = serve(router, http.MethodPost, "/users/", `{"nombre":`, headers)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	response = serve(router, http.MethodPost, "/users/", body, map[string]string{"token": "token123", "Content-Type": "text/plain", "X-API-Version": "2"})
+	assert.Equal(t, http.StatusUnsupportedMediaType, response.Code)
+
+	response = serve(router, http.MethodGet, "/users/99", "", headers)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+	response = serve(router, http.MethodPut, "/users/99", strings.Replace(body, "eva@", "otra@", 1), headers)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+	response = serve(router, http.MethodDelete, "/users/2", "", headers)
+	assert.Equal(t, http.StatusNoContent, response.Code)
+	response = serve(router, http.MethodDelete, "/users/2", "", headers)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+
+	response = serve(router, http.MethodGet, "/users/1", "", map[string]string{"X-API-Version": "3"})
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+func TestMethods(t *testing.T) {
+	router, _ := newUsersRouter(t)
+
+	// Testea que OPTIONS y los 405 informen los metodos de la ruta
+	response := serve(router, http.MethodOptions, "/users/1", "", nil)
+	assert.Equal(t, http.StatusNoContent, response.Code)
+	assert.Equal(t, "DELETE, GET, OPTIONS, PATCH, PUT", response.Header().Get("Allow"))
+
+	response = serve(router, http.MethodPost, "/users/1", "", nil)
+	assert.Equal(t, http.StatusMethodNotAllowed, response.Code)
+	assert.Equal(t, "DELETE, GET, OPTIONS, PATCH, PUT", response.Header().Get("Allow"))
+	assert.Contains(t, response.Body.String(), `"code":"405"`)
+}
+
+func TestVersion2MethodNotAllowed(t *testing.T) {
+	router, _ := newUsersRouter(t)
+	v2 := router.Group("/v2/users")
+	v2.Use(FixedVersion(2))
+	v2.GET("/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	// Testea que los 405 de la version 2 respondan con problem+json, por la
+	// ruta o por el encabezado X-API-Version
+	for _, request := range []struct {
+		target  string
+		headers map[string]string
+	}{
+		{"/v2/users/1", nil},
+		{"/users/1", map[string]string{"X-API-Version": "2"}},
+	} {
+		response := serve(router, http.MethodPost, request.target, "", request.headers)
+		assert.Equal(t, http.StatusMethodNotAllowed, response.Code)
+		assert.Equal(t, web.ProblemMediaType, response.Header().Get("Content-Type"))
+		assert.Equal(t, "2", response.Header().Get("X-API-Version"))
+
+		var problem web.Problem
+		assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &problem))
+		assert.Equal(t, http.StatusMethodNotAllowed, problem.Status)
+		assert.Equal(t, web.ProblemTypeBase+"method-not-allowed", problem.Type)
+	}
+}
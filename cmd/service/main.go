@@ -1,16 +1,33 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/EdigiraldoML/go-web-arquitecture/cmd/service/handler"
-	"github.com/EdigiraldoML/go-web-arquitecture/docs"
+	v2handler "github.com/EdigiraldoML/go-web-arquitecture/cmd/service/handler/v2"
+	v1docs "github.com/EdigiraldoML/go-web-arquitecture/docs/v1"
+	v2docs "github.com/EdigiraldoML/go-web-arquitecture/docs/v2"
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/config"
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/idempotency"
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/jobs"
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/privacy"
 	"github.com/EdigiraldoML/go-web-arquitecture/internal/users"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/health"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/logger"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/metrics"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/ratelimit"
 	"github.com/EdigiraldoML/go-web-arquitecture/pkg/store"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/trace"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/web"
 
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
 
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"github.com/swaggo/gin-swagger/swaggerFiles"
@@ -26,36 +43,366 @@ import (
 
 // @license.name Apache 2.0
 // @license.url https://www.apache.org/licenses/LICENSE-2.0
+
+// @BasePath /v1
 func main() {
+	app := newApp()
+	os.Exit(exitCode(app.Run(os.Args), app.ErrWriter))
+}
 
-	pathUsersJSON := "users.json"
+// run serves, reloading the configuration on SIGHUP, until SIGINT or SIGTERM
+// and returns the exit code: 0 when the shutdown drained every request and
+// job, 1 when it had to cut them off or the server failed, 2 when the
+// configuration is invalid.
+func run(args []string) (exitCode int) {
+	// The .env file sets the variables not already in the environment, it is
+	// one more source of the environment layer.
+	lookupEnv, envErr := environment()
 
-	err := godotenv.Load()
+	cfg, sources, err := config.Load(args, lookupEnv, os.Stderr)
 	if err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, err)
+		return 2
 	}
 
-	db := store.NewStorage(store.FileType, pathUsersJSON)
-	repository := users.CreateRepository(db)
-	service := users.CreateService(repository)
-	controller := handler.CreateUser(service)
+	log := newLogger(cfg.Log, os.Stdout)
+	if envErr != nil {
+		log.Warn("no se pudo cargar el archivo .env", logger.Err(envErr))
+	}
 
-	router := gin.Default()
+	tracer, closeTracer := newTracer(cfg.Trace, log)
+	defer closeTracer()
 
-	docs.SwaggerInfo.Host = os.Getenv("HOST")
-	router.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	registry := metrics.NewRegistry()
+	storeMetrics := store.NewMetrics(registry)
 
-	usrs := router.Group("/users")
-	usrs.GET("/", controller.FilterByUrlParams())
-	usrs.GET("/GetAll", controller.GetAll())
-	usrs.GET("/:id", controller.GetUserByID())
-	usrs.POST("/", controller.NewUser())
-	usrs.PUT("/:id", controller.FullUpdate())
-	usrs.DELETE("/:id", controller.DeleteUserByID())
-	usrs.PATCH("/:id", controller.PartialUpdateToUser())
+	// The commands that change the users take the same lock, so they can not
+	// write behind the back of the server, nor can a second server.
+	unlockUsers, err := store.Lock(cfg.Storage.Users)
+	if err != nil {
+		log.Error("no se pudo bloquear el almacenamiento de usuarios", logger.Err(err))
+		return 1
+	}
+	defer unlockUsers()
 
-	err = router.Run()
+	db := store.Instrument(store.NewStorage(store.Type(cfg.Storage.Type), cfg.Storage.Users, log), "users", storeMetrics)
+	migrated, unparsed, err := users.MigrateTimestamps(db)
 	if err != nil {
-		fmt.Println(err)
+		log.Error("no se pudieron migrar los timestamps", logger.Err(err))
+	}
+	if migrated > 0 || len(unparsed) > 0 {
+		log.Info("timestamps migrados", logger.F("users", migrated), logger.F("invalid_fecha_de_creacion_ids", unparsed))
+	}
+	migrated, err = users.MigrateBirthDates(db, users.SystemClock.Now())
+	if err != nil {
+		log.Error("no se pudieron migrar las fechas de nacimiento", logger.Err(err))
+	}
+	if migrated > 0 {
+		log.Info("fechas de nacimiento derivadas de la edad", logger.F("users", migrated))
+	}
+	repository := users.TraceRepository(users.InstrumentRepository(users.CreateRepository(db, log), users.NewRepositoryMetrics(registry)))
+	// Sets the users gauge before the first request reads them.
+	_, err = repository.GetAll(context.Background())
+	if err != nil {
+		log.Error("no se pudieron leer los usuarios", logger.Err(err))
+	}
+	service := users.TraceService(users.CreateService(repository, log))
+
+	limiter := ratelimit.CreateLimiter(nil, nil)
+	reloads := createReloader(args, cfg, sources, log, limiter)
+
+	idempotencyDb := store.Instrument(store.NewStorage(store.Type(cfg.Storage.Type), cfg.Storage.Idempotency, log), "idempotency", storeMetrics)
+	idempotencyRepository := idempotency.CreateRepository(idempotencyDb)
+	idempotencyLocker := idempotency.CreateKeyLocker()
+
+	jobsDb := store.Instrument(store.NewStorage(store.Type(cfg.Storage.Type), cfg.Storage.Jobs, log), "jobs", storeMetrics)
+	jobsRepository := jobs.CreateRepository(jobsDb)
+	jobRunner := jobs.CreateRunner(jobsRepository, cfg.Jobs.Dir, cfg.Jobs.Workers, cfg.Jobs.ArtifactTTL, log)
+	handler.RegisterJobs(jobRunner, service)
+	requeued, err := jobRunner.Start()
+	if err != nil {
+		log.Error("no se pudieron iniciar los trabajos", logger.Err(err))
+	}
+	if requeued > 0 {
+		log.Info("trabajos interrumpidos reanudados", logger.F("jobs", requeued))
+	}
+	jobsController := handler.CreateJobs(jobRunner)
+
+	erasuresDb := store.Instrument(store.NewStorage(store.Type(cfg.Storage.Type), cfg.Storage.Erasures, log), "erasures", storeMetrics)
+	privacyService := privacy.CreateService(service, idempotencyRepository, jobsRepository, cfg.Jobs.Dir, privacy.CreateRepository(erasuresDb), log)
+
+	drainer := &handler.Drainer{}
+	checker := newChecker(cfg.Ready, repository, jobRunner, map[string]storeCheck{
+		"users":       {db, func() interface{} { return &users.Users{} }},
+		"idempotency": {idempotencyDb, func() interface{} { return &idempotency.Records{} }},
+		"jobs":        {jobsDb, func() interface{} { return &jobs.Jobs{} }},
+		"erasures":    {erasuresDb, func() interface{} { return &privacy.Erasures{} }},
+	})
+	checker.Add("shutdown", func(ctx context.Context) error {
+		return drainer.Check()
+	})
+
+	controller := handler.CreateUser(service, privacyService, log)
+	controllerV2 := v2handler.CreateController(service, privacyService, log)
+
+	web.UseJSONFieldNames()
+
+	// RequestLogger replaces the access log of gin.Default, wrapping Recovery
+	// so that panics are logged as the 500 they end in. Tracing goes first so
+	// the log lines carry the trace id, after AbortConnections, which cuts the
+	// connections once the rest are done with them. The exports are streamed,
+	// so they are bound by the client that reads them and not by the timeout.
+	router := gin.New()
+	timeout := handler.Timeout(cfg.Limits.RequestTimeout, "/users/export", "/v1/users/export", "/v2/users/export")
+	router.Use(handler.AbortConnections(), handler.Tracing(tracer), handler.RequestLogger(log), handler.Metrics(registry), handler.CORS(), timeout, handler.RejectWhileDraining(drainer), gin.Recovery())
+	router.HandleMethodNotAllowed = true
+	router.NoMethod(handler.MethodNotAllowed(router, cfg.Server.APIVersion))
+
+	router.GET("/metrics", gin.WrapH(registry))
+	router.GET("/healthz", handler.Healthz())
+	router.GET("/readyz", handler.Readyz(checker))
+	admin := router.Group("/admin")
+	admin.Use(handler.RequireAccessToken())
+	admin.GET("/config", handler.AdminConfig(reloads.View))
+	admin.POST("/reload", handler.AdminReload(reloads.Reload))
+
+	v1docs.SwaggerInfo.Host = cfg.Server.Host
+	v2docs.SwaggerInfo.Host = cfg.Server.Host
+	router.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.InstanceName("v1")))
+	router.GET("/v1/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.InstanceName("v1")))
+	router.GET("/v2/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.InstanceName("v2")))
+
+	idempotent := handler.Idempotency(idempotencyRepository, idempotencyLocker, cfg.Limits.IdempotencyTTL)
+
+	// The unversioned routes keep the X-API-Version switch for clients still migrating.
+	legacy := router.Group("/users")
+	legacy.Use(handler.APIVersion(cfg.Server.APIVersion), handler.Deprecated("/v2/users"), handler.RateLimit(limiter))
+	registerUsersV1(router, legacy, controller, idempotent)
+
+	v1 := router.Group("/v1/users")
+	v1.Use(handler.FixedVersion(1), handler.Deprecated("/v2/users"), handler.RateLimit(limiter))
+	registerUsersV1(router, v1, controller, idempotent)
+
+	v2 := router.Group("/v2/users")
+	v2.Use(handler.FixedVersion(2), handler.RateLimit(limiter))
+	registerUsersV2(router, v2, controllerV2, idempotent)
+
+	// Jobs take the version 1 field names in their params.
+	for _, prefix := range []string{"/jobs", "/v1/jobs"} {
+		jobsGroup := router.Group(prefix)
+		jobsGroup.Use(handler.FixedVersion(1), handler.RateLimit(limiter))
+		registerJobs(router, jobsGroup, jobsController)
 	}
+
+	signals, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+	go func() {
+		for range hangups {
+			_, _ = reloads.Reload()
+		}
+	}()
+
+	// The backups made by check --fix expire while the server runs too.
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			pruneBackups(cfg.Storage, cfg.Storage.Users, time.Now(), log)
+			select {
+			case <-ticker.C:
+			case <-signals.Done():
+				return
+			}
+		}
+	}()
+
+	server := &http.Server{Addr: cfg.Server.Address, Handler: router}
+	serveErr := make(chan error, 1)
+	go func() {
+		if cfg.Server.TLS.Enabled() {
+			serveErr <- server.ListenAndServeTLS(cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile)
+			return
+		}
+		serveErr <- server.ListenAndServe()
+	}()
+	log.Info("servidor iniciado", logger.F("address", server.Addr), logger.F("tls", cfg.Server.TLS.Enabled()))
+
+	select {
+	case err = <-serveErr:
+		log.Error("el servidor se detuvo", logger.Err(err))
+		return 1
+	case <-signals.Done():
+	}
+	// A second signal ends the process at once.
+	stopSignals()
+
+	return shutdown(log, server, drainer, jobRunner, cfg.Server.ShutdownDelay, cfg.Server.ShutdownTimeout)
+}
+
+// shutdown rejects new mutations and fails the readiness probe, waits delay
+// for the orchestrator to notice, and then waits up to timeout for the
+// requests and jobs in flight. The stores write synchronously, so once they
+// are done nothing is left to flush.
+func shutdown(log *logger.Logger, server *http.Server, drainer *handler.Drainer, jobRunner *jobs.Runner, delay time.Duration, timeout time.Duration) (exitCode int) {
+	log.Info("apagando el servidor", logger.F("delay", delay), logger.F("timeout", timeout))
+	drainer.Start()
+	time.Sleep(delay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := server.Shutdown(ctx)
+	if err != nil {
+		log.Error("se cortaron solicitudes sin terminar", logger.Err(err))
+		server.Close()
+		exitCode = 1
+	}
+
+	err = jobRunner.Stop(ctx)
+	if err != nil {
+		log.Error("quedaron trabajos sin terminar, se reanudaran al iniciar", logger.Err(err))
+		exitCode = 1
+	}
+
+	if exitCode == 0 {
+		log.Info("servidor apagado")
+	}
+
+	return exitCode
+}
+
+// storeCheck is a store probed by the readiness checks, with a new document of
+// the type it keeps.
+type storeCheck struct {
+	db       store.Store
+	document func() interface{}
+}
+
+// newChecker checks that every store is readable and writable, that the users
+// parse, that the disk has the free space of ready and that the jobs runner
+// started and is saving the jobs, each within the check timeout of ready.
+func newChecker(ready config.Ready, repository users.Repository, jobRunner *jobs.Runner, stores map[string]storeCheck) *health.Checker {
+	checker := health.NewChecker(ready.CheckTimeout)
+	for name, s := range stores {
+		s := s
+		checker.Add("store_"+name, func(ctx context.Context) error {
+			return store.Check(ctx, s.db, s.document())
+		})
+	}
+	checker.Add("users_file", func(ctx context.Context) error {
+		_, err := repository.GetAll(ctx)
+		if err != nil {
+			return fmt.Errorf("no se pudieron leer los usuarios: %w", err)
+		}
+		return nil
+	})
+	checker.Add("disk", health.DiskSpace(".", ready.MinFreeDiskMB<<20))
+	checker.Add("jobs_start", func(ctx context.Context) error {
+		err := jobRunner.StartError()
+		if err != nil {
+			return fmt.Errorf("no se pudieron iniciar los trabajos: %w", err)
+		}
+		return nil
+	})
+	checker.Add("jobs_flush", func(ctx context.Context) error {
+		err := jobRunner.FlushError()
+		if err != nil {
+			return fmt.Errorf("no se pudieron guardar los trabajos: %w", err)
+		}
+		return nil
+	})
+
+	return checker
+}
+
+// newTracer exports the spans with the exporter of settings, none, stdout or
+// otlp-file, which appends to its file. Without an exporter the tracer is nil
+// and traces nothing.
+func newTracer(settings config.Trace, log *logger.Logger) (tracer *trace.Tracer, close func()) {
+	exporter, closeExporter, err := trace.NewExporter(settings.Exporter, settings.File, settings.ServiceName)
+	if err != nil {
+		log.Warn("no se exportan las trazas", logger.Err(err))
+	}
+	close = func() {
+		err := closeExporter()
+		if err != nil {
+			log.Error("no se pudo cerrar el exportador de trazas", logger.Err(err))
+		}
+	}
+	if exporter == nil {
+		return nil, close
+	}
+
+	tracer = trace.NewTracer(exporter, func(err error) {
+		log.Warn("no se pudo exportar la traza", logger.Err(err))
+	})
+
+	return tracer, close
+}
+
+// newLogger writes to out with the level and format of settings, already
+// validated.
+func newLogger(settings config.Log, out io.Writer) *logger.Logger {
+	level, _ := logger.ParseLevel(settings.Level)
+	format, _ := logger.ParseFormat(settings.Format)
+
+	return logger.New(out, level, format)
+}
+
+func registerUsersV1(router *gin.Engine, usrs *gin.RouterGroup, controller *handler.User, idempotent gin.HandlerFunc) {
+	listFormats := handler.Negotiate(web.ListFormats...)
+	recordFormats := handler.Negotiate(web.RecordFormats...)
+
+	usrs.OPTIONS("/", handler.Options(router))
+	usrs.OPTIONS("/:id", handler.Options(router))
+	usrs.GET("/", listFormats, controller.FilterByUrlParams())
+	usrs.GET("/GetAll", listFormats, controller.GetAll())
+	usrs.GET("/export", controller.Export())
+	usrs.GET("/:id", recordFormats, controller.GetUserByID())
+	usrs.GET("/:id/export", recordFormats, controller.SubjectExport())
+	usrs.POST("/:id/erase", recordFormats, controller.Erase())
+	usrs.POST("/", recordFormats, idempotent, controller.NewUser())
+	usrs.POST("/batch", recordFormats, idempotent, controller.Batch())
+	usrs.POST("/import", recordFormats, idempotent, controller.Import())
+	usrs.PUT("/:id", recordFormats, controller.FullUpdate())
+	usrs.DELETE("/:id", recordFormats, controller.DeleteUserByID())
+	usrs.PATCH("/:id", recordFormats, controller.PartialUpdateToUser())
+	usrs.PATCH("/", recordFormats, controller.BulkUpdate())
+	usrs.DELETE("/", recordFormats, controller.BulkDelete())
+}
+
+func registerUsersV2(router *gin.Engine, usrs *gin.RouterGroup, controller *v2handler.Controller, idempotent gin.HandlerFunc) {
+	listFormats := handler.Negotiate(web.ListFormats...)
+	recordFormats := handler.Negotiate(web.RecordFormats...)
+
+	usrs.OPTIONS("", handler.Options(router))
+	usrs.OPTIONS("/:id", handler.Options(router))
+	usrs.GET("", listFormats, controller.List())
+	usrs.GET("/export", controller.Export())
+	usrs.GET("/:id", recordFormats, controller.Get())
+	usrs.GET("/:id/export", recordFormats, controller.SubjectExport())
+	usrs.POST("/:id/erase", recordFormats, controller.Erase())
+	usrs.POST("", recordFormats, idempotent, controller.Create())
+	usrs.POST("/batch", recordFormats, idempotent, controller.Batch())
+	usrs.POST("/import", recordFormats, idempotent, controller.Import())
+	usrs.PUT("/:id", recordFormats, controller.Replace())
+	usrs.PATCH("/:id", recordFormats, controller.Update())
+	usrs.DELETE("/:id", recordFormats, controller.Delete())
+	usrs.PATCH("", recordFormats, controller.BulkUpdate())
+	usrs.DELETE("", recordFormats, controller.BulkDelete())
+}
+
+func registerJobs(router *gin.Engine, jobsGroup *gin.RouterGroup, controller *handler.Jobs) {
+	recordFormats := handler.Negotiate(web.RecordFormats...)
+
+	jobsGroup.OPTIONS("", handler.Options(router))
+	jobsGroup.OPTIONS("/:id", handler.Options(router))
+	jobsGroup.POST("", recordFormats, controller.Create())
+	jobsGroup.GET("/:id", recordFormats, controller.Get())
+	jobsGroup.POST("/:id/cancel", recordFormats, controller.Cancel())
+	jobsGroup.GET("/:id/artifact", controller.Artifact())
 }
@@ -0,0 +1,304 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// service is the binary running in a directory of its own, seeded with the
+// stores and the .env of cmd/service.
+type service struct {
+	cmd    *exec.Cmd
+	dir    string
+	addr   string
+	token  string
+	output *output
+	exited chan struct{}
+}
+
+// output collects what the service writes, read while it is still running.
+type output struct {
+	mu     sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (o *output) Write(p []byte) (n int, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.buffer.Write(p)
+}
+
+func (o *output) String() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.buffer.String()
+}
+
+func buildService(t *testing.T) string {
+	if testing.Short() {
+		t.Skip("compila y ejecuta el servicio")
+	}
+
+	bin := filepath.Join(t.TempDir(), "service")
+	build := exec.Command("go", "build", "-o", bin, ".")
+	output, err := build.CombinedOutput()
+	if err != nil {
+		t.Fatalf("no se pudo compilar el servicio: %v\n%s", err, output)
+	}
+
+	return bin
+}
+
+func startService(t *testing.T, bin string, env ...string) *service {
+	dir := t.TempDir()
+	for _, name := range []string{".env", "users.json"} {
+		data, err := os.ReadFile(name)
+		assert.Nil(t, err)
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, name), data, 0644))
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	svc := &service{dir: dir, addr: fmt.Sprintf("127.0.0.1:%d", port), output: &output{}, exited: make(chan struct{})}
+	svc.cmd = exec.Command(bin)
+	svc.cmd.Dir = dir
+	svc.cmd.Env = append(os.Environ(), append([]string{fmt.Sprintf("PORT=%d", port), "GIN_MODE=release", "LOG_LEVEL=info"}, env...)...)
+	svc.cmd.Stdout = svc.output
+	svc.cmd.Stderr = svc.output
+	assert.Nil(t, svc.cmd.Start())
+	go func() {
+		_ = svc.cmd.Wait()
+		close(svc.exited)
+	}()
+	t.Cleanup(func() {
+		_ = svc.cmd.Process.Kill()
+		<-svc.exited
+	})
+
+	envFile, _ := os.ReadFile(filepath.Join(dir, ".env"))
+	for _, line := range strings.Split(string(envFile), "\n") {
+		if strings.HasPrefix(line, "TOKEN=") {
+			svc.token = strings.Trim(strings.TrimPrefix(line, "TOKEN="), `"`)
+		}
+	}
+
+	for idx := 0; idx < 100; idx++ {
+		response, err := http.Get("http://" + svc.addr + "/healthz")
+		if err == nil {
+			response.Body.Close()
+			return svc
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("el servicio no inicio:\n%s", svc.output)
+
+	return nil
+}
+
+func (svc *service) request(t *testing.T, method string, path string, body string) int {
+	request, err := http.NewRequest(method, "http://"+svc.addr+path, strings.NewReader(body))
+	assert.Nil(t, err)
+	request.Header.Set("token", svc.token)
+	request.Header.Set("Content-Type", "application/merge-patch+json")
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("la solicitud %s %s fallo: %v", method, path, err)
+	}
+	response.Body.Close()
+
+	return response.StatusCode
+}
+
+// startSlowPatch sends a patch of the user 1 whose body arrives in two
+// parts, so that the request is in flight until the second one is written.
+func (svc *service) startSlowPatch(t *testing.T, body string) net.Conn {
+	conn, err := net.Dial("tcp", svc.addr)
+	assert.Nil(t, err)
+
+	half := len(body) / 2
+	_, err = fmt.Fprintf(conn, "PATCH /v2/users/1 HTTP/1.1\r\nHost: %s\r\ntoken: %s\r\nContent-Type: application/merge-patch+json\r\nContent-Length: %d\r\n\r\n%s",
+		svc.addr, svc.token, len(body), body[:half])
+	assert.Nil(t, err)
+
+	return conn
+}
+
+func (svc *service) signal(t *testing.T) {
+	assert.Nil(t, svc.cmd.Process.Signal(syscall.SIGTERM))
+}
+
+func (svc *service) exitCode(t *testing.T) int {
+	select {
+	case <-svc.exited:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("el servicio no se detuvo:\n%s", svc.output)
+	}
+
+	return svc.cmd.ProcessState.ExitCode()
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	bin := buildService(t)
+	svc := startService(t, bin, "SHUTDOWN_DELAY=1s", "SHUTDOWN_TIMEOUT=5s")
+
+	body := `{"height": 1.99}`
+	conn := svc.startSlowPatch(t, body)
+	defer conn.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	svc.signal(t)
+	time.Sleep(200 * time.Millisecond)
+
+	// Testea que durante el apagado se lea pero se rechacen las mutaciones nuevas
+	assert.Equal(t, http.StatusOK, svc.request(t, http.MethodGet, "/v2/users/2", ""))
+	assert.Equal(t, http.StatusServiceUnavailable, svc.request(t, http.MethodPatch, "/v2/users/2", `{"height": 1.5}`))
+	assert.Equal(t, http.StatusServiceUnavailable, svc.request(t, http.MethodGet, "/readyz", ""))
+
+	// Testea que la mutacion en curso termine y se guarde
+	_, err := conn.Write([]byte(body[len(body)/2:]))
+	assert.Nil(t, err)
+	response, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	assert.Equal(t, 0, svc.exitCode(t), svc.output.String())
+	assert.Contains(t, svc.output.String(), "servidor apagado")
+
+	var stored struct {
+		Users []map[string]interface{} `json:"users"`
+	}
+	data, err := os.ReadFile(filepath.Join(svc.dir, "users.json"))
+	assert.Nil(t, err)
+	assert.Nil(t, json.Unmarshal(data, &stored))
+	for _, user := range stored.Users {
+		if user["id"] == float64(1) {
+			assert.Equal(t, 1.99, user["altura"])
+		}
+		if user["id"] == float64(2) {
+			assert.NotEqual(t, 1.5, user["altura"])
+		}
+	}
+}
+
+func TestForcedShutdown(t *testing.T) {
+	bin := buildService(t)
+	svc := startService(t, bin, "SHUTDOWN_TIMEOUT=300ms")
+
+	// Testea que una solicitud que no termina fuerce el apagado
+	conn := svc.startSlowPatch(t, `{"height": 1.99}`)
+	defer conn.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	svc.signal(t)
+	assert.Equal(t, 1, svc.exitCode(t), svc.output.String())
+	assert.Contains(t, svc.output.String(), "se cortaron solicitudes sin terminar")
+
+	data, err := os.ReadFile(filepath.Join(svc.dir, "users.json"))
+	assert.Nil(t, err)
+	assert.True(t, json.Valid(data))
+}
+
+func TestUsersLock(t *testing.T) {
+	bin := buildService(t)
+	svc := startService(t, bin)
+	command := func(args ...string) (output string, err error) {
+		cmd := exec.Command(bin, args...)
+		cmd.Dir = svc.dir
+		data, err := cmd.CombinedOutput()
+		return string(data), err
+	}
+
+	// Testea que los comandos que cambian los usuarios no corran con el servidor iniciado
+	output, err := command("users", "delete", "10")
+	assert.NotNil(t, err)
+	assert.Contains(t, output, "bloqueado por otro proceso")
+	output, err = command("users", "get", "10")
+	assert.Nil(t, err, output)
+
+	svc.signal(t)
+	assert.Equal(t, 0, svc.exitCode(t), svc.output.String())
+	output, err = command("users", "delete", "10")
+	assert.Nil(t, err, output)
+}
+
+func TestReadiness(t *testing.T) {
+	bin := buildService(t)
+	svc := startService(t, bin)
+	defer svc.signal(t)
+	assert.Equal(t, http.StatusOK, svc.request(t, http.MethodGet, "/readyz", ""), svc.output.String())
+
+	// Testea que un almacenamiento con otro formato que el esperado no este listo
+	assert.Nil(t, os.WriteFile(filepath.Join(svc.dir, "erasures.json"), []byte(`[]`), 0644))
+	assert.Equal(t, http.StatusServiceUnavailable, svc.request(t, http.MethodGet, "/readyz", ""))
+
+	// Testea que sin poder iniciar los trabajos el servicio no este listo
+	failed := startService(t, bin, "JOBS_DIR=.env/jobs")
+	defer failed.signal(t)
+	assert.Equal(t, http.StatusServiceUnavailable, failed.request(t, http.MethodGet, "/readyz", ""))
+}
+
+func TestReload(t *testing.T) {
+	bin := buildService(t)
+	svc := startService(t, bin)
+
+	envFile := filepath.Join(svc.dir, ".env")
+	data, err := os.ReadFile(envFile)
+	assert.Nil(t, err)
+	env := strings.NewReplacer("TOKEN="+svc.token, "TOKEN=rotado", "CORS_ORIGINS=", "CORS_ORIGINS=https://app.example.com", "JOB_WORKERS=2", "JOB_WORKERS=5").Replace(string(data))
+	assert.Nil(t, os.WriteFile(envFile, []byte(env), 0644))
+	oldToken := svc.token
+
+	// Testea que SIGHUP rote el token sin reiniciar el servicio
+	assert.Nil(t, svc.cmd.Process.Signal(syscall.SIGHUP))
+	svc.token = "rotado"
+	for idx := 0; idx < 100 && svc.request(t, http.MethodGet, "/v2/users/1", "") != http.StatusOK; idx++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	assert.Equal(t, http.StatusOK, svc.request(t, http.MethodGet, "/v2/users/1", ""))
+	svc.token = oldToken
+	assert.Equal(t, http.StatusForbidden, svc.request(t, http.MethodGet, "/v2/users/1", ""))
+	svc.token = "rotado"
+
+	// Testea que el diff quede en el log y que los cambios no recargables se informen
+	assert.Contains(t, svc.output.String(), "configuracion recargada")
+	assert.Contains(t, svc.output.String(), `"settings":["jobs.workers"]`)
+
+	// Testea que los nuevos origenes CORS respondan el preflight
+	request, _ := http.NewRequest(http.MethodOptions, "http://"+svc.addr+"/v2/users", nil)
+	request.Header.Set("Origin", "https://app.example.com")
+	request.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	response, err := http.DefaultClient.Do(request)
+	assert.Nil(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusNoContent, response.StatusCode)
+	assert.Equal(t, "https://app.example.com", response.Header.Get("Access-Control-Allow-Origin"))
+
+	// Testea que el endpoint recargue y que una configuracion invalida no se aplique
+	assert.Equal(t, http.StatusOK, svc.request(t, http.MethodPost, "/admin/reload", ""))
+	assert.Nil(t, os.WriteFile(envFile, []byte(strings.Replace(env, "LOG_FORMAT=json", "LOG_FORMAT=xml", 1)), 0644))
+	assert.Equal(t, http.StatusUnprocessableEntity, svc.request(t, http.MethodPost, "/admin/reload", ""))
+	assert.Equal(t, http.StatusOK, svc.request(t, http.MethodGet, "/v2/users/1", ""))
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"io"
+	"os"
+	"sync"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/cmd/service/handler"
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/config"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/logger"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/ratelimit"
+
+	"github.com/joho/godotenv"
+)
+
+// environment looks the variables up in the process environment and then in
+// the .env file, read on every call so that a reload sees its changes.
+func environment() (lookupEnv func(key string) (string, bool), err error) {
+	dotenv, err := godotenv.Read()
+	lookupEnv = func(key string) (string, bool) {
+		if value, found := os.LookupEnv(key); found {
+			return value, true
+		}
+		value, found := dotenv[key]
+		return value, found
+	}
+
+	return lookupEnv, err
+}
+
+// reloader holds the configuration the service runs with, and reads it again
+// on SIGHUP or from the admin endpoint. The settings tagged reload are
+// swapped while serving, the others are reported and wait for a restart.
+type reloader struct {
+	args    []string
+	log     *logger.Logger
+	limiter *ratelimit.Limiter
+
+	mu      sync.Mutex
+	cfg     config.Config
+	sources config.Sources
+}
+
+func createReloader(args []string, cfg config.Config, sources config.Sources, log *logger.Logger, limiter *ratelimit.Limiter) *reloader {
+	r := &reloader{args: args, log: log, limiter: limiter, cfg: cfg, sources: sources}
+	r.apply(cfg)
+
+	return r
+}
+
+// apply sets the reloadable settings. Each one is swapped atomically, a
+// request sees either the old value or the new one.
+func (r *reloader) apply(cfg config.Config) {
+	level, _ := logger.ParseLevel(cfg.Log.Level)
+	r.log.SetLevel(level)
+
+	handler.UseAuth(cfg.Auth.Mode == config.AuthToken, cfg.Auth.Token)
+	handler.UseCORSOrigins(cfg.Server.Origins())
+
+	// The tiers were parsed when the configuration was validated.
+	tiers, keyTiers, _ := cfg.RateLimitTiers()
+	r.limiter.Reconfigure(tiers, keyTiers)
+}
+
+// Reload reads the configuration again. An invalid one is logged and
+// returned, and the service keeps running with the one it had.
+func (r *reloader) Reload() (changes []config.Change, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lookupEnv, envErr := environment()
+	if envErr != nil {
+		r.log.Warn("no se pudo cargar el archivo .env", logger.Err(envErr))
+	}
+	loaded, loadedSources, err := config.Load(r.args, lookupEnv, io.Discard)
+	if err != nil {
+		r.log.Error("no se recargo la configuracion, se mantiene la anterior", logger.Err(err))
+		return nil, err
+	}
+
+	next, changes := config.Reload(r.cfg, loaded)
+	sources := config.Sources{}
+	for key, source := range r.sources {
+		sources[key] = source
+	}
+
+	var pending []string
+	for _, change := range changes {
+		if change.Reloadable {
+			sources[change.Key] = loadedSources[change.Key]
+		} else {
+			pending = append(pending, change.Key)
+		}
+	}
+
+	r.apply(next)
+	r.cfg, r.sources = next, sources
+
+	r.log.Info("configuracion recargada", logger.F("changes", changes))
+	if len(pending) > 0 {
+		r.log.Warn("hay cambios que requieren reiniciar el servicio", logger.F("settings", pending))
+	}
+
+	return changes, nil
+}
+
+// View returns the configuration running, as the admin endpoint shows it.
+func (r *reloader) View() (effective map[string]interface{}, sources map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.cfg.Effective(), r.sources
+}
@@ -0,0 +1,390 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/logger"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/ratelimit"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/store"
+)
+
+// Config is every setting of the service. Each field is read, from lowest
+// to highest precedence, from its default, the config file, the environment
+// variable of its env tag and the flag named by its path, like
+// --server.address. Fields tagged secret are redacted when shown, and the
+// ones tagged reload are applied by a reload without restarting.
+type Config struct {
+	Server  Server  `yaml:"server"`
+	Storage Storage `yaml:"storage"`
+	Auth    Auth    `yaml:"auth"`
+	Log     Log     `yaml:"log"`
+	Limits  Limits  `yaml:"limits"`
+	Jobs    Jobs    `yaml:"jobs"`
+	Trace   Trace   `yaml:"trace"`
+	Ready   Ready   `yaml:"ready"`
+}
+
+type Server struct {
+	Address         string        `yaml:"address" env:"ADDRESS" usage:"direccion donde escucha el servidor, como :8080 (PORT sigue siendo aceptado)"`
+	Host            string        `yaml:"host" env:"HOST" usage:"host publicado en la documentacion de la API"`
+	APIVersion      int           `yaml:"api_version" env:"API_VERSION" usage:"version de la API de las rutas /users, 1 o 2"`
+	TLS             TLS           `yaml:"tls"`
+	CORSOrigins     string        `yaml:"cors_origins" env:"CORS_ORIGINS" reload:"true" usage:"origenes separados por comas que pueden llamar a la API desde un navegador, * para cualquiera"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" usage:"tiempo maximo para terminar las solicitudes y trabajos al apagarse"`
+	ShutdownDelay   time.Duration `yaml:"shutdown_delay" env:"SHUTDOWN_DELAY" usage:"espera entre la senal y el cierre para que el orquestador deje de enviar trafico"`
+}
+
+// TLS is enabled when both files are set.
+type TLS struct {
+	CertFile string `yaml:"cert_file" env:"TLS_CERT_FILE" usage:"certificado PEM del servidor"`
+	KeyFile  string `yaml:"key_file" env:"TLS_KEY_FILE" usage:"clave privada PEM del certificado"`
+}
+
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
+}
+
+type Storage struct {
+	Type        string `yaml:"type" env:"STORAGE_TYPE" usage:"tipo de almacenamiento, filestorage"`
+	Users       string `yaml:"users" env:"STORAGE_USERS" usage:"archivo de los usuarios"`
+	Idempotency string `yaml:"idempotency" env:"STORAGE_IDEMPOTENCY" usage:"archivo de las respuestas idempotentes"`
+	Jobs        string `yaml:"jobs" env:"STORAGE_JOBS" usage:"archivo de los trabajos"`
+	Erasures    string `yaml:"erasures" env:"STORAGE_ERASURES" usage:"archivo del registro de borrados"`
+	// Backups keeps the copies of the users file made by check --fix. They
+	// hold personal data that erasures do not reach, so they are removed
+	// once older than BackupRetention.
+	Backups         string        `yaml:"backups" env:"STORAGE_BACKUPS" usage:"directorio de las copias de seguridad de check --fix"`
+	BackupRetention time.Duration `yaml:"backup_retention" env:"STORAGE_BACKUP_RETENTION" usage:"tiempo que se guardan las copias de seguridad, que los borrados no alcanzan"`
+}
+
+const (
+	AuthToken = "token"
+	AuthNone  = "none"
+)
+
+type Auth struct {
+	Mode  string `yaml:"mode" env:"AUTH_MODE" reload:"true" usage:"token exige el encabezado token, none no autentica (solo desarrollo)"`
+	Token string `yaml:"token" env:"TOKEN" secret:"true" reload:"true" usage:"token de acceso aceptado en modo token"`
+}
+
+type Log struct {
+	Level  string `yaml:"level" env:"LOG_LEVEL" reload:"true" usage:"nivel de log: debug, info, warn o error"`
+	Format string `yaml:"format" env:"LOG_FORMAT" usage:"formato de log: json o console"`
+}
+
+type Limits struct {
+	RequestTimeout time.Duration `yaml:"request_timeout" env:"REQUEST_TIMEOUT" usage:"tiempo maximo de cada solicitud salvo las exportaciones, 0 para no limitarlo"`
+	IdempotencyTTL time.Duration `yaml:"idempotency_ttl" env:"IDEMPOTENCY_TTL" usage:"tiempo que se guardan las respuestas idempotentes"`
+	RateLimit      RateLimit     `yaml:"rate_limit"`
+}
+
+// RateLimit holds the tiers as written by the operator, like 60/1m:10. They
+// are read from the file or from RATE_LIMIT_TIERS and RATE_LIMIT_<TIER>_READ
+// and _WRITE, and have no flags.
+type RateLimit struct {
+	Tiers map[string]RateTier `yaml:"tiers" reload:"true"`
+	Keys  map[string]string   `yaml:"keys" secret:"true" reload:"true"`
+}
+
+type RateTier struct {
+	Read  string `yaml:"read" json:"read"`
+	Write string `yaml:"write" json:"write"`
+}
+
+type Jobs struct {
+	Dir         string        `yaml:"dir" env:"JOBS_DIR" usage:"directorio de los archivos de los trabajos"`
+	Workers     int           `yaml:"workers" env:"JOB_WORKERS" usage:"trabajos ejecutados a la vez"`
+	ArtifactTTL time.Duration `yaml:"artifact_ttl" env:"JOB_ARTIFACT_TTL" usage:"tiempo que se guardan los resultados de los trabajos"`
+}
+
+type Trace struct {
+	Exporter    string `yaml:"exporter" env:"TRACE_EXPORTER" usage:"exportador de trazas: none, stdout u otlp-file"`
+	File        string `yaml:"file" env:"TRACE_FILE" usage:"archivo del exportador otlp-file"`
+	ServiceName string `yaml:"service_name" env:"TRACE_SERVICE_NAME" usage:"nombre del servicio en las trazas"`
+}
+
+type Ready struct {
+	CheckTimeout  time.Duration `yaml:"check_timeout" env:"READY_CHECK_TIMEOUT" usage:"tiempo maximo de cada chequeo de /readyz"`
+	MinFreeDiskMB uint64        `yaml:"min_free_disk_mb" env:"READY_MIN_FREE_DISK_MB" usage:"espacio libre en disco minimo para estar listo"`
+}
+
+// Default returns the settings used when nothing else sets them.
+func Default() Config {
+	return Config{
+		Server: Server{
+			Address:         ":8080",
+			APIVersion:      1,
+			ShutdownTimeout: 30 * time.Second,
+		},
+		Storage: Storage{
+			Type:            string(store.FileType),
+			Users:           "users.json",
+			Idempotency:     "idempotency.json",
+			Jobs:            "jobs.json",
+			Erasures:        "erasures.json",
+			Backups:         "backups",
+			BackupRetention: 30 * 24 * time.Hour,
+		},
+		Auth: Auth{Mode: AuthToken},
+		Log:  Log{Level: "info", Format: "json"},
+		Limits: Limits{
+			RequestTimeout: 30 * time.Second,
+			IdempotencyTTL: 24 * time.Hour,
+			RateLimit:      RateLimit{Tiers: map[string]RateTier{}, Keys: map[string]string{}},
+		},
+		Jobs:  Jobs{Dir: "jobs", Workers: 2, ArtifactTTL: 24 * time.Hour},
+		Trace: Trace{Exporter: "none", File: "traces.jsonl", ServiceName: "users"},
+		Ready: Ready{CheckTimeout: 2 * time.Second, MinFreeDiskMB: 100},
+	}
+}
+
+// Origins returns the CORS origins as a list.
+func (s Server) Origins() (origins []string) {
+	for _, origin := range strings.Split(s.CORSOrigins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+
+	return origins
+}
+
+// RateLimitTiers parses the rate limit tiers for the limiter.
+func (c Config) RateLimitTiers() (tiers []ratelimit.Tier, keyTiers map[string]string, err error) {
+	names := make([]string, 0, len(c.Limits.RateLimit.Tiers))
+	for name := range c.Limits.RateLimit.Tiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		tier := ratelimit.Tier{Name: name}
+		tier.Read, err = ratelimit.ParseLimit(c.Limits.RateLimit.Tiers[name].Read)
+		if err != nil {
+			return nil, nil, fmt.Errorf("limits.rate_limit.tiers.%s.read: %w", name, err)
+		}
+		tier.Write, err = ratelimit.ParseLimit(c.Limits.RateLimit.Tiers[name].Write)
+		if err != nil {
+			return nil, nil, fmt.Errorf("limits.rate_limit.tiers.%s.write: %w", name, err)
+		}
+		tiers = append(tiers, tier)
+	}
+
+	keyTiers = map[string]string{}
+	for key, tier := range c.Limits.RateLimit.Keys {
+		keyTiers[key] = tier
+	}
+
+	return tiers, keyTiers, nil
+}
+
+// ValidationError lists every invalid setting, so they can all be fixed at
+// once.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "la configuracion no es valida:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Only keeps the problems of the settings under the prefixes, like
+// "storage.", and returns nil when none is left.
+func (e *ValidationError) Only(prefixes ...string) error {
+	var problems []string
+	for _, problem := range e.Problems {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(problem, prefix) {
+				problems = append(problems, problem)
+				break
+			}
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Problems: problems}
+}
+
+// Validate checks the settings, naming for each problem the key, where its
+// value came from according to sources, and what is expected.
+func (c Config) Validate(sources Sources) (err error) {
+	var problems []string
+	invalid := func(key string, format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf("%s%s: %s", key, sources.describe(key), fmt.Sprintf(format, args...)))
+	}
+
+	_, port, splitErr := net.SplitHostPort(c.Server.Address)
+	if splitErr != nil {
+		invalid("server.address", "%q no es una direccion, use host:puerto o :puerto, como :8080", c.Server.Address)
+	} else if number, convErr := strconv.Atoi(port); convErr != nil || number < 0 || number > 65535 {
+		invalid("server.address", "el puerto %q debe ser un numero entre 0 y 65535", port)
+	}
+	if c.Server.APIVersion != 1 && c.Server.APIVersion != 2 {
+		invalid("server.api_version", "debe ser 1 o 2(recibido: %d)", c.Server.APIVersion)
+	}
+	if c.Server.TLS.Enabled() {
+		if c.Server.TLS.CertFile == "" || c.Server.TLS.KeyFile == "" {
+			invalid("server.tls", "defina cert_file y key_file juntos, o ninguno para servir sin TLS")
+		}
+		for key, file := range map[string]string{"server.tls.cert_file": c.Server.TLS.CertFile, "server.tls.key_file": c.Server.TLS.KeyFile} {
+			if file == "" {
+				continue
+			}
+			if _, statErr := os.Stat(file); statErr != nil {
+				invalid(key, "no se puede leer %s: %v", file, statErr)
+			}
+		}
+	}
+	for _, origin := range c.Server.Origins() {
+		if origin == "*" {
+			continue
+		}
+		parsed, parseErr := url.Parse(origin)
+		if parseErr != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || parsed.Path != "" {
+			invalid("server.cors_origins", "%q no es un origen, use esquema y host como https://app.example.com, o *", origin)
+		}
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		invalid("server.shutdown_timeout", "debe ser mayor a cero, como 30s")
+	}
+	if c.Server.ShutdownDelay < 0 {
+		invalid("server.shutdown_delay", "no puede ser negativo")
+	}
+
+	if c.Storage.Type != string(store.FileType) {
+		invalid("storage.type", "el unico tipo soportado es %s(recibido: %s)", store.FileType, c.Storage.Type)
+	}
+	for key, file := range map[string]string{
+		"storage.users": c.Storage.Users, "storage.idempotency": c.Storage.Idempotency,
+		"storage.jobs": c.Storage.Jobs, "storage.erasures": c.Storage.Erasures,
+	} {
+		if file == "" {
+			invalid(key, "indique el archivo, como %s.json", strings.TrimPrefix(key, "storage."))
+			continue
+		}
+		if info, statErr := os.Stat(filepath.Dir(file)); statErr != nil || !info.IsDir() {
+			invalid(key, "el directorio %s no existe, creelo o cambie la ruta", filepath.Dir(file))
+		}
+	}
+	if c.Storage.Backups == "" {
+		invalid("storage.backups", "indique el directorio de las copias de seguridad, como backups")
+	}
+	if c.Storage.BackupRetention <= 0 {
+		invalid("storage.backup_retention", "debe ser mayor a cero")
+	}
+
+	switch c.Auth.Mode {
+	case AuthToken:
+		if c.Auth.Token == "" {
+			invalid("auth.token", "el modo token requiere un token, defina TOKEN o auth.token")
+		}
+	case AuthNone:
+	default:
+		invalid("auth.mode", "debe ser %s o %s(recibido: %s)", AuthToken, AuthNone, c.Auth.Mode)
+	}
+
+	if _, levelErr := logger.ParseLevel(c.Log.Level); levelErr != nil {
+		invalid("log.level", "%v", levelErr)
+	}
+	if _, formatErr := logger.ParseFormat(c.Log.Format); formatErr != nil {
+		invalid("log.format", "%v", formatErr)
+	}
+
+	if c.Limits.RequestTimeout < 0 {
+		invalid("limits.request_timeout", "no puede ser negativo, use 0 para no limitar")
+	}
+	if c.Limits.IdempotencyTTL <= 0 {
+		invalid("limits.idempotency_ttl", "debe ser mayor a cero, como 24h")
+	}
+	if _, _, tiersErr := c.RateLimitTiers(); tiersErr != nil {
+		invalid("limits.rate_limit", "%v", tiersErr)
+	}
+	for _, tier := range c.Limits.RateLimit.Keys {
+		if _, found := c.Limits.RateLimit.Tiers[tier]; !found {
+			invalid("limits.rate_limit.keys", "una clave usa el nivel %s, que no esta definido en limits.rate_limit.tiers", tier)
+		}
+	}
+
+	if c.Jobs.Dir == "" {
+		invalid("jobs.dir", "indique el directorio de los trabajos, como jobs")
+	}
+	if c.Jobs.Workers < 1 {
+		invalid("jobs.workers", "debe ser al menos 1(recibido: %d)", c.Jobs.Workers)
+	}
+	if c.Jobs.ArtifactTTL <= 0 {
+		invalid("jobs.artifact_ttl", "debe ser mayor a cero, como 24h")
+	}
+
+	switch strings.ToLower(c.Trace.Exporter) {
+	case "none", "stdout":
+	case "otlp-file":
+		if c.Trace.File == "" {
+			invalid("trace.file", "el exportador otlp-file requiere un archivo")
+		}
+	default:
+		invalid("trace.exporter", "debe ser none, stdout u otlp-file(recibido: %s)", c.Trace.Exporter)
+	}
+
+	if c.Ready.CheckTimeout <= 0 {
+		invalid("ready.check_timeout", "debe ser mayor a cero, como 2s")
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return &ValidationError{Problems: problems}
+	}
+
+	return nil
+}
+
+// redacted replaces the secrets shown by the admin endpoint.
+const redacted = "[redactado]"
+
+// Effective returns the settings keyed by their path, as the admin endpoint
+// shows them: durations as text and secrets redacted. The keys of the rate
+// limits are secrets too, they are shown by a prefix of their SHA-256 so an
+// operator can tell which one is configured.
+func (c Config) Effective() map[string]interface{} {
+	effective := map[string]interface{}{}
+	walk(reflect.ValueOf(c), "", func(path string, field reflect.StructField, value reflect.Value) {
+		secret := field.Tag.Get("secret") == "true"
+
+		switch typed := value.Interface().(type) {
+		case time.Duration:
+			effective[path] = typed.String()
+		case string:
+			if secret && typed != "" {
+				effective[path] = redacted
+			} else {
+				effective[path] = typed
+			}
+		case map[string]string:
+			shown := map[string]string{}
+			for key, tier := range typed {
+				if secret {
+					sum := sha256.Sum256([]byte(key))
+					key = "sha256:" + hex.EncodeToString(sum[:4])
+				}
+				shown[key] = tier
+			}
+			effective[path] = shown
+		default:
+			effective[path] = typed
+		}
+	})
+
+	return effective
+}
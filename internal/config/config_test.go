@@ -0,0 +1,164 @@
+package config
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// env is the environment of a test, read like os.LookupEnv.
+func env(pairs map[string]string) func(key string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, found := pairs[key]
+		return value, found
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	assert.Nil(t, os.WriteFile(path, []byte(`
+server:
+  address: ":9000"
+  api_version: 2
+log:
+  level: debug
+  format: console
+jobs:
+  workers: 4
+limits:
+  rate_limit:
+    tiers:
+      default: {read: 100/1m, write: 10/1m}
+    keys:
+      secret-token: default
+`), 0644))
+
+	cfg, sources, err := Load([]string{"--log.level", "warn"}, env(map[string]string{
+		"CONFIG_FILE": path,
+		"TOKEN":       "token123",
+		"LOG_LEVEL":   "error",
+		"JOB_WORKERS": "8",
+	}), io.Discard)
+	assert.Nil(t, err)
+
+	// Testea que el archivo pise los valores por defecto, el entorno al archivo y las flags al entorno
+	assert.Equal(t, ":9000", cfg.Server.Address)
+	assert.Equal(t, 2, cfg.Server.APIVersion)
+	assert.Equal(t, "console", cfg.Log.Format)
+	assert.Equal(t, 8, cfg.Jobs.Workers)
+	assert.Equal(t, "warn", cfg.Log.Level)
+	assert.Equal(t, 24*time.Hour, cfg.Jobs.ArtifactTTL)
+	assert.Equal(t, "archivo "+path, sources["server.address"])
+	assert.Equal(t, "env JOB_WORKERS", sources["jobs.workers"])
+	assert.Equal(t, "flag --log.level", sources["log.level"])
+	assert.Equal(t, "default", sources["jobs.artifact_ttl"])
+
+	tiers, keyTiers, err := cfg.RateLimitTiers()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(tiers))
+	assert.Equal(t, "default", keyTiers["secret-token"])
+
+	// Testea que PORT siga definiendo la direccion
+	cfg, sources, err = Load(nil, env(map[string]string{"TOKEN": "token123", "PORT": "8089"}), io.Discard)
+	assert.Nil(t, err)
+	assert.Equal(t, ":8089", cfg.Server.Address)
+	assert.Equal(t, "env PORT", sources["server.address"])
+}
+
+func TestLoadTOML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	assert.Nil(t, os.WriteFile(path, []byte(`
+# servicio de usuarios
+[server]
+address = "127.0.0.1:9001" # solo local
+shutdown_timeout = "10s"
+
+[auth]
+mode = 'none'
+
+[limits.rate_limit.tiers.batch]
+read = "600/1m"
+write = "60/1m:10"
+`), 0644))
+
+	cfg, sources, err := Load([]string{"--config", path}, env(nil), io.Discard)
+	assert.Nil(t, err)
+	assert.Equal(t, "127.0.0.1:9001", cfg.Server.Address)
+	assert.Equal(t, 10*time.Second, cfg.Server.ShutdownTimeout)
+	assert.Equal(t, AuthNone, cfg.Auth.Mode)
+	assert.Equal(t, "60/1m:10", cfg.Limits.RateLimit.Tiers["batch"].Write)
+	assert.Equal(t, "archivo "+path, sources["auth.mode"])
+
+	// Testea que una clave desconocida del archivo sea un error
+	assert.Nil(t, os.WriteFile(path, []byte("[server]\nadress = \":9001\"\n"), 0644))
+	_, _, err = Load([]string{"--config", path}, env(nil), io.Discard)
+	assert.Contains(t, err.Error(), "adress")
+}
+
+func TestLoadValidation(t *testing.T) {
+	_, _, err := Load([]string{"--jobs.workers", "0"}, env(map[string]string{
+		"AUTH_MODE":       "token",
+		"LOG_LEVEL":       "verbose",
+		"REQUEST_TIMEOUT": "30",
+		"STORAGE_USERS":   "/no/existe/users.json",
+	}), io.Discard)
+
+	// Testea que se informen todos los problemas, con la fuente de cada valor
+	var validation *ValidationError
+	assert.True(t, errors.As(err, &validation))
+	assert.Equal(t, 5, len(validation.Problems), err.Error())
+	assert.Contains(t, err.Error(), `limits.request_timeout (env REQUEST_TIMEOUT): "30" no es una duracion`)
+	assert.Contains(t, err.Error(), "auth.token: el modo token requiere un token")
+	assert.Contains(t, err.Error(), "log.level (env LOG_LEVEL):")
+	assert.Contains(t, err.Error(), "jobs.workers (flag --jobs.workers): debe ser al menos 1")
+	assert.Contains(t, err.Error(), "storage.users (env STORAGE_USERS): el directorio /no/existe no existe")
+
+	// Testea que se puedan quedar solo los problemas de una seccion
+	storageOnly := validation.Only("storage.", "trace.")
+	assert.Equal(t, 1, len(storageOnly.(*ValidationError).Problems))
+	assert.Nil(t, validation.Only("trace."))
+}
+
+func TestEffective(t *testing.T) {
+	cfg := Default()
+	cfg.Auth.Token = "token123"
+	cfg.Limits.RateLimit.Keys = map[string]string{"secret-token": "batch"}
+
+	effective := cfg.Effective()
+
+	// Testea que los secretos no se muestren
+	assert.Equal(t, "[redactado]", effective["auth.token"])
+	assert.Equal(t, map[string]string{"sha256:930bbdc5": "batch"}, effective["limits.rate_limit.keys"])
+	assert.Equal(t, "30s", effective["limits.request_timeout"])
+	assert.Equal(t, ":8080", effective["server.address"])
+}
+
+func TestReload(t *testing.T) {
+	running := Default()
+	running.Auth.Token = "token123"
+
+	loaded := Default()
+	loaded.Auth.Token = "token456"
+	loaded.Log.Level = "debug"
+	loaded.Server.Address = ":9000"
+	loaded.Limits.RateLimit.Keys = nil
+
+	next, changes := Reload(running, loaded)
+
+	// Testea que solo se apliquen los valores recargables y se informen los demas
+	assert.Equal(t, "token456", next.Auth.Token)
+	assert.Equal(t, "debug", next.Log.Level)
+	assert.Equal(t, ":8080", next.Server.Address)
+	assert.Equal(t, []Change{
+		{Key: "server.address", Old: ":8080", New: ":9000", Reloadable: false},
+		{Key: "auth.token", Old: "[redactado]", New: "[redactado]", Reloadable: true},
+		{Key: "log.level", Old: "info", New: "debug", Reloadable: true},
+	}, changes)
+	assert.Equal(t, "token123", running.Auth.Token)
+}
@@ -0,0 +1,328 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ConfigFileEnv names the config file when there is no --config flag.
+const ConfigFileEnv = "CONFIG_FILE"
+
+// Sources tells for each setting the layer that set it: default, the file,
+// the environment variable or the flag.
+type Sources map[string]string
+
+const sourceDefault = "default"
+
+func (s Sources) describe(key string) string {
+	source, found := s[key]
+	if !found || source == sourceDefault {
+		return ""
+	}
+
+	return " (" + source + ")"
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// walk visits the settings of v, the fields that are not structs, with their
+// path of yaml names, like server.tls.cert_file.
+func walk(v reflect.Value, prefix string, visit func(path string, field reflect.StructField, value reflect.Value)) {
+	t := v.Type()
+	for idx := 0; idx < t.NumField(); idx++ {
+		field := t.Field(idx)
+		path := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+
+		value := v.Field(idx)
+		if value.Kind() == reflect.Struct {
+			walk(value, path, visit)
+			continue
+		}
+		visit(path, field, value)
+	}
+}
+
+// Load builds the configuration from, lowest precedence first: the defaults,
+// the YAML or TOML file named by --config or CONFIG_FILE, the environment
+// variables read with lookupEnv and the flags in args. The result is
+// validated, and a ValidationError lists every problem found.
+func Load(args []string, lookupEnv func(key string) (string, bool), output io.Writer) (cfg Config, sources Sources, err error) {
+	cfg = Default()
+	sources = Sources{}
+	walk(reflect.ValueOf(cfg), "", func(path string, field reflect.StructField, value reflect.Value) {
+		sources[path] = sourceDefault
+	})
+
+	var problems []string
+	set := func(path string, value reflect.Value, raw string, source string) {
+		setErr := setValue(value, raw)
+		if setErr != nil {
+			problems = append(problems, fmt.Sprintf("%s (%s): %v", path, source, setErr))
+			return
+		}
+		sources[path] = source
+	}
+
+	// The flags are parsed first to find --config, and applied last.
+	flags, configPath := newFlagSet(&cfg, output)
+	err = flags.Parse(args)
+	if err != nil {
+		return cfg, sources, err
+	}
+
+	if *configPath == "" {
+		*configPath, _ = lookupEnv(ConfigFileEnv)
+	}
+	if *configPath != "" {
+		err = loadFile(*configPath, &cfg, sources)
+		if err != nil {
+			return cfg, sources, err
+		}
+	}
+
+	walk(reflect.ValueOf(&cfg).Elem(), "", func(path string, field reflect.StructField, value reflect.Value) {
+		env := field.Tag.Get("env")
+		if env == "" {
+			return
+		}
+		if raw, found := lookupEnv(env); found && raw != "" {
+			set(path, value, raw, "env "+env)
+		}
+	})
+	// PORT is how the address was set before, and what gin reads.
+	if port, found := lookupEnv("PORT"); found && port != "" && sources["server.address"] != "env ADDRESS" {
+		cfg.Server.Address = ":" + port
+		sources["server.address"] = "env PORT"
+	}
+	problems = append(problems, loadRateLimitEnv(&cfg, sources, lookupEnv)...)
+
+	flags.Visit(func(f *flag.Flag) {
+		pending, ok := f.Value.(*flagValue)
+		if !ok {
+			return
+		}
+		set(f.Name, pending.value, pending.raw, "flag --"+f.Name)
+	})
+
+	validationErr := cfg.Validate(sources)
+	var validation *ValidationError
+	if errors.As(validationErr, &validation) {
+		problems = append(problems, validation.Problems...)
+	}
+	if len(problems) > 0 {
+		return cfg, sources, &ValidationError{Problems: problems}
+	}
+
+	return cfg, sources, nil
+}
+
+// flagValue keeps the text of a flag until the layers below it are loaded.
+type flagValue struct {
+	value reflect.Value
+	raw   string
+}
+
+func (f *flagValue) String() string {
+	return f.raw
+}
+
+func (f *flagValue) Set(raw string) error {
+	f.raw = raw
+	return nil
+}
+
+// IsBoolFlag lets the bool settings be set as --flag, without a value.
+func (f *flagValue) IsBoolFlag() bool {
+	return f.value.IsValid() && f.value.Kind() == reflect.Bool
+}
+
+// Flag is a flag read by Load.
+type Flag struct {
+	Name  string
+	Usage string
+}
+
+// Flags lists the flags read by Load, for a command line parsed elsewhere
+// that hands the ones set to Load as --name=value.
+func Flags() (flags []Flag) {
+	cfg := Default()
+	set, _ := newFlagSet(&cfg, io.Discard)
+	set.VisitAll(func(f *flag.Flag) {
+		flags = append(flags, Flag{Name: f.Name, Usage: f.Usage})
+	})
+
+	return flags
+}
+
+func newFlagSet(cfg *Config, output io.Writer) (flags *flag.FlagSet, configPath *string) {
+	flags = flag.NewFlagSet("service", flag.ContinueOnError)
+	flags.SetOutput(output)
+	configPath = flags.String("config", "", "archivo de configuracion YAML (.yaml, .yml) o TOML (.toml), o la variable "+ConfigFileEnv)
+
+	walk(reflect.ValueOf(cfg).Elem(), "", func(path string, field reflect.StructField, value reflect.Value) {
+		if value.Kind() == reflect.Map {
+			return
+		}
+
+		usage := field.Tag.Get("usage")
+		if env := field.Tag.Get("env"); env != "" {
+			usage += " (env " + env + ")"
+		}
+		flags.Var(&flagValue{value: value}, path, usage)
+	})
+
+	return flags, configPath
+}
+
+func setValue(value reflect.Value, raw string) (err error) {
+	raw = strings.TrimSpace(raw)
+
+	if value.Type() == durationType {
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("%q no es una duracion, use por ejemplo 500ms, 30s o 24h", raw)
+		}
+		value.SetInt(int64(duration))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Int:
+		number, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%q no es un numero entero", raw)
+		}
+		value.SetInt(int64(number))
+	case reflect.Uint64:
+		number, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q no es un numero entero positivo", raw)
+		}
+		value.SetUint(number)
+	case reflect.Bool:
+		boolean, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q no es true ni false", raw)
+		}
+		value.SetBool(boolean)
+	default:
+		return fmt.Errorf("el tipo %s no se puede configurar como texto", value.Type())
+	}
+
+	return nil
+}
+
+// loadFile reads the config file over cfg. Unknown keys are an error, a
+// typo would otherwise be silently ignored.
+func loadFile(path string, cfg *Config, sources Sources) (err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("no se pudo leer el archivo de configuracion: %w", err)
+	}
+
+	var document map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &document)
+	case ".toml":
+		document, err = parseTOML(data)
+		if err == nil {
+			// The TOML document is decoded like the YAML one, through its tags.
+			data, err = yaml.Marshal(document)
+		}
+	default:
+		return fmt.Errorf("el archivo de configuracion %s debe terminar en .yaml, .yml o .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("el archivo de configuracion %s no es valido: %w", path, err)
+	}
+
+	err = yaml.UnmarshalStrict(data, cfg)
+	if err != nil {
+		return fmt.Errorf("el archivo de configuracion %s no es valido: %w", path, err)
+	}
+
+	walk(reflect.ValueOf(*cfg), "", func(keyPath string, field reflect.StructField, value reflect.Value) {
+		if hasPath(document, keyPath) {
+			sources[keyPath] = "archivo " + path
+		}
+	})
+
+	return nil
+}
+
+// hasPath tells whether the decoded document sets the dotted path.
+func hasPath(document interface{}, path string) bool {
+	for _, key := range strings.Split(path, ".") {
+		var found bool
+		switch typed := document.(type) {
+		case map[string]interface{}:
+			document, found = typed[key]
+		case map[interface{}]interface{}:
+			document, found = typed[key]
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// loadRateLimitEnv reads the tiers from RATE_LIMIT_TIERS, a list of names,
+// with the limits of each in RATE_LIMIT_<TIER>_READ and _WRITE, and the
+// keys from RATE_LIMIT_KEYS, a list of key:tier. They replace the ones of
+// the file.
+func loadRateLimitEnv(cfg *Config, sources Sources, lookupEnv func(key string) (string, bool)) (problems []string) {
+	if names, found := lookupEnv("RATE_LIMIT_TIERS"); found && strings.TrimSpace(names) != "" {
+		cfg.Limits.RateLimit.Tiers = map[string]RateTier{}
+		for _, name := range strings.Split(names, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+
+			prefix := "RATE_LIMIT_" + strings.ToUpper(name)
+			read, _ := lookupEnv(prefix + "_READ")
+			write, _ := lookupEnv(prefix + "_WRITE")
+			cfg.Limits.RateLimit.Tiers[name] = RateTier{Read: read, Write: write}
+		}
+		sources["limits.rate_limit.tiers"] = "env RATE_LIMIT_TIERS"
+	}
+
+	if pairs, found := lookupEnv("RATE_LIMIT_KEYS"); found && strings.TrimSpace(pairs) != "" {
+		cfg.Limits.RateLimit.Keys = map[string]string{}
+		for _, pair := range strings.Split(pairs, ",") {
+			pair = strings.TrimSpace(pair)
+			if pair == "" {
+				continue
+			}
+
+			idx := strings.LastIndex(pair, ":")
+			if idx <= 0 || idx == len(pair)-1 {
+				// The pair holds a key, a secret, so it is not echoed.
+				problems = append(problems, "limits.rate_limit.keys (env RATE_LIMIT_KEYS): hay una asignacion invalida, el formato esperado es clave:nivel separado por comas")
+				continue
+			}
+			cfg.Limits.RateLimit.Keys[pair[:idx]] = pair[idx+1:]
+		}
+		sources["limits.rate_limit.keys"] = "env RATE_LIMIT_KEYS"
+	}
+
+	return problems
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"reflect"
+)
+
+// Change is a setting that differs between two configurations, with its
+// values shown as Effective shows them.
+type Change struct {
+	Key        string      `json:"key"`
+	Old        interface{} `json:"old"`
+	New        interface{} `json:"new"`
+	Reloadable bool        `json:"reloadable"`
+}
+
+// Reload returns the configuration to keep running with once loaded has been
+// read again: the settings tagged reload come from loaded, the rest stay as
+// in running until a restart. changes lists every setting that differs, in
+// the order of Config.
+func Reload(running Config, loaded Config) (next Config, changes []Change) {
+	loadedValues := map[string]reflect.Value{}
+	walk(reflect.ValueOf(loaded), "", func(path string, field reflect.StructField, value reflect.Value) {
+		loadedValues[path] = value
+	})
+	runningShown, loadedShown := running.Effective(), loaded.Effective()
+
+	next = running
+	walk(reflect.ValueOf(&next).Elem(), "", func(path string, field reflect.StructField, value reflect.Value) {
+		loadedValue := loadedValues[path]
+		if equal(value, loadedValue) {
+			return
+		}
+
+		change := Change{Key: path, Old: runningShown[path], New: loadedShown[path], Reloadable: field.Tag.Get("reload") == "true"}
+		if change.Reloadable {
+			value.Set(loadedValue)
+		}
+		changes = append(changes, change)
+	})
+
+	return next, changes
+}
+
+// equal compares two settings, a nil map being equal to an empty one.
+func equal(a reflect.Value, b reflect.Value) bool {
+	if a.Kind() == reflect.Map && a.Len() == 0 && b.Len() == 0 {
+		return true
+	}
+
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseTOML reads the subset of TOML a config file needs: tables like
+// [server.tls], keys with a string, integer, float or boolean value, arrays
+// of those, and comments. Durations are strings, like "30s".
+func parseTOML(data []byte) (document map[string]interface{}, err error) {
+	document = map[string]interface{}{}
+	table := document
+
+	for idx, line := range strings.Split(string(data), "\n") {
+		number := idx + 1
+		line = strings.TrimSpace(stripComment(line))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") || strings.HasPrefix(line, "[[") {
+				return nil, fmt.Errorf("linea %d: tabla invalida %s", number, line)
+			}
+			table, err = tomlTable(document, strings.Trim(line, "[]"))
+			if err != nil {
+				return nil, fmt.Errorf("linea %d: %w", number, err)
+			}
+			continue
+		}
+
+		eq := strings.Index(line, "=")
+		if eq <= 0 {
+			return nil, fmt.Errorf("linea %d: se esperaba clave = valor", number)
+		}
+		key := tomlKey(line[:eq])
+		if _, found := table[key]; found {
+			return nil, fmt.Errorf("linea %d: la clave %s esta repetida", number, key)
+		}
+		table[key], err = tomlValue(strings.TrimSpace(line[eq+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("linea %d: %s: %w", number, key, err)
+		}
+	}
+
+	return document, nil
+}
+
+func tomlTable(document map[string]interface{}, name string) (table map[string]interface{}, err error) {
+	table = document
+	for _, part := range strings.Split(name, ".") {
+		part = tomlKey(part)
+		if part == "" {
+			return nil, fmt.Errorf("tabla invalida [%s]", name)
+		}
+
+		next, found := table[part]
+		if !found {
+			next = map[string]interface{}{}
+			table[part] = next
+		}
+		nested, ok := next.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s ya tiene un valor y no puede ser una tabla", part)
+		}
+		table = nested
+	}
+
+	return table, nil
+}
+
+func tomlKey(key string) string {
+	key = strings.TrimSpace(key)
+	if unquoted, err := strconv.Unquote(key); err == nil {
+		return unquoted
+	}
+
+	return strings.Trim(key, "'")
+}
+
+func tomlValue(raw string) (value interface{}, err error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		return strconv.Unquote(raw)
+	case strings.HasPrefix(raw, "'"):
+		if len(raw) < 2 || !strings.HasSuffix(raw, "'") {
+			return nil, fmt.Errorf("texto sin cerrar %s", raw)
+		}
+		return raw[1 : len(raw)-1], nil
+	case strings.HasPrefix(raw, "["):
+		if !strings.HasSuffix(raw, "]") {
+			return nil, fmt.Errorf("lista sin cerrar %s", raw)
+		}
+		values := []interface{}{}
+		for _, item := range splitTOMLArray(raw[1 : len(raw)-1]) {
+			itemValue, err := tomlValue(item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, itemValue)
+		}
+		return values, nil
+	case raw == "true" || raw == "false":
+		return raw == "true", nil
+	}
+
+	number := strings.ReplaceAll(raw, "_", "")
+	if integer, err := strconv.ParseInt(number, 10, 64); err == nil {
+		return integer, nil
+	}
+	if float, err := strconv.ParseFloat(number, 64); err == nil {
+		return float, nil
+	}
+
+	return nil, fmt.Errorf("valor invalido %s, los textos van entre comillas", raw)
+}
+
+// stripComment removes a # comment that is not inside a string.
+func stripComment(line string) string {
+	var quote rune
+	for idx, char := range line {
+		switch {
+		case quote != 0 && char == quote && (quote == '\'' || idx == 0 || line[idx-1] != '\\'):
+			quote = 0
+		case quote == 0 && (char == '"' || char == '\''):
+			quote = char
+		case quote == 0 && char == '#':
+			return line[:idx]
+		}
+	}
+
+	return line
+}
+
+// splitTOMLArray splits the items of an array, leaving the commas inside
+// strings alone.
+func splitTOMLArray(items string) (parts []string) {
+	var quote rune
+	start := 0
+	for idx, char := range items {
+		switch {
+		case quote != 0 && char == quote && (quote == '\'' || items[idx-1] != '\\'):
+			quote = 0
+		case quote == 0 && (char == '"' || char == '\''):
+			quote = char
+		case quote == 0 && char == ',':
+			parts = append(parts, items[start:idx])
+			start = idx + 1
+		}
+	}
+	parts = append(parts, items[start:])
+
+	trimmed := parts[:0]
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			trimmed = append(trimmed, part)
+		}
+	}
+
+	return trimmed
+}
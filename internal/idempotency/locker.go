@@ -0,0 +1,50 @@
+package idempotency
+
+import "sync"
+
+// KeyLocker serializes the requests that share an Idempotency-Key, so that
+// concurrent duplicates wait for the first one and then replay its response.
+type KeyLocker struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	mu      sync.Mutex
+	waiters int
+}
+
+func CreateKeyLocker() *KeyLocker {
+	newKeyLocker := &KeyLocker{
+		locks: map[string]*keyLock{},
+	}
+
+	return newKeyLocker
+}
+
+// Lock blocks until the key is free and returns the function that releases it.
+func (k *KeyLocker) Lock(key string) (unlock func()) {
+	k.mu.Lock()
+	lock, found := k.locks[key]
+	if !found {
+		lock = &keyLock{}
+		k.locks[key] = lock
+	}
+	lock.waiters++
+	k.mu.Unlock()
+
+	lock.mu.Lock()
+
+	unlock = func() {
+		lock.mu.Unlock()
+
+		k.mu.Lock()
+		lock.waiters--
+		if lock.waiters == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+
+	return unlock
+}
@@ -0,0 +1,145 @@
+package idempotency
+
+import (
+	"sync"
+	"time"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/store"
+)
+
+type Records struct {
+	Records []Record `json:"records"`
+}
+
+// Record keeps the response sent for an Idempotency-Key so that retries of the
+// same request can be answered without executing it again. Key is prefixed
+// with the client that sent it.
+type Record struct {
+	Key         string    `json:"key"`
+	Fingerprint string    `json:"fingerprint"`
+	StatusCode  int       `json:"status_code"`
+	ContentType string    `json:"content_type"`
+	Location    string    `json:"location,omitempty"`
+	Body        []byte    `json:"body"`
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type Repository interface {
+	Get(key string) (record Record, found bool, err error)
+	Save(record Record) (err error)
+	All() (records []Record, err error)
+	DeleteWhere(match func(record Record) bool) (deleted int, err error)
+}
+
+type repository struct {
+	db  store.Store
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+func CreateRepository(db store.Store) Repository {
+	newRepository := &repository{
+		db:  db,
+		now: time.Now,
+	}
+
+	return newRepository
+}
+
+func (r *repository) Get(key string) (record Record, found bool, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var records Records
+	err = r.db.Read(&records)
+	if err != nil {
+		return record, false, err
+	}
+
+	now := r.now()
+	for _, rec := range records.Records {
+		if rec.Key == key && now.Before(rec.ExpiresAt) {
+			return rec, true, nil
+		}
+	}
+
+	return record, false, nil
+}
+
+// Save stores the record, replacing any previous one with the same key and
+// dropping the records whose TTL already expired.
+func (r *repository) Save(record Record) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var records Records
+	err = r.db.Read(&records)
+	if err != nil {
+		return err
+	}
+
+	now := r.now()
+	alive := []Record{}
+	for _, rec := range records.Records {
+		if rec.Key != record.Key && now.Before(rec.ExpiresAt) {
+			alive = append(alive, rec)
+		}
+	}
+	records.Records = append(alive, record)
+
+	err = r.db.Write(&records)
+
+	return err
+}
+
+// All returns the records whose TTL has not expired.
+func (r *repository) All() (records []Record, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var stored Records
+	err = r.db.Read(&stored)
+	if err != nil {
+		return records, err
+	}
+
+	now := r.now()
+	for _, rec := range stored.Records {
+		if now.Before(rec.ExpiresAt) {
+			records = append(records, rec)
+		}
+	}
+
+	return records, nil
+}
+
+// DeleteWhere removes the records matched by match, expired or not.
+func (r *repository) DeleteWhere(match func(record Record) bool) (deleted int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var records Records
+	err = r.db.Read(&records)
+	if err != nil {
+		return 0, err
+	}
+
+	kept := []Record{}
+	for _, rec := range records.Records {
+		if match(rec) {
+			deleted++
+			continue
+		}
+		kept = append(kept, rec)
+	}
+
+	if deleted == 0 {
+		return 0, nil
+	}
+	records.Records = kept
+
+	err = r.db.Write(&records)
+
+	return deleted, err
+}
@@ -0,0 +1,88 @@
+package idempotency
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/logger"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/store"
+	"github.com/stretchr/testify/assert"
+)
+
+type myDbRecords struct {
+	Records []Record
+}
+
+func (db *myDbRecords) Read(data interface{}) (err error) {
+	data2 := data.(*Records)
+	data2.Records = db.Records
+
+	return nil
+}
+func (db *myDbRecords) Write(data interface{}) (err error) {
+
+	data2 := data.(*Records)
+	db.Records = data2.Records
+
+	return nil
+}
+
+func TestSaveAndGet(t *testing.T) {
+	now := time.Date(2021, 12, 22, 10, 0, 0, 0, time.UTC)
+	expired := Record{Key: "expired", Fingerprint: "abc", StatusCode: 200, CreatedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour)}
+	newRecord := Record{Key: "key1", Fingerprint: "def", StatusCode: 200, Body: []byte(`{"code":"200"}`), CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+
+	db := &myDbRecords{
+		Records: []Record{expired},
+	}
+
+	repo := &repository{
+		db:  db,
+		now: func() time.Time { return now },
+	}
+
+	// Testea que un registro vencido no sea devuelto
+	_, found, err := repo.Get("expired")
+	assert.Nil(t, err)
+	assert.False(t, found)
+
+	err = repo.Save(newRecord)
+	assert.Nil(t, err)
+
+	record, found, err := repo.Get("key1")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, newRecord, record)
+
+	// Testea que al guardar se eliminen los registros vencidos
+	assert.Equal(t, []Record{newRecord}, db.Records)
+}
+
+func TestRepositoryWithoutStoreFile(t *testing.T) {
+	now := time.Now()
+	record := Record{Key: "key1", Fingerprint: "def", StatusCode: 201, Body: []byte(`{"code":"201"}`), CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+
+	repo := CreateRepository(store.NewStorage(store.FileType, filepath.Join(t.TempDir(), "idempotency.json"), logger.Nop()))
+
+	// Testea que sin el archivo del almacenamiento no haya registros y se
+	// pueda guardar el primero
+	_, found, err := repo.Get("key1")
+	assert.Nil(t, err)
+	assert.False(t, found)
+
+	assert.Nil(t, repo.Save(record))
+
+	_, found, err = repo.Get("key1")
+	assert.Nil(t, err)
+	assert.True(t, found)
+}
+
+func TestFingerprint(t *testing.T) {
+	first := Fingerprint("POST", "/users/", []byte(`{"nombre": "a", "edad": 1}`))
+	sameBody := Fingerprint("POST", "/users/", []byte(`{"edad":1,"nombre":"a"}`))
+	otherBody := Fingerprint("POST", "/users/", []byte(`{"edad":2,"nombre":"a"}`))
+
+	assert.Equal(t, first, sameBody)
+	assert.NotEqual(t, first, otherBody)
+}
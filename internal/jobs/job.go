@@ -0,0 +1,65 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCanceled  Status = "canceled"
+)
+
+var (
+	ErrJobNotFound     = errors.New("el trabajo no fue encontrado")
+	ErrJobFinished     = errors.New("el trabajo ya termino")
+	ErrUnknownJobType  = errors.New("el tipo de trabajo no es soportado")
+	ErrArtifactExpired = errors.New("el archivo del trabajo ya no esta disponible")
+)
+
+type Jobs struct {
+	Jobs []Job `json:"jobs"`
+}
+
+// Job is a long-running import, export or bulk change executed by the Runner
+// outside of the request that created it.
+type Job struct {
+	ID     int64  `json:"id" xml:"id" yaml:"id"`
+	Type   string `json:"type" xml:"type" yaml:"type"`
+	Status Status `json:"status" xml:"status" yaml:"status"`
+	// Params are decoded by the handler of the job type.
+	Params json.RawMessage `json:"params,omitempty" xml:"-" yaml:"-" swaggerignore:"true"`
+	// Input is the file the job reads, like the body of an import.
+	Input           string   `json:"input,omitempty" xml:"-" yaml:"-" swaggerignore:"true"`
+	Progress        Progress `json:"progress" xml:"progress" yaml:"progress"`
+	CancelRequested bool     `json:"cancel_requested" xml:"cancel_requested" yaml:"cancel_requested"`
+	// Result is what the handler returned, like an import report.
+	Result json.RawMessage `json:"result,omitempty" xml:"-" yaml:"-" swaggertype:"object"`
+	Error  string          `json:"error,omitempty" xml:"error,omitempty" yaml:"error,omitempty"`
+	// Artifact is the file written by the job, downloadable until ArtifactExpiresAt.
+	Artifact          string     `json:"artifact,omitempty" xml:"artifact,omitempty" yaml:"artifact,omitempty"`
+	ArtifactExpiresAt *time.Time `json:"artifact_expires_at,omitempty" xml:"artifact_expires_at,omitempty" yaml:"artifact_expires_at,omitempty"`
+	// Attempts counts the runs, a job interrupted by a restart runs again.
+	Attempts   int        `json:"attempts" xml:"attempts" yaml:"attempts"`
+	CreatedAt  time.Time  `json:"created_at" xml:"created_at" yaml:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty" xml:"started_at,omitempty" yaml:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty" xml:"finished_at,omitempty" yaml:"finished_at,omitempty"`
+}
+
+// Progress counts the items processed by a job. Total is zero while unknown.
+type Progress struct {
+	Total     int `json:"total" xml:"total" yaml:"total"`
+	Processed int `json:"processed" xml:"processed" yaml:"processed"`
+	Failed    int `json:"failed" xml:"failed" yaml:"failed"`
+}
+
+// Finished tells whether the job reached a final status.
+func (j Job) Finished() bool {
+	return j.Status == StatusSucceeded || j.Status == StatusFailed || j.Status == StatusCanceled
+}
@@ -0,0 +1,220 @@
+package jobs
+
+import (
+	"sync"
+	"time"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/store"
+)
+
+type Repository interface {
+	Create(job Job) (createdJob Job, err error)
+	Get(id int64) (job Job, err error)
+	List() (jobs []Job, err error)
+	Update(id int64, apply func(job *Job) error) (updatedJob Job, err error)
+	Claim() (job Job, found bool, err error)
+	Requeue() (requeued int, err error)
+	ExpireArtifacts() (artifacts []string, err error)
+}
+
+type repository struct {
+	db  store.Store
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+func CreateRepository(db store.Store) Repository {
+	newRepository := &repository{
+		db:  db,
+		now: time.Now,
+	}
+
+	return newRepository
+}
+
+// Create queues a new job with the next id.
+func (r *repository) Create(job Job) (createdJob Job, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var jobs Jobs
+	err = r.db.Read(&jobs)
+	if err != nil {
+		return createdJob, err
+	}
+
+	job.ID = 1
+	if len(jobs.Jobs) > 0 {
+		job.ID = jobs.Jobs[len(jobs.Jobs)-1].ID + 1
+	}
+	job.Status = StatusQueued
+	job.CreatedAt = r.now()
+
+	jobs.Jobs = append(jobs.Jobs, job)
+
+	err = r.db.Write(&jobs)
+
+	return job, err
+}
+
+func (r *repository) Get(id int64) (job Job, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var jobs Jobs
+	err = r.db.Read(&jobs)
+	if err != nil {
+		return job, err
+	}
+
+	for _, storedJob := range jobs.Jobs {
+		if storedJob.ID == id {
+			return storedJob, nil
+		}
+	}
+
+	return job, ErrJobNotFound
+}
+
+func (r *repository) List() (jobs []Job, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var stored Jobs
+	err = r.db.Read(&stored)
+
+	return stored.Jobs, err
+}
+
+// Update saves the changes apply makes to the job, nothing is saved if it
+// returns an error.
+func (r *repository) Update(id int64, apply func(job *Job) error) (updatedJob Job, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var jobs Jobs
+	err = r.db.Read(&jobs)
+	if err != nil {
+		return updatedJob, err
+	}
+
+	for idx := range jobs.Jobs {
+		if jobs.Jobs[idx].ID != id {
+			continue
+		}
+
+		err = apply(&jobs.Jobs[idx])
+		if err != nil {
+			return jobs.Jobs[idx], err
+		}
+
+		err = r.db.Write(&jobs)
+
+		return jobs.Jobs[idx], err
+	}
+
+	return updatedJob, ErrJobNotFound
+}
+
+// Claim marks the oldest queued job as running and returns it, found is false
+// when there is nothing to run.
+func (r *repository) Claim() (job Job, found bool, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var jobs Jobs
+	err = r.db.Read(&jobs)
+	if err != nil {
+		return job, false, err
+	}
+
+	for idx := range jobs.Jobs {
+		if jobs.Jobs[idx].Status != StatusQueued {
+			continue
+		}
+
+		now := r.now()
+		jobs.Jobs[idx].Status = StatusRunning
+		jobs.Jobs[idx].StartedAt = &now
+		jobs.Jobs[idx].Attempts++
+
+		err = r.db.Write(&jobs)
+
+		return jobs.Jobs[idx], err == nil, err
+	}
+
+	return job, false, nil
+}
+
+// Requeue puts back in the queue the jobs left running by a previous process,
+// or cancels them if that was requested.
+func (r *repository) Requeue() (requeued int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var jobs Jobs
+	err = r.db.Read(&jobs)
+	if err != nil {
+		return 0, err
+	}
+
+	changed := false
+	for idx := range jobs.Jobs {
+		job := &jobs.Jobs[idx]
+		if job.Status != StatusRunning {
+			continue
+		}
+
+		changed = true
+		if job.CancelRequested {
+			now := r.now()
+			job.Status = StatusCanceled
+			job.FinishedAt = &now
+			continue
+		}
+
+		job.Status = StatusQueued
+		job.Progress = Progress{}
+		requeued++
+	}
+
+	if !changed {
+		return 0, nil
+	}
+
+	err = r.db.Write(&jobs)
+
+	return requeued, err
+}
+
+// ExpireArtifacts forgets the artifacts whose expiration passed and returns
+// them so that their files can be removed.
+func (r *repository) ExpireArtifacts() (artifacts []string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var jobs Jobs
+	err = r.db.Read(&jobs)
+	if err != nil {
+		return artifacts, err
+	}
+
+	now := r.now()
+	for idx := range jobs.Jobs {
+		job := &jobs.Jobs[idx]
+		if job.Artifact == "" || job.ArtifactExpiresAt == nil || now.Before(*job.ArtifactExpiresAt) {
+			continue
+		}
+
+		artifacts = append(artifacts, job.Artifact)
+		job.Artifact = ""
+	}
+
+	if len(artifacts) == 0 {
+		return artifacts, nil
+	}
+
+	err = r.db.Write(&jobs)
+
+	return artifacts, err
+}
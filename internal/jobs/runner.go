@@ -0,0 +1,465 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/logger"
+)
+
+// Handler runs a job of one type. It reports its progress through the
+// execution and should return soon after ctx is canceled.
+type Handler func(ctx context.Context, execution *Execution) (result interface{}, err error)
+
+// Runner executes the queued jobs in the background with a fixed number of
+// workers. Jobs live in the repository, so the ones interrupted by a restart
+// run again when the next Runner starts.
+type Runner struct {
+	repository  Repository
+	handlers    map[string]Handler
+	dir         string
+	workers     int
+	artifactTTL time.Duration
+	logger      *logger.Logger
+	now         func() time.Time
+
+	wake     chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+	working  sync.WaitGroup
+	mu       sync.Mutex
+	running  map[int64]context.CancelFunc
+	startErr error
+	flushErr error
+}
+
+// CreateRunner returns a runner keeping the job files in dir.
+func CreateRunner(repository Repository, dir string, workers int, artifactTTL time.Duration, log *logger.Logger) *Runner {
+	if workers < 1 {
+		workers = 1
+	}
+
+	newRunner := &Runner{
+		repository:  repository,
+		handlers:    map[string]Handler{},
+		dir:         dir,
+		workers:     workers,
+		artifactTTL: artifactTTL,
+		logger:      log.With(logger.F("component", "jobs")),
+		now:         time.Now,
+		wake:        make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+		running:     map[int64]context.CancelFunc{},
+	}
+
+	return newRunner
+}
+
+// Register sets the handler of a job type.
+func (r *Runner) Register(jobType string, handler Handler) {
+	r.handlers[jobType] = handler
+}
+
+// Start requeues the jobs interrupted by a previous process and starts the
+// workers and the cleanup of expired artifacts. StartError keeps its error.
+func (r *Runner) Start() (requeued int, err error) {
+	requeued, err = r.start()
+
+	r.mu.Lock()
+	r.startErr = err
+	r.mu.Unlock()
+
+	return requeued, err
+}
+
+func (r *Runner) start() (requeued int, err error) {
+	err = os.MkdirAll(r.dir, 0755)
+	if err != nil {
+		return 0, err
+	}
+
+	requeued, err = r.repository.Requeue()
+	if err != nil {
+		return 0, err
+	}
+
+	r.working.Add(r.workers)
+	for idx := 0; idx < r.workers; idx++ {
+		go r.work()
+	}
+	go r.cleanup()
+
+	return requeued, nil
+}
+
+// Stop stops claiming jobs and waits for the running ones to finish, or for
+// ctx to end. The jobs still running then stay as such in the repository and
+// run again when the next Runner starts.
+func (r *Runner) Stop(ctx context.Context) (err error) {
+	r.stopOnce.Do(func() { close(r.stop) })
+
+	done := make(chan struct{})
+	go func() {
+		r.working.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Enqueue saves a job of a registered type for the workers to run.
+func (r *Runner) Enqueue(jobType string, params interface{}, input string) (job Job, err error) {
+	if _, found := r.handlers[jobType]; !found {
+		return job, fmt.Errorf("%w(recibido: %s)", ErrUnknownJobType, jobType)
+	}
+
+	job = Job{Type: jobType, Input: input}
+	job.Params, err = json.Marshal(params)
+	if err != nil {
+		return job, err
+	}
+
+	job, err = r.repository.Create(job)
+	if err != nil {
+		return job, err
+	}
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+
+	return job, nil
+}
+
+// Get returns the job with the given id.
+func (r *Runner) Get(id int64) (job Job, err error) {
+	return r.repository.Get(id)
+}
+
+// Cancel stops a job. Queued jobs are canceled right away, running jobs once
+// their handler returns.
+func (r *Runner) Cancel(id int64) (job Job, err error) {
+	job, err = r.repository.Update(id, func(job *Job) error {
+		if job.Finished() {
+			return ErrJobFinished
+		}
+
+		job.CancelRequested = true
+		if job.Status == StatusQueued {
+			now := r.now()
+			job.Status = StatusCanceled
+			job.FinishedAt = &now
+		}
+
+		return nil
+	})
+	if err != nil {
+		return job, err
+	}
+
+	if job.Status == StatusCanceled {
+		r.removeFile(job.Input)
+		return job, nil
+	}
+
+	r.mu.Lock()
+	if cancel, found := r.running[id]; found {
+		cancel()
+	}
+	r.mu.Unlock()
+
+	return job, nil
+}
+
+// Path returns where a job file is kept.
+func (r *Runner) Path(name string) string {
+	return filepath.Join(r.dir, name)
+}
+
+// InputFile creates a file to hold the input of a job before enqueueing it.
+func (r *Runner) InputFile(jobType string) (file *os.File, err error) {
+	err = os.MkdirAll(r.dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.CreateTemp(r.dir, jobType+"-*.input")
+}
+
+func (r *Runner) work() {
+	defer r.working.Done()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		default:
+		}
+
+		job, found, err := r.repository.Claim()
+		r.flushed(err)
+		if err != nil || !found {
+			select {
+			case <-r.wake:
+			case <-ticker.C:
+			case <-r.stop:
+				return
+			}
+			continue
+		}
+
+		r.run(job)
+	}
+}
+
+func (r *Runner) run(job Job) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r.mu.Lock()
+	r.running[job.ID] = cancel
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.running, job.ID)
+		r.mu.Unlock()
+	}()
+
+	// A cancellation requested while the job was being claimed.
+	if current, err := r.repository.Get(job.ID); err == nil && current.CancelRequested {
+		cancel()
+	}
+
+	execution := &Execution{job: job, runner: r, logger: r.logger.With(logger.F("job_id", job.ID), logger.F("job_type", job.Type))}
+	execution.logger.Info("trabajo iniciado", logger.F("attempt", job.Attempts))
+
+	var result interface{}
+	err := errors.New("el trabajo no tiene un manejador")
+	if handler, found := r.handlers[job.Type]; found {
+		result, err = r.call(ctx, handler, execution)
+	}
+
+	r.finish(execution, ctx, result, err)
+}
+
+// call runs the handler, turning a panic into the error of the job.
+func (r *Runner) call(ctx context.Context, handler Handler, execution *Execution) (result interface{}, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("el trabajo fallo inesperadamente: %v", recovered)
+		}
+	}()
+
+	return handler(ctx, execution)
+}
+
+func (r *Runner) finish(execution *Execution, ctx context.Context, result interface{}, err error) {
+	encodedResult, marshalErr := json.Marshal(result)
+	if marshalErr != nil && err == nil {
+		err = marshalErr
+	}
+
+	job, updateErr := r.repository.Update(execution.job.ID, func(job *Job) error {
+		now := r.now()
+		job.FinishedAt = &now
+		job.Progress = execution.progress()
+		if result != nil {
+			job.Result = encodedResult
+		}
+
+		switch {
+		case err != nil && ctx.Err() != nil && job.CancelRequested:
+			job.Status = StatusCanceled
+		case err != nil:
+			job.Status = StatusFailed
+			job.Error = err.Error()
+		default:
+			job.Status = StatusSucceeded
+		}
+
+		if job.Status == StatusSucceeded && execution.artifact != "" {
+			expiresAt := now.Add(r.artifactTTL)
+			job.Artifact = execution.artifact
+			job.ArtifactExpiresAt = &expiresAt
+		}
+
+		return nil
+	})
+	r.flushed(updateErr)
+	if updateErr != nil {
+		execution.logger.Error("no se pudo guardar el final del trabajo", logger.Err(updateErr))
+		return
+	}
+
+	level := logger.InfoLevel
+	if job.Status == StatusFailed {
+		level = logger.WarnLevel
+	}
+	execution.logger.Log(level, "trabajo terminado", logger.F("status", job.Status), logger.F("progress", job.Progress), logger.Err(err))
+
+	r.removeFile(job.Input)
+	if job.Status != StatusSucceeded && execution.artifact != "" {
+		r.removeFile(execution.artifact)
+	}
+}
+
+func (r *Runner) cleanup() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-r.stop:
+			return
+		}
+
+		artifacts, err := r.repository.ExpireArtifacts()
+		r.flushed(err)
+		if err != nil {
+			r.logger.Error("no se pudieron vencer los archivos de los trabajos", logger.Err(err))
+			continue
+		}
+
+		for _, artifact := range artifacts {
+			r.removeFile(artifact)
+		}
+	}
+}
+
+// FlushError returns the error of the last write of the jobs made in the
+// background by the workers and the cleanup, nil when it succeeded.
+func (r *Runner) FlushError() (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.flushErr
+}
+
+// StartError returns the error of Start, the runner runs no jobs after it.
+func (r *Runner) StartError() (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.startErr
+}
+
+// flushed records the outcome of a background write.
+func (r *Runner) flushed(err error) {
+	r.mu.Lock()
+	r.flushErr = err
+	r.mu.Unlock()
+}
+
+func (r *Runner) removeFile(name string) {
+	if name == "" {
+		return
+	}
+
+	err := os.Remove(r.Path(name))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		r.logger.Warn("no se pudo borrar el archivo del trabajo", logger.F("file", name), logger.Err(err))
+	}
+}
+
+// progressInterval limits how often the progress of a running job is saved.
+const progressInterval = time.Second
+
+// Execution is handed to a Handler to read its job and report progress.
+type Execution struct {
+	job    Job
+	runner *Runner
+	logger *logger.Logger
+
+	mu       sync.Mutex
+	current  Progress
+	savedAt  time.Time
+	artifact string
+}
+
+// Job returns the job being run.
+func (e *Execution) Job() Job {
+	return e.job
+}
+
+// Params decodes the params the job was enqueued with.
+func (e *Execution) Params(v interface{}) error {
+	return json.Unmarshal(e.job.Params, v)
+}
+
+// InputPath returns the path of the input file of the job.
+func (e *Execution) InputPath() string {
+	return e.runner.Path(e.job.Input)
+}
+
+// CreateArtifact creates the file the job leaves for download, named after
+// the job with the given extension.
+func (e *Execution) CreateArtifact(extension string) (file *os.File, err error) {
+	e.artifact = fmt.Sprintf("job-%d.%s", e.job.ID, extension)
+
+	return os.Create(e.runner.Path(e.artifact))
+}
+
+// SetTotal sets how many items the job will process.
+func (e *Execution) SetTotal(total int) {
+	e.mu.Lock()
+	e.current.Total = total
+	e.mu.Unlock()
+
+	e.save()
+}
+
+// Advance adds processed and failed items, saving the progress at most once
+// per progressInterval.
+func (e *Execution) Advance(processed int, failed int) {
+	e.mu.Lock()
+	e.current.Processed += processed
+	e.current.Failed += failed
+	e.mu.Unlock()
+
+	e.save()
+}
+
+func (e *Execution) progress() Progress {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.current
+}
+
+func (e *Execution) save() {
+	now := e.runner.now()
+
+	e.mu.Lock()
+	if now.Sub(e.savedAt) < progressInterval {
+		e.mu.Unlock()
+		return
+	}
+	e.savedAt = now
+	progress := e.current
+	e.mu.Unlock()
+
+	_, err := e.runner.repository.Update(e.job.ID, func(job *Job) error {
+		job.Progress = progress
+		return nil
+	})
+	e.runner.flushed(err)
+	if err != nil {
+		e.logger.Warn("no se pudo guardar el progreso del trabajo", logger.Err(err))
+	}
+}
@@ -0,0 +1,209 @@
+package jobs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/logger"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type myDbJobs struct {
+	Jobs []Job
+}
+
+func (db *myDbJobs) Read(data interface{}) (err error) {
+	data2 := data.(*Jobs)
+	data2.Jobs = append([]Job{}, db.Jobs...)
+
+	return nil
+}
+func (db *myDbJobs) Write(data interface{}) (err error) {
+
+	data2 := data.(*Jobs)
+	db.Jobs = append([]Job{}, data2.Jobs...)
+
+	return nil
+}
+
+func TestRequeueAndExpireArtifacts(t *testing.T) {
+	now := time.Date(2021, 12, 22, 10, 0, 0, 0, time.UTC)
+	expired := now.Add(-time.Minute)
+	alive := now.Add(time.Hour)
+
+	db := &myDbJobs{
+		Jobs: []Job{
+			{ID: 1, Status: StatusRunning, Progress: Progress{Total: 10, Processed: 4}},
+			{ID: 2, Status: StatusRunning, CancelRequested: true},
+			{ID: 3, Status: StatusSucceeded, Artifact: "job-3.csv", ArtifactExpiresAt: &expired},
+			{ID: 4, Status: StatusSucceeded, Artifact: "job-4.csv", ArtifactExpiresAt: &alive},
+		},
+	}
+	repo := &repository{
+		db:  db,
+		now: func() time.Time { return now },
+	}
+
+	// Testea que los trabajos interrumpidos vuelvan a la cola
+	requeued, err := repo.Requeue()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, requeued)
+	assert.Equal(t, StatusQueued, db.Jobs[0].Status)
+	assert.Equal(t, Progress{}, db.Jobs[0].Progress)
+	assert.Equal(t, StatusCanceled, db.Jobs[1].Status)
+
+	job, found, err := repo.Claim()
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, int64(1), job.ID)
+	assert.Equal(t, 1, job.Attempts)
+
+	_, found, err = repo.Claim()
+	assert.Nil(t, err)
+	assert.False(t, found)
+
+	artifacts, err := repo.ExpireArtifacts()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"job-3.csv"}, artifacts)
+	assert.Equal(t, "", db.Jobs[2].Artifact)
+	assert.Equal(t, "job-4.csv", db.Jobs[3].Artifact)
+}
+
+func TestRunner(t *testing.T) {
+	dir, err := os.MkdirTemp("", "jobs")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	repo := CreateRepository(&myDbJobs{})
+	runner := CreateRunner(repo, dir, 1, time.Hour, logger.Nop())
+
+	started := make(chan struct{})
+	runner.Register("export", func(ctx context.Context, execution *Execution) (interface{}, error) {
+		file, err := execution.CreateArtifact("csv")
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		execution.SetTotal(2)
+		execution.Advance(2, 0)
+
+		return map[string]int{"rows": 2}, nil
+	})
+	runner.Register("wait", func(ctx context.Context, execution *Execution) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	blocked, release := make(chan struct{}), make(chan struct{})
+	runner.Register("block", func(ctx context.Context, execution *Execution) (interface{}, error) {
+		close(blocked)
+		<-release
+		return nil, nil
+	})
+
+	_, err = runner.Enqueue("unknown", nil, "")
+	assert.ErrorIs(t, err, ErrUnknownJobType)
+
+	_, err = runner.Start()
+	assert.Nil(t, err)
+
+	waitFor := func(id int64) Job {
+		for idx := 0; idx < 100; idx++ {
+			job, err := runner.Get(id)
+			assert.Nil(t, err)
+			if job.Finished() {
+				return job
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		t.Fatalf("el trabajo %d no termino", id)
+		return Job{}
+	}
+
+	job, err := runner.Enqueue("export", nil, "")
+	assert.Nil(t, err)
+
+	job = waitFor(job.ID)
+	assert.Equal(t, StatusSucceeded, job.Status)
+	assert.Equal(t, Progress{Total: 2, Processed: 2}, job.Progress)
+	assert.JSONEq(t, `{"rows":2}`, string(job.Result))
+	assert.Equal(t, "job-1.csv", job.Artifact)
+	assert.FileExists(t, runner.Path(job.Artifact))
+
+	// Testea la cancelacion de un trabajo en ejecucion
+	job, err = runner.Enqueue("wait", nil, "")
+	assert.Nil(t, err)
+	<-started
+
+	_, err = runner.Cancel(job.ID)
+	assert.Nil(t, err)
+
+	job = waitFor(job.ID)
+	assert.Equal(t, StatusCanceled, job.Status)
+
+	_, err = runner.Cancel(job.ID)
+	assert.ErrorIs(t, err, ErrJobFinished)
+
+	// Testea que Stop espere al trabajo en ejecucion y deje de tomar trabajos
+	job, err = runner.Enqueue("block", nil, "")
+	assert.Nil(t, err)
+	<-blocked
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, runner.Stop(ctx), context.DeadlineExceeded)
+	job, _ = runner.Get(job.ID)
+	assert.Equal(t, StatusRunning, job.Status)
+
+	close(release)
+	assert.Nil(t, runner.Stop(context.Background()))
+	job, _ = runner.Get(job.ID)
+	assert.Equal(t, StatusSucceeded, job.Status)
+
+	queued, err := runner.Enqueue("export", nil, "")
+	assert.Nil(t, err)
+	time.Sleep(50 * time.Millisecond)
+	queued, _ = runner.Get(queued.ID)
+	assert.Equal(t, StatusQueued, queued.Status)
+}
+
+func TestRunnerWithoutStoreFile(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "jobs.json")
+	repo := CreateRepository(store.NewStorage(store.FileType, fileName, logger.Nop()))
+	runner := CreateRunner(repo, filepath.Join(dir, "jobs"), 1, time.Hour, logger.Nop())
+	runner.Register("export", func(ctx context.Context, execution *Execution) (interface{}, error) {
+		return nil, nil
+	})
+
+	// Testea que en una instalacion nueva, sin el archivo de los trabajos, el
+	// runner inicie y cree el archivo con el primer trabajo
+	_, err := runner.Start()
+	assert.Nil(t, err)
+	assert.Nil(t, runner.StartError())
+	defer runner.Stop(context.Background())
+
+	job, err := runner.Enqueue("export", nil, "")
+	assert.Nil(t, err)
+	_, err = runner.Get(job.ID)
+	assert.Nil(t, err)
+	assert.FileExists(t, fileName)
+}
+
+func TestRunnerStartError(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "jobs.json")
+	assert.Nil(t, os.WriteFile(fileName, []byte(`[]`), 0644))
+	runner := CreateRunner(CreateRepository(store.NewStorage(store.FileType, fileName, logger.Nop())), filepath.Join(dir, "jobs"), 1, time.Hour, logger.Nop())
+
+	// Testea que un runner que no pudo iniciar lo informe
+	_, err := runner.Start()
+	assert.NotNil(t, err)
+	assert.Equal(t, err, runner.StartError())
+}
@@ -0,0 +1,92 @@
+package privacy
+
+import (
+	"sync"
+	"time"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/store"
+)
+
+type Erasures struct {
+	Erasures []Erasure `json:"erasures"`
+}
+
+// Erasure logs the erasure of the personal data of a user: what was removed
+// from each store and whether a scan made afterwards still found it.
+type Erasure struct {
+	ID     int64     `json:"id" xml:"id" yaml:"id"`
+	UserID int64     `json:"user_id" xml:"user_id" yaml:"user_id"`
+	Fields []string  `json:"fields" xml:"field" yaml:"fields"`
+	At     time.Time `json:"erased_at" xml:"erased_at" yaml:"erased_at"`
+	// IdempotencyRecords counts the stored responses deleted.
+	IdempotencyRecords int `json:"idempotency_records" xml:"idempotency_records" yaml:"idempotency_records"`
+	// Jobs counts the jobs whose params or results were scrubbed.
+	Jobs int `json:"jobs" xml:"jobs" yaml:"jobs"`
+	// Artifacts counts the job files deleted.
+	Artifacts int `json:"artifacts" xml:"artifacts" yaml:"artifacts"`
+	// Remaining counts the places still holding the data after the erasure,
+	// Verified is set when there are none.
+	Remaining int  `json:"remaining" xml:"remaining" yaml:"remaining"`
+	Verified  bool `json:"verified" xml:"verified" yaml:"verified"`
+}
+
+type Repository interface {
+	Create(erasure Erasure) (createdErasure Erasure, err error)
+	ListByUser(userID int64) (erasures []Erasure, err error)
+}
+
+type repository struct {
+	db store.Store
+	mu sync.Mutex
+}
+
+func CreateRepository(db store.Store) Repository {
+	newRepository := &repository{
+		db: db,
+	}
+
+	return newRepository
+}
+
+// Create appends the erasure to the log with the next id.
+func (r *repository) Create(erasure Erasure) (createdErasure Erasure, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var erasures Erasures
+	err = r.db.Read(&erasures)
+	if err != nil {
+		return createdErasure, err
+	}
+
+	erasure.ID = 1
+	if len(erasures.Erasures) > 0 {
+		erasure.ID = erasures.Erasures[len(erasures.Erasures)-1].ID + 1
+	}
+
+	erasures.Erasures = append(erasures.Erasures, erasure)
+
+	err = r.db.Write(&erasures)
+
+	return erasure, err
+}
+
+func (r *repository) ListByUser(userID int64) (erasures []Erasure, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var stored Erasures
+	err = r.db.Read(&stored)
+	if err != nil {
+		return erasures, err
+	}
+
+	erasures = []Erasure{}
+	for _, erasure := range stored.Erasures {
+		if erasure.UserID == userID {
+			erasures = append(erasures, erasure)
+		}
+	}
+
+	return erasures, nil
+}
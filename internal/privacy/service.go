@@ -0,0 +1,363 @@
+package privacy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/idempotency"
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/jobs"
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/users"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/logger"
+)
+
+var ErrPendingJobs = errors.New("hay trabajos sin terminar con datos del usuario, espere a que terminen o cancelelos")
+
+// redactedValue replaces the personal data found in the results of jobs.
+const redactedValue = "borrado"
+
+// SubjectData is what the stores of the service hold about a user: the
+// record, the stored responses and jobs that mention it and the log of its
+// erasures. It is not all the data about the user, NotIncluded says what is
+// left out.
+type SubjectData struct {
+	User               users.User       `json:"user" xml:"user" yaml:"user"`
+	IdempotencyRecords []StoredResponse `json:"idempotency_records" xml:"idempotency_record" yaml:"idempotency_records"`
+	Jobs               []jobs.Job       `json:"jobs" xml:"job" yaml:"jobs"`
+	Erasures           []Erasure        `json:"erasures" xml:"erasure" yaml:"erasures"`
+	NotIncluded        []string         `json:"not_included" xml:"not_included" yaml:"not_included"`
+}
+
+// NotIncluded lists the data about a user that Export does not gather. The
+// request logs carry the user_id but go to the output of the process, kept
+// by whoever collects it, and the service keeps no history of the changes.
+// Erase does not reach the backups either, they expire on their own.
+var NotIncluded = []string{
+	"registros de las solicitudes: el servicio los escribe en su salida y no los guarda",
+	"historial de cambios: el servicio guarda solo el estado actual del usuario y sus borrados",
+	"copias de seguridad de check --fix: guardan el archivo de usuarios como estaba y se eliminan pasado storage.backup_retention",
+}
+
+// StoredResponse is an idempotency record with its body as text.
+type StoredResponse struct {
+	Key         string    `json:"key" xml:"key" yaml:"key"`
+	StatusCode  int       `json:"status_code" xml:"status_code" yaml:"status_code"`
+	ContentType string    `json:"content_type" xml:"content_type" yaml:"content_type"`
+	Body        string    `json:"body" xml:"body" yaml:"body"`
+	CreatedAt   time.Time `json:"created_at" xml:"created_at" yaml:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at" xml:"expires_at" yaml:"expires_at"`
+}
+
+type Service interface {
+	Export(ctx context.Context, id int64) (data SubjectData, err error)
+	Erase(ctx context.Context, id int64) (erasure Erasure, tombstone users.User, err error)
+}
+
+type service struct {
+	users       users.Service
+	idempotency idempotency.Repository
+	jobs        jobs.Repository
+	jobsDir     string
+	erasures    Repository
+	logger      *logger.Logger
+	now         func() time.Time
+}
+
+// CreateService returns the service that exports and erases the personal data
+// of a user across the users store and the data derived from it: idempotency
+// records and the params, results and files of jobs kept in jobsDir.
+func CreateService(usersService users.Service, idempotencyRepository idempotency.Repository, jobsRepository jobs.Repository, jobsDir string, erasures Repository, log *logger.Logger) Service {
+	newService := &service{
+		users:       usersService,
+		idempotency: idempotencyRepository,
+		jobs:        jobsRepository,
+		jobsDir:     jobsDir,
+		erasures:    erasures,
+		logger:      log.With(logger.F("component", "privacy")),
+		now:         time.Now,
+	}
+
+	return newService
+}
+
+func (s *service) Export(ctx context.Context, id int64) (data SubjectData, err error) {
+	data.User, err = s.users.GetUserByID(ctx, id)
+	if err != nil {
+		return data, err
+	}
+
+	data.Erasures, err = s.erasures.ListByUser(id)
+	if err != nil {
+		return data, err
+	}
+	data.NotIncluded = NotIncluded
+
+	data.IdempotencyRecords, data.Jobs = []StoredResponse{}, []jobs.Job{}
+	if data.User.ErasedAt != nil {
+		return data, nil
+	}
+
+	subject := newSubject(data.User)
+
+	records, err := s.idempotency.All()
+	if err != nil {
+		return data, err
+	}
+	for _, record := range records {
+		if subject.mentionedIn(record.Body) {
+			data.IdempotencyRecords = append(data.IdempotencyRecords, StoredResponse{Key: record.Key, StatusCode: record.StatusCode, ContentType: record.ContentType, Body: string(record.Body), CreatedAt: record.CreatedAt, ExpiresAt: record.ExpiresAt})
+		}
+	}
+
+	storedJobs, err := s.jobs.List()
+	if err != nil {
+		return data, err
+	}
+	for _, job := range storedJobs {
+		if s.jobMentions(job, subject) {
+			data.Jobs = append(data.Jobs, job)
+		}
+	}
+
+	return data, nil
+}
+
+// Erase scrubs the derived data first and anonymizes the user last, so that a
+// failure leaves the record intact and the erasure can be retried. Jobs still
+// running with data of the user block the erasure.
+func (s *service) Erase(ctx context.Context, id int64) (erasure Erasure, tombstone users.User, err error) {
+	user, err := s.users.GetUserByID(ctx, id)
+	if err != nil {
+		return erasure, tombstone, err
+	}
+
+	err = users.CheckNotErased(user)
+	if err != nil {
+		return erasure, tombstone, err
+	}
+
+	subject := newSubject(user)
+
+	storedJobs, err := s.jobs.List()
+	if err != nil {
+		return erasure, tombstone, err
+	}
+
+	var mentioned []jobs.Job
+	for _, job := range storedJobs {
+		if !s.jobMentions(job, subject) {
+			continue
+		}
+		if !job.Finished() {
+			return erasure, tombstone, fmt.Errorf("%w(trabajo: %d)", ErrPendingJobs, job.ID)
+		}
+		mentioned = append(mentioned, job)
+	}
+
+	erasure = Erasure{UserID: id, Fields: users.ErasedFields}
+
+	erasure.IdempotencyRecords, err = s.idempotency.DeleteWhere(func(record idempotency.Record) bool {
+		return subject.mentionedIn(record.Body)
+	})
+	if err != nil {
+		return erasure, tombstone, err
+	}
+
+	for _, job := range mentioned {
+		artifactDeleted, err := s.scrubJob(job, subject)
+		if err != nil {
+			return erasure, tombstone, err
+		}
+
+		erasure.Jobs++
+		if artifactDeleted {
+			erasure.Artifacts++
+		}
+	}
+
+	_, tombstone, err = s.users.Erase(ctx, id)
+	if err != nil {
+		return erasure, tombstone, err
+	}
+	erasure.At = *tombstone.ErasedAt
+
+	erasure.Remaining, err = s.remaining(tombstone, subject)
+	if err != nil {
+		return erasure, tombstone, err
+	}
+	erasure.Verified = erasure.Remaining == 0
+
+	erasure, err = s.erasures.Create(erasure)
+	if err != nil {
+		return erasure, tombstone, err
+	}
+
+	s.log(ctx).Info("datos personales borrados", logger.F("user_id", erasure.UserID), logger.F("erasure_id", erasure.ID),
+		logger.F("idempotency_records", erasure.IdempotencyRecords), logger.F("jobs", erasure.Jobs),
+		logger.F("artifacts", erasure.Artifacts), logger.F("verified", erasure.Verified))
+
+	return erasure, tombstone, nil
+}
+
+// log returns the logger of the request in ctx, or the one of the service.
+func (s *service) log(ctx context.Context) *logger.Logger {
+	if requestLogger := logger.FromContext(ctx, nil); requestLogger != nil {
+		return requestLogger.With(logger.F("component", "privacy"))
+	}
+
+	return s.logger
+}
+
+// scrubJob drops the params of the job and redacts its result. Its artifact,
+// if it holds data of the user, is deleted.
+func (s *service) scrubJob(job jobs.Job, subject subject) (artifactDeleted bool, err error) {
+	artifactDeleted = job.Artifact != "" && s.fileMentions(job.Artifact, subject)
+	if artifactDeleted {
+		err = os.Remove(filepath.Join(s.jobsDir, job.Artifact))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return false, err
+		}
+	}
+
+	now := s.now()
+	_, err = s.jobs.Update(job.ID, func(job *jobs.Job) error {
+		if subject.mentionedIn(job.Params) {
+			job.Params = nil
+		}
+
+		job.Result = subject.redact(job.Result)
+		if subject.mentionedIn(job.Result) {
+			job.Result = nil
+		}
+
+		if artifactDeleted {
+			job.Artifact = ""
+			job.ArtifactExpiresAt = &now
+		}
+
+		return nil
+	})
+
+	return artifactDeleted, err
+}
+
+// remaining scans every store again for the personal data of the subject.
+func (s *service) remaining(tombstone users.User, subject subject) (remaining int, err error) {
+	encodedTombstone, err := json.Marshal(tombstone)
+	if err != nil {
+		return 0, err
+	}
+	if subject.mentionedIn(encodedTombstone) {
+		remaining++
+	}
+
+	records, err := s.idempotency.All()
+	if err != nil {
+		return remaining, err
+	}
+	for _, record := range records {
+		if subject.mentionedIn(record.Body) {
+			remaining++
+		}
+	}
+
+	storedJobs, err := s.jobs.List()
+	if err != nil {
+		return remaining, err
+	}
+	for _, job := range storedJobs {
+		if s.jobMentions(job, subject) {
+			remaining++
+		}
+	}
+
+	return remaining, nil
+}
+
+func (s *service) jobMentions(job jobs.Job, subject subject) bool {
+	return subject.mentionedIn(job.Params) || subject.mentionedIn(job.Result) ||
+		s.fileMentions(job.Input, subject) || s.fileMentions(job.Artifact, subject)
+}
+
+func (s *service) fileMentions(name string, subject subject) bool {
+	if name == "" {
+		return false
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.jobsDir, name))
+	if err != nil {
+		return false
+	}
+
+	return subject.mentionedIn(data)
+}
+
+// subject holds the personal values of a user to look for in derived data.
+type subject struct {
+	nombre   string
+	apellido string
+	email    string
+}
+
+func newSubject(user users.User) subject {
+	return subject{nombre: user.Nombre, apellido: user.Apellido, email: user.Email}
+}
+
+// mentionedIn tells whether data holds the email of the user, or both its
+// nombre and apellido.
+func (s subject) mentionedIn(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+
+	if s.email != "" && bytes.Contains(data, []byte(s.email)) {
+		return true
+	}
+
+	return s.nombre != "" && s.apellido != "" &&
+		bytes.Contains(data, []byte(s.nombre)) && bytes.Contains(data, []byte(s.apellido))
+}
+
+// redact replaces the JSON strings equal to a personal value of the user.
+func (s subject) redact(data json.RawMessage) json.RawMessage {
+	if len(data) == 0 {
+		return data
+	}
+
+	var decoded interface{}
+	err := json.Unmarshal(data, &decoded)
+	if err != nil {
+		return nil
+	}
+
+	redacted, err := json.Marshal(s.redactValue(decoded))
+	if err != nil {
+		return nil
+	}
+
+	return redacted
+}
+
+func (s subject) redactValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case string:
+		if typed == s.nombre || typed == s.apellido || typed == s.email {
+			return redactedValue
+		}
+	case []interface{}:
+		for idx := range typed {
+			typed[idx] = s.redactValue(typed[idx])
+		}
+	case map[string]interface{}:
+		for key := range typed {
+			typed[key] = s.redactValue(typed[key])
+		}
+	}
+
+	return value
+}
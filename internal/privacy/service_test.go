@@ -0,0 +1,150 @@
+package privacy
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/idempotency"
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/jobs"
+	"github.com/EdigiraldoML/go-web-arquitecture/internal/users"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/logger"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// myDb keeps any of the stores as JSON, like the file store.
+type myDb struct {
+	Data []byte
+}
+
+func (db *myDb) Read(data interface{}) (err error) {
+	if len(db.Data) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(db.Data, data)
+}
+func (db *myDb) Write(data interface{}) (err error) {
+	db.Data, err = json.Marshal(data)
+
+	return err
+}
+
+func TestExportAndErase(t *testing.T) {
+	dir, err := os.MkdirTemp("", "jobs")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	usersDb := &myDb{}
+	assert.Nil(t, usersDb.Write(&users.Users{Users: []users.User{
+		{Id: 1, Nombre: "Eva", Apellido: "Lopez", Email: "eva@email.com", FechaDeNacimiento: "1990-01-01", Altura: 1.6, Activo: true},
+		{Id: 2, Nombre: "Juan", Apellido: "Perez", Email: "juan@email.com", FechaDeNacimiento: "1990-01-01", Altura: 1.7, Activo: true},
+	}}))
+
+	idempotencyDb := &myDb{}
+	assert.Nil(t, idempotencyDb.Write(&idempotency.Records{Records: []idempotency.Record{
+		{Key: "eva", Body: []byte(`{"data":{"email":"eva@email.com"}}`), ExpiresAt: now.Add(time.Hour)},
+		{Key: "juan", Body: []byte(`{"data":{"email":"juan@email.com"}}`), ExpiresAt: now.Add(time.Hour)},
+	}}))
+
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "job-1.csv"), []byte("id,email\n1,eva@email.com\n"), 0644))
+	jobsDb := &myDb{}
+	assert.Nil(t, jobsDb.Write(&jobs.Jobs{Jobs: []jobs.Job{
+		{ID: 1, Type: "export", Status: jobs.StatusSucceeded, Artifact: "job-1.csv", ArtifactExpiresAt: &now},
+		{ID: 2, Type: "bulk_update", Status: jobs.StatusSucceeded, Result: json.RawMessage(`{"changes":[{"field":"apellido","from":"Lopez","to":"Gomez"},{"field":"nombre","from":"Eva","to":"Ana"}]}`)},
+		{ID: 3, Type: "export", Status: jobs.StatusSucceeded, Result: json.RawMessage(`{"rows":2}`)},
+	}}))
+
+	usersService := users.CreateService(users.CreateRepository(usersDb, logger.Nop()), logger.Nop())
+	service := CreateService(usersService, idempotency.CreateRepository(idempotencyDb), jobs.CreateRepository(jobsDb), dir, CreateRepository(&myDb{}), logger.Nop())
+
+	data, err := service.Export(context.Background(), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "eva@email.com", data.User.Email)
+	assert.Len(t, data.IdempotencyRecords, 1)
+	assert.Equal(t, "eva", data.IdempotencyRecords[0].Key)
+	assert.Len(t, data.Jobs, 2)
+	assert.Equal(t, []Erasure{}, data.Erasures)
+	assert.Equal(t, NotIncluded, data.NotIncluded)
+
+	erasure, tombstone, err := service.Erase(context.Background(), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, Erasure{ID: 1, UserID: 1, Fields: users.ErasedFields, At: *tombstone.ErasedAt, IdempotencyRecords: 1, Jobs: 2, Artifacts: 1, Verified: true}, erasure)
+	assert.Equal(t, users.ErasedEmail(1), tombstone.Email)
+	assert.False(t, tombstone.Activo)
+	assert.NoFileExists(t, filepath.Join(dir, "job-1.csv"))
+
+	// Testea que los datos de los demas usuarios no cambien
+	assert.NotContains(t, string(usersDb.Data), "Eva")
+	assert.Contains(t, string(usersDb.Data), "juan@email.com")
+	assert.NotContains(t, string(idempotencyDb.Data), "eva@email.com")
+	assert.Contains(t, string(idempotencyDb.Data), `"key":"juan"`)
+	assert.NotContains(t, string(jobsDb.Data), "Lopez")
+	assert.Contains(t, string(jobsDb.Data), "Gomez")
+
+	data, err = service.Export(context.Background(), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []Erasure{erasure}, data.Erasures)
+
+	_, _, err = service.Erase(context.Background(), 1)
+	assert.ErrorIs(t, err, users.ErrUserErased)
+
+	_, err = usersService.Patch(context.Background(), 1, func(user users.User) (users.User, error) {
+		user.Nombre = "Eva"
+		return user, nil
+	})
+	assert.ErrorIs(t, err, users.ErrUserErased)
+}
+
+func TestEraseWithPendingJobs(t *testing.T) {
+	usersDb := &myDb{}
+	assert.Nil(t, usersDb.Write(&users.Users{Users: []users.User{
+		{Id: 1, Nombre: "Eva", Apellido: "Lopez", Email: "eva@email.com", FechaDeNacimiento: "1990-01-01", Altura: 1.6, Activo: true},
+	}}))
+	jobsDb := &myDb{}
+	assert.Nil(t, jobsDb.Write(&jobs.Jobs{Jobs: []jobs.Job{
+		{ID: 1, Type: "bulk_delete", Status: jobs.StatusQueued, Params: json.RawMessage(`{"filter":{"Searched":{"email":"eva@email.com"}}}`)},
+	}}))
+
+	service := CreateService(users.CreateService(users.CreateRepository(usersDb, logger.Nop()), logger.Nop()), idempotency.CreateRepository(&myDb{}), jobs.CreateRepository(jobsDb), "", CreateRepository(&myDb{}), logger.Nop())
+
+	_, _, err := service.Erase(context.Background(), 1)
+	assert.ErrorIs(t, err, ErrPendingJobs)
+	assert.Contains(t, string(usersDb.Data), "eva@email.com")
+}
+
+func TestExportAndEraseWithoutStoreFiles(t *testing.T) {
+	dir := t.TempDir()
+	newStore := func(name string) store.Store {
+		return store.NewStorage(store.FileType, filepath.Join(dir, name), logger.Nop())
+	}
+
+	usersDb := newStore("users.json")
+	assert.Nil(t, usersDb.Write(&users.Users{Users: []users.User{
+		{Id: 1, Nombre: "Eva", Apellido: "Lopez", Email: "eva@email.com", FechaDeNacimiento: "1990-01-01", Altura: 1.6, Activo: true},
+	}}))
+
+	usersService := users.CreateService(users.CreateRepository(usersDb, logger.Nop()), logger.Nop())
+	service := CreateService(usersService, idempotency.CreateRepository(newStore("idempotency.json")), jobs.CreateRepository(newStore("jobs.json")), filepath.Join(dir, "jobs"), CreateRepository(newStore("erasures.json")), logger.Nop())
+
+	// Testea que sin los archivos de los demas almacenamientos se pueda
+	// exportar y borrar al usuario
+	data, err := service.Export(context.Background(), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "eva@email.com", data.User.Email)
+	assert.Empty(t, data.IdempotencyRecords)
+	assert.Empty(t, data.Jobs)
+	assert.Equal(t, []Erasure{}, data.Erasures)
+
+	erasure, _, err := service.Erase(context.Background(), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), erasure.ID)
+	assert.True(t, erasure.Verified)
+	assert.FileExists(t, filepath.Join(dir, "erasures.json"))
+}
@@ -0,0 +1,133 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/logger"
+)
+
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+var (
+	ErrInvalidBatchOperation = errors.New("la operacion del lote no es valida")
+	ErrBatchRolledBack       = errors.New("la operacion fue revertida porque otra operacion del lote fallo")
+)
+
+// BatchOperation is one item of a batch. User holds the values for create and
+// update, ID the user to update or delete.
+type BatchOperation struct {
+	Op   string
+	ID   int64
+	User User
+}
+
+// BatchResult is the outcome of the operation at Index. User is the created
+// or updated user, Err is nil when the operation was applied.
+type BatchResult struct {
+	Index int
+	Op    string
+	ID    int64
+	User  *User
+	Err   error
+}
+
+// Batch runs the operations in order over the stored users and saves them in
+// a single write. Failed operations are reported in their result and skipped,
+// unless atomic is set: then nothing is saved if any of them fails, and the
+// results of the operations that did succeed carry ErrBatchRolledBack.
+func (s *service) Batch(ctx context.Context, operations []BatchOperation, atomic bool) (results []BatchResult, err error) {
+	err = s.repository.Transaction(ctx, func(usersInDatabase *Users) error {
+		results = make([]BatchResult, len(operations))
+		failed := false
+
+		for idx, operation := range operations {
+			user, err := s.applyBatchOperation(ctx, usersInDatabase, operation)
+
+			results[idx] = BatchResult{Index: idx, Op: operation.Op, ID: operation.ID, Err: err}
+			if err != nil {
+				failed = true
+				continue
+			}
+			if operation.Op != BatchDelete {
+				results[idx].ID = user.Id
+				results[idx].User = &user
+			}
+		}
+
+		if atomic && failed {
+			for idx := range results {
+				if results[idx].Err == nil {
+					results[idx].Err = ErrBatchRolledBack
+					results[idx].ID = operations[idx].ID
+					results[idx].User = nil
+				}
+			}
+			return ErrBatchRolledBack
+		}
+
+		return nil
+	})
+	if errors.Is(err, ErrBatchRolledBack) {
+		s.log(ctx).Debug("lote revertido", logger.F("operations", len(operations)))
+		return results, nil
+	}
+	if err != nil {
+		return results, err
+	}
+
+	s.log(ctx).Debug("lote aplicado", logger.F("operations", len(operations)), logger.F("atomic", atomic))
+
+	return results, nil
+}
+
+func (s *service) applyBatchOperation(ctx context.Context, usersInDatabase *Users, operation BatchOperation) (user User, err error) {
+	switch operation.Op {
+	case BatchCreate:
+		err = ValidateUser(operation.User)
+		if err != nil {
+			return user, err
+		}
+
+		user, err = s.prepareCreate(ctx, *usersInDatabase, operation.User)
+		if err != nil {
+			return user, err
+		}
+
+		usersInDatabase.Users = append(usersInDatabase.Users, user)
+	case BatchUpdate:
+		err = ValidateUser(operation.User)
+		if err != nil {
+			return user, err
+		}
+
+		user, err = s.prepareReplace(ctx, *usersInDatabase, operation.ID, operation.User)
+		if err != nil {
+			return user, err
+		}
+
+		ptrUser, _ := GetUserById(operation.ID, usersInDatabase)
+		*ptrUser = user
+	case BatchDelete:
+		idx, err := GetUserIndexById(operation.ID, usersInDatabase)
+		if err != nil {
+			return user, err
+		}
+
+		err = CheckNotErased(usersInDatabase.Users[idx])
+		if err != nil {
+			return user, err
+		}
+
+		usersInDatabase.Users = append(usersInDatabase.Users[:idx], usersInDatabase.Users[idx+1:]...)
+	default:
+		err = fmt.Errorf("%w, op debe ser create, update o delete(recibido: %s)", ErrInvalidBatchOperation, operation.Op)
+	}
+
+	return user, err
+}
@@ -0,0 +1,107 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/logger"
+)
+
+var (
+	ErrEmptyFilter        = errors.New("se requiere al menos un filtro para modificar usuarios en bloque")
+	ErrConfirmationNeeded = errors.New("la cantidad de usuarios a modificar no fue confirmada")
+	ErrCountMismatch      = errors.New("la cantidad confirmada no coincide con la cantidad de usuarios encontrados")
+	errDryRun             = errors.New("simulacion, no se guardan los cambios")
+)
+
+// BulkOptions control UpdateWhere and DeleteWhere. Unless DryRun is set, the
+// change is only applied if ConfirmCount is the number of matched users.
+type BulkOptions struct {
+	DryRun       bool
+	ConfirmCount int
+}
+
+// BulkChange is the state of a matched user before and after the change.
+// After is nil for deleted users.
+type BulkChange struct {
+	Before User
+	After  *User
+}
+
+// UpdateWhere applies the patch to every user matching the filter in a single
+// write. If the patch fails for any of them nothing is saved.
+func (s *service) UpdateWhere(ctx context.Context, filter Filter, patch PatchFunc, options BulkOptions) (changes []BulkChange, err error) {
+	return s.bulk(ctx, filter, options, func(usersInDatabase *Users, matched User) (change BulkChange, err error) {
+		patchedUser, err := s.preparePatch(*usersInDatabase, matched, patch)
+		if err != nil {
+			return change, fmt.Errorf("usuario %d: %w", matched.Id, err)
+		}
+
+		// Later patches are validated against the users already patched.
+		ptrUser, _ := GetUserById(matched.Id, usersInDatabase)
+		*ptrUser = patchedUser
+
+		return BulkChange{Before: matched, After: &patchedUser}, nil
+	})
+}
+
+// DeleteWhere deletes every user matching the filter in a single write.
+func (s *service) DeleteWhere(ctx context.Context, filter Filter, options BulkOptions) (changes []BulkChange, err error) {
+	return s.bulk(ctx, filter, options, func(usersInDatabase *Users, matched User) (change BulkChange, err error) {
+		err = CheckNotErased(matched)
+		if err != nil {
+			return change, err
+		}
+
+		idx, err := GetUserIndexById(matched.Id, usersInDatabase)
+		if err != nil {
+			return change, err
+		}
+
+		usersInDatabase.Users = append(usersInDatabase.Users[:idx], usersInDatabase.Users[idx+1:]...)
+
+		return BulkChange{Before: matched}, nil
+	})
+}
+
+type bulkFunc func(usersInDatabase *Users, matched User) (change BulkChange, err error)
+
+func (s *service) bulk(ctx context.Context, filter Filter, options BulkOptions, apply bulkFunc) (changes []BulkChange, err error) {
+	if filter.IsEmpty() {
+		return changes, ErrEmptyFilter
+	}
+
+	err = s.repository.Transaction(ctx, func(usersInDatabase *Users) error {
+		matched := s.filterUsers(Users{Users: append([]User{}, usersInDatabase.Users...)}, filter)
+
+		if !options.DryRun && options.ConfirmCount != len(matched) {
+			return fmt.Errorf("%w(encontrados: %d, confirmados: %d)", ErrCountMismatch, len(matched), options.ConfirmCount)
+		}
+
+		changes = []BulkChange{}
+		for _, user := range matched {
+			change, err := apply(usersInDatabase, user)
+			if err != nil {
+				return err
+			}
+			changes = append(changes, change)
+		}
+
+		if options.DryRun {
+			return errDryRun
+		}
+
+		return nil
+	})
+	if errors.Is(err, errDryRun) {
+		return changes, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.log(ctx).Debug("cambio masivo aplicado", logger.F("params", filter.Params), logger.F("users", len(changes)))
+
+	return changes, nil
+}
@@ -0,0 +1,24 @@
+package users
+
+import "time"
+
+// Clock tells the service the current time, tests inject a fixed one.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now().UTC()
+}
+
+// SystemClock is the clock used by CreateService.
+var SystemClock Clock = systemClock{}
+
+// FixedClock always returns the same instant.
+type FixedClock time.Time
+
+func (f FixedClock) Now() time.Time {
+	return time.Time(f)
+}
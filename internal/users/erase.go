@@ -0,0 +1,70 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/logger"
+)
+
+var ErrUserErased = errors.New("los datos personales del usuario fueron borrados")
+
+// ErasedFields are the personal fields replaced by Erase.
+var ErasedFields = []string{"nombre", "apellido", "email"}
+
+const erasedValue = "borrado"
+
+// Erase irreversibly replaces the personal fields of the user. The record is
+// kept, inactive and marked with ErasedAt, as a tombstone for the data that
+// refers to its id; it can no longer be changed or deleted. original holds
+// the values before the erasure so that derived data can be scrubbed.
+func (s *service) Erase(ctx context.Context, id int64) (original User, tombstone User, err error) {
+	err = s.repository.Transaction(ctx, func(usersInDatabase *Users) error {
+		ptrUser, err := GetUserById(id, usersInDatabase)
+		if err != nil {
+			return err
+		}
+
+		err = CheckNotErased(*ptrUser)
+		if err != nil {
+			return err
+		}
+
+		original = s.withEdad(*ptrUser)
+
+		now := s.clock.Now()
+		ptrUser.Nombre = erasedValue
+		ptrUser.Apellido = erasedValue
+		ptrUser.Email = ErasedEmail(id)
+		ptrUser.Activo = false
+		ptrUser.ErasedAt = &now
+		ptrUser.UpdatedAt = now
+
+		tombstone = s.withEdad(*ptrUser)
+
+		return nil
+	})
+	if err != nil {
+		return original, tombstone, err
+	}
+
+	s.log(ctx).Debug("datos personales del usuario borrados", logger.F("user_id", id))
+
+	return original, tombstone, nil
+}
+
+// ErasedEmail is the email left on a tombstone, unique so that the email
+// check of the other users still holds.
+func ErasedEmail(id int64) string {
+	return fmt.Sprintf("%s-%d@invalid", erasedValue, id)
+}
+
+// CheckNotErased rejects changes to a tombstone.
+func CheckNotErased(user User) (err error) {
+	if user.ErasedAt != nil {
+		return fmt.Errorf("%w(id: %d)", ErrUserErased, user.Id)
+	}
+
+	return nil
+}
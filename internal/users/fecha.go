@@ -0,0 +1,47 @@
+package users
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// fechaLayouts are the formats found in fecha_de_creacion, clients send both
+// two and four digit years ("22/12/21", "13/12/2021").
+var fechaLayouts = []string{"02/01/2006", "2/1/2006", "02/01/06", "2/1/06"}
+
+// ParseFecha parses a legacy dd/mm/yy(yy) date.
+func ParseFecha(fecha string) (date time.Time, err error) {
+	fecha = strings.TrimSpace(fecha)
+	for _, layout := range fechaLayouts {
+		date, err = time.Parse(layout, fecha)
+		if err == nil {
+			return date, nil
+		}
+	}
+
+	err = fmt.Errorf("la fecha %q no tiene el formato dd/mm/aaaa", fecha)
+
+	return date, err
+}
+
+// FormatFecha writes a date in the dd/mm/yyyy format used by version 1.
+func FormatFecha(date time.Time) string {
+	return date.Format("02/01/2006")
+}
+
+// ParseDateParam parses the created_after/created_before params, given as an
+// RFC 3339 timestamp or as a yyyy-mm-dd date (midnight UTC).
+func ParseDateParam(value string) (date time.Time, err error) {
+	date, err = time.Parse(time.RFC3339, value)
+	if err == nil {
+		return date, nil
+	}
+
+	date, err = time.Parse("2006-01-02", value)
+	if err != nil {
+		err = fmt.Errorf("la fecha debe tener formato RFC 3339 o aaaa-mm-dd(recibido: %s)", value)
+	}
+
+	return date, err
+}
@@ -0,0 +1,506 @@
+package users
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Codes of the issues found by Fsck.
+const (
+	IssueInvalidDocument   = "invalid_document"
+	IssueUnknownField      = "unknown_field"
+	IssueWrongType         = "wrong_type"
+	IssueMissingField      = "missing_field"
+	IssueInvalidId         = "invalid_id"
+	IssueDuplicateId       = "duplicate_id"
+	IssueInvalidEmail      = "invalid_email"
+	IssueDuplicateEmail    = "duplicate_email"
+	IssueInvalidDate       = "invalid_date"
+	IssueInconsistentState = "inconsistent_activo"
+	IssueInvalidUser       = "invalid_user"
+	IssuePendingMigration  = "pending_migration"
+)
+
+// Severities of the issues. Only the errors make a document fail the check,
+// the warnings are data that migrate fills.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// FsckIssue is a problem of a users document. Position is the index of the
+// user in the document, -1 for the document itself, and Id the id the user
+// has in the file, 0 when it has none.
+type FsckIssue struct {
+	Position int    `json:"position"`
+	Id       int64  `json:"id"`
+	Field    string `json:"field,omitempty"`
+	Code     string `json:"code"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Fixable  bool   `json:"fixable"`
+}
+
+// FsckChange is a value that the fix of an issue sets. A nil New removes
+// the field.
+type FsckChange struct {
+	Position int         `json:"position"`
+	Id       int64       `json:"id"`
+	Field    string      `json:"field"`
+	Old      interface{} `json:"old"`
+	New      interface{} `json:"new"`
+}
+
+// FsckReport is the result of checking a users document.
+type FsckReport struct {
+	Users           int          `json:"users"`
+	UsersWithErrors int          `json:"users_with_errors"`
+	Warnings        int          `json:"warnings"`
+	Issues          []FsckIssue  `json:"issues"`
+	Changes         []FsckChange `json:"changes"`
+}
+
+// Failed tells whether the report has errors.
+func (r FsckReport) Failed() bool {
+	for _, issue := range r.Issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// fieldKinds are the json fields of User by the kind of value they hold.
+var fieldKinds = userFieldKinds()
+
+func userFieldKinds() (kinds map[string]reflect.Kind) {
+	kinds = map[string]reflect.Kind{}
+	userType := reflect.TypeOf(User{})
+	for idx := 0; idx < userType.NumField(); idx++ {
+		field := userType.Field(idx)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+
+		kind := field.Type.Kind()
+		if kind == reflect.Ptr {
+			kind = field.Type.Elem().Kind()
+		}
+		// The timestamps are written as RFC 3339 strings.
+		if kind == reflect.Struct {
+			kind = reflect.String
+		}
+		kinds[name] = kind
+	}
+
+	return kinds
+}
+
+// fsck holds the state of a check while it goes over the users.
+type fsck struct {
+	now    time.Time
+	report FsckReport
+	// broken is set when a user can't be written back, so no fix is offered.
+	broken bool
+	nextId int64
+	ids    map[int64]bool
+	emails map[string]int64
+}
+
+// Fsck checks a users document as written by manual edits: duplicate or
+// non positive ids, invalid or duplicate emails, dates that don't parse,
+// values of another type than the schema, unknown fields and activo values
+// that contradict the state of the user.
+//
+// The report lists in Changes the fix of every fixable issue, and fixed is
+// the document with them applied, nil when there is nothing to fix or when
+// some issue keeps the result from being a users document. The issues that
+// need a decision, as which user keeps a repeated email, are only reported.
+func Fsck(data []byte, now time.Time) (report FsckReport, fixed *Users) {
+	f := &fsck{now: now, ids: map[int64]bool{}, emails: map[string]int64{}}
+
+	var document map[string]json.RawMessage
+	err := json.Unmarshal(data, &document)
+	if err != nil {
+		f.issue(-1, 0, "", IssueInvalidDocument, SeverityError, false, "no es un documento JSON de usuarios: %v", err)
+		return f.report, nil
+	}
+
+	for _, key := range sortedKeys(document) {
+		if key != "users" {
+			f.issue(-1, 0, key, IssueUnknownField, SeverityError, true, "el campo %s no es parte del documento", key)
+			f.report.Changes = append(f.report.Changes, FsckChange{Position: -1, Field: key, Old: document[key]})
+		}
+	}
+
+	rawUsers, found := document["users"]
+	if !found {
+		f.issue(-1, 0, "users", IssueMissingField, SeverityError, false, "falta la lista users")
+		return f.report, nil
+	}
+
+	var entries []json.RawMessage
+	err = json.Unmarshal(rawUsers, &entries)
+	if err != nil {
+		f.issue(-1, 0, "users", IssueWrongType, SeverityError, false, "users debe ser una lista de usuarios")
+		return f.report, nil
+	}
+
+	records := make([]map[string]interface{}, len(entries))
+	for idx, entry := range entries {
+		decoder := json.NewDecoder(bytes.NewReader(entry))
+		decoder.UseNumber()
+		if decoder.Decode(&records[idx]) != nil || records[idx] == nil {
+			continue
+		}
+		converted, _ := convert(records[idx]["id"], reflect.Int64)
+		if id, ok := integer(converted); ok && id >= f.nextId {
+			f.nextId = id + 1
+		}
+	}
+	if f.nextId == 0 {
+		f.nextId = 1
+	}
+
+	for idx, record := range records {
+		f.checkUser(idx, record)
+	}
+
+	f.report.Users = len(entries)
+	users := map[int]bool{}
+	for _, issue := range f.report.Issues {
+		switch {
+		case issue.Severity == SeverityWarning:
+			f.report.Warnings++
+		case issue.Position >= 0:
+			users[issue.Position] = true
+		}
+	}
+	f.report.UsersWithErrors = len(users)
+
+	if f.broken || len(f.report.Changes) == 0 {
+		return f.report, nil
+	}
+
+	fixedData, err := json.Marshal(map[string]interface{}{"users": records})
+	if err != nil {
+		return f.report, nil
+	}
+	fixed = &Users{}
+	decoder := json.NewDecoder(bytes.NewReader(fixedData))
+	decoder.DisallowUnknownFields()
+	if decoder.Decode(fixed) != nil {
+		return f.report, nil
+	}
+
+	return f.report, fixed
+}
+
+// checkUser checks the user at position, fixing record in place.
+func (f *fsck) checkUser(position int, record map[string]interface{}) {
+	if record == nil {
+		f.issue(position, 0, "", IssueWrongType, SeverityError, false, "el usuario debe ser un objeto")
+		f.broken = true
+		return
+	}
+
+	id, _ := integer(record["id"])
+
+	for _, key := range sortedKeys(record) {
+		if _, known := fieldKinds[key]; !known {
+			f.issue(position, id, key, IssueUnknownField, SeverityError, true, "el campo %s no es parte del usuario", key)
+			f.change(position, id, record, key, nil)
+		}
+	}
+
+	// The checks go on without the mistyped fields, which can't be fixed.
+	mistyped := map[string]bool{}
+	for _, field := range sortedKeys(fieldKinds) {
+		value, found := record[field]
+		if !found || value == nil {
+			continue
+		}
+		converted, ok := convert(value, fieldKinds[field])
+		switch {
+		case !ok:
+			f.issue(position, id, field, IssueWrongType, SeverityError, false, "%s debe ser %s(recibido: %v)", field, kindNames[fieldKinds[field]], value)
+			mistyped[field] = true
+			f.broken = true
+		case converted != value:
+			f.issue(position, id, field, IssueWrongType, SeverityError, true, "%s debe ser %s(recibido: %q)", field, kindNames[fieldKinds[field]], fmt.Sprint(value))
+			f.change(position, id, record, field, converted)
+		}
+	}
+	id, _ = integer(record["id"])
+
+	if !mistyped["id"] {
+		f.checkId(position, id, record)
+	}
+	erased := f.checkDates(position, id, record, mistyped)
+	f.checkActivo(position, id, record, erased)
+	f.checkEmail(position, id, record, erased)
+
+	if erased {
+		return
+	}
+	valid := map[string]interface{}{}
+	for field, value := range record {
+		valid[field] = value
+	}
+	for field := range mistyped {
+		delete(valid, field)
+		if standIn, found := validStandIns[field]; found {
+			valid[field] = standIn
+		}
+	}
+	var user User
+	data, _ := json.Marshal(valid)
+	if json.Unmarshal(data, &user) == nil {
+		if err := ValidateUser(user); err != nil {
+			f.issue(position, id, "", IssueInvalidUser, SeverityError, false, "%v", err)
+		}
+	}
+}
+
+// validStandIns pass ValidateUser in place of the mistyped fields it checks,
+// already reported, so that it checks the rest.
+var validStandIns = map[string]interface{}{
+	"nombre":   "-",
+	"apellido": "-",
+	"email":    "-",
+	"altura":   json.Number("1"),
+}
+
+func (f *fsck) checkId(position int, id int64, record map[string]interface{}) {
+	switch {
+	case id <= 0:
+		f.issue(position, id, "id", IssueInvalidId, SeverityError, true, "el id debe ser mayor a cero")
+	case f.ids[id]:
+		f.issue(position, id, "id", IssueDuplicateId, SeverityError, true, "el id esta repetido")
+	default:
+		f.ids[id] = true
+		return
+	}
+
+	f.change(position, id, record, "id", json.Number(strconv.FormatInt(f.nextId, 10)))
+	f.ids[f.nextId] = true
+	f.nextId++
+}
+
+// checkDates checks the dates of the user, but the mistyped ones, and returns
+// whether it is erased.
+func (f *fsck) checkDates(position int, id int64, record map[string]interface{}, mistyped map[string]bool) (erased bool) {
+	creacion, creacionErr := ParseFecha(text(record, "fecha_de_creacion"))
+	if text(record, "fecha_de_creacion") != "" && creacionErr != nil {
+		f.issue(position, id, "fecha_de_creacion", IssueInvalidDate, SeverityError, false, "%v", creacionErr)
+	}
+
+	createdAt := text(record, "created_at")
+	for _, field := range []string{"created_at", "updated_at"} {
+		if mistyped[field] {
+			continue
+		}
+		value := text(record, field)
+		date, err := time.Parse(time.RFC3339Nano, value)
+		if err == nil && !date.IsZero() {
+			continue
+		}
+
+		var fix interface{}
+		switch {
+		case field == "updated_at" && createdAt != "":
+			fix = createdAt
+		case creacionErr == nil:
+			fix = creacion.Format(time.RFC3339)
+		}
+
+		if value == "" || err == nil {
+			f.issue(position, id, field, IssuePendingMigration, SeverityWarning, fix != nil, "falta %s, migrate lo deriva de fecha_de_creacion", field)
+		} else {
+			f.issue(position, id, field, IssueInvalidDate, SeverityError, fix != nil, "%s debe tener formato RFC 3339(recibido: %s)", field, value)
+		}
+		if fix != nil {
+			f.change(position, id, record, field, fix)
+			if field == "created_at" {
+				createdAt = fix.(string)
+			}
+		}
+	}
+
+	if value := text(record, "erased_at"); value != "" || mistyped["erased_at"] {
+		erased = true
+		if _, err := time.Parse(time.RFC3339Nano, value); err != nil && !mistyped["erased_at"] {
+			f.issue(position, id, "erased_at", IssueInvalidDate, SeverityError, false, "erased_at debe tener formato RFC 3339(recibido: %s)", value)
+		}
+	}
+
+	if mistyped["fecha_de_nacimiento"] {
+		return erased
+	}
+	if nacimiento := text(record, "fecha_de_nacimiento"); nacimiento != "" {
+		_, err := ParseFechaDeNacimiento(nacimiento, f.now)
+		if err == nil {
+			return erased
+		}
+		// dd/mm/yyyy is the format of fecha_de_creacion, the usual mistake.
+		date, legacyErr := ParseFecha(nacimiento)
+		fixable := legacyErr == nil && !date.After(f.now)
+		f.issue(position, id, "fecha_de_nacimiento", IssueInvalidDate, SeverityError, fixable, "%v", err)
+		if fixable {
+			f.change(position, id, record, "fecha_de_nacimiento", date.Format(FechaDeNacimientoLayout))
+		}
+	} else if edad, _ := integer(record["edad"]); edad > 0 && !erased {
+		f.issue(position, id, "fecha_de_nacimiento", IssuePendingMigration, SeverityWarning, true, "falta fecha_de_nacimiento, migrate la deriva de la edad")
+		f.change(position, id, record, "fecha_de_nacimiento", FechaDeNacimientoAproximada(edad, f.now))
+	}
+
+	return erased
+}
+
+func (f *fsck) checkActivo(position int, id int64, record map[string]interface{}, erased bool) {
+	activo, found := record["activo"]
+	switch {
+	case erased && activo != false:
+		f.issue(position, id, "activo", IssueInconsistentState, SeverityError, true, "el usuario esta borrado y activo no es false")
+		f.change(position, id, record, "activo", false)
+	case !found || activo == nil:
+		f.issue(position, id, "activo", IssueMissingField, SeverityError, false, "falta el campo activo")
+	}
+}
+
+func (f *fsck) checkEmail(position int, id int64, record map[string]interface{}, erased bool) {
+	email := text(record, "email")
+	if email == "" {
+		return
+	}
+
+	if !validEmail(email) {
+		trimmed := strings.TrimSpace(email)
+		fixable := validEmail(trimmed)
+		f.issue(position, id, "email", IssueInvalidEmail, SeverityError, fixable, "el email %q no es valido", email)
+		if !fixable {
+			return
+		}
+		f.change(position, id, record, "email", trimmed)
+		email = trimmed
+	}
+
+	// The tombstones have unique emails of their own.
+	if erased {
+		return
+	}
+	key := strings.ToLower(email)
+	if otherId, found := f.emails[key]; found {
+		f.issue(position, id, "email", IssueDuplicateEmail, SeverityError, false, "el email %s esta repetido, lo tiene el usuario %d", email, otherId)
+		return
+	}
+	f.emails[key] = id
+}
+
+func (f *fsck) issue(position int, id int64, field string, code string, severity string, fixable bool, format string, args ...interface{}) {
+	f.report.Issues = append(f.report.Issues, FsckIssue{
+		Position: position,
+		Id:       id,
+		Field:    field,
+		Code:     code,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+		Fixable:  fixable,
+	})
+}
+
+// change records the fix of field and applies it to record.
+func (f *fsck) change(position int, id int64, record map[string]interface{}, field string, value interface{}) {
+	f.report.Changes = append(f.report.Changes, FsckChange{Position: position, Id: id, Field: field, Old: record[field], New: value})
+	if value == nil {
+		delete(record, field)
+	} else {
+		record[field] = value
+	}
+}
+
+var kindNames = map[reflect.Kind]string{
+	reflect.Int64:   "un entero",
+	reflect.Float64: "un numero",
+	reflect.Bool:    "true o false",
+	reflect.String:  "un texto",
+}
+
+// convert returns value as the kind of its field. The values that only
+// changed of type in a manual edit, as "1.8" or "si", are converted, and ok
+// is false for the ones that can't be.
+func convert(value interface{}, kind reflect.Kind) (converted interface{}, ok bool) {
+	switch kind {
+	case reflect.Int64:
+		if _, ok = integer(value); ok {
+			return value, true
+		}
+		if number, err := strconv.ParseInt(strings.TrimSpace(fmt.Sprint(value)), 10, 64); err == nil {
+			return json.Number(strconv.FormatInt(number, 10)), true
+		}
+	case reflect.Float64:
+		if _, isNumber := value.(json.Number); isNumber {
+			return value, true
+		}
+		if number, err := strconv.ParseFloat(strings.TrimSpace(fmt.Sprint(value)), 64); err == nil {
+			return json.Number(strconv.FormatFloat(number, 'f', -1, 64)), true
+		}
+	case reflect.Bool:
+		if _, isBool := value.(bool); isBool {
+			return value, true
+		}
+		switch strings.ToLower(strings.TrimSpace(fmt.Sprint(value))) {
+		case "true", "si", "sí", "1":
+			return true, true
+		case "false", "no", "0":
+			return false, true
+		}
+	case reflect.String:
+		switch value.(type) {
+		case string:
+			return value, true
+		case json.Number:
+			return value.(json.Number).String(), true
+		}
+	}
+
+	return value, false
+}
+
+// integer returns value when it is a JSON integer.
+func integer(value interface{}) (number int64, ok bool) {
+	jsonNumber, isNumber := value.(json.Number)
+	if !isNumber {
+		return 0, false
+	}
+	number, err := jsonNumber.Int64()
+
+	return number, err == nil
+}
+
+// text returns the string value of field, empty when it is not a string.
+func text(record map[string]interface{}, field string) string {
+	value, _ := record[field].(string)
+	return value
+}
+
+// validEmail accepts a bare address, without name or spaces around.
+func validEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email
+}
+
+func sortedKeys(values interface{}) (keys []string) {
+	for _, key := range reflect.ValueOf(values).MapKeys() {
+		keys = append(keys, key.String())
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,186 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/logger"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/web"
+)
+
+const (
+	ImportCreated   = "created"
+	ImportUpdated   = "updated"
+	ImportUnchanged = "unchanged"
+)
+
+var (
+	ErrImportEmpty    = errors.New("el archivo no tiene filas para importar")
+	errImportRollback = errors.New("importacion revertida")
+)
+
+// ImportOptions control Import. By default the import is all-or-nothing:
+// nothing is saved if any row is invalid. SkipInvalid saves the valid rows
+// and Upsert updates the user with the same email instead of rejecting it.
+// Resume is for running again an import that may have been saved already:
+// the rows equal to the user with their email are accepted as unchanged.
+type ImportOptions struct {
+	SkipInvalid bool
+	Upsert      bool
+	Resume      bool
+}
+
+// ImportRow is a row read from the imported file. Err is set when the row
+// could not be parsed.
+type ImportRow struct {
+	Line int
+	User User
+	Err  error
+}
+
+// ImportedRow is a row saved by Import.
+type ImportedRow struct {
+	Line   int
+	ID     int64
+	Action string
+	User   User
+}
+
+// RowError is a row rejected by Import.
+type RowError struct {
+	Line int
+	Err  error
+}
+
+// ImportReport lists the accepted and the rejected rows. Applied tells
+// whether the accepted rows were saved.
+type ImportReport struct {
+	Total    int
+	Applied  bool
+	Accepted []ImportedRow
+	Errors   []RowError
+}
+
+// Import validates every row and saves the accepted ones in a single write,
+// assigning their ids as Create does. Rejected imports are not an error, the
+// report lists why and has Applied unset.
+func (s *service) Import(ctx context.Context, rows []ImportRow, options ImportOptions) (report ImportReport, err error) {
+	if len(rows) == 0 {
+		return report, ErrImportEmpty
+	}
+
+	err = s.repository.Transaction(ctx, func(usersInDatabase *Users) error {
+		report = ImportReport{Total: len(rows), Accepted: []ImportedRow{}, Errors: []RowError{}}
+
+		for _, row := range rows {
+			imported, err := s.importRow(ctx, usersInDatabase, row, options)
+			if err != nil {
+				report.Errors = append(report.Errors, RowError{Line: row.Line, Err: err})
+				continue
+			}
+			report.Accepted = append(report.Accepted, imported)
+		}
+
+		if (len(report.Errors) > 0 && !options.SkipInvalid) || len(report.Accepted) == 0 {
+			return errImportRollback
+		}
+
+		return nil
+	})
+	if errors.Is(err, errImportRollback) {
+		s.log(ctx).Debug("importacion rechazada", logger.F("rows", report.Total), logger.F("errors", len(report.Errors)))
+		return report, nil
+	}
+	if err != nil {
+		return report, err
+	}
+
+	report.Applied = true
+	s.log(ctx).Debug("importacion aplicada", logger.F("rows", report.Total), logger.F("accepted", len(report.Accepted)), logger.F("errors", len(report.Errors)))
+
+	return report, nil
+}
+
+func (s *service) importRow(ctx context.Context, usersInDatabase *Users, row ImportRow, options ImportOptions) (imported ImportedRow, err error) {
+	if row.Err != nil {
+		return imported, row.Err
+	}
+
+	err = ValidateUser(row.User)
+	if err != nil {
+		return imported, err
+	}
+
+	if options.Resume {
+		for _, registeredUser := range usersInDatabase.Users {
+			if registeredUser.Email == row.User.Email && s.sameImportedData(registeredUser, row.User) {
+				return ImportedRow{Line: row.Line, ID: registeredUser.Id, Action: ImportUnchanged, User: registeredUser}, nil
+			}
+		}
+	}
+
+	imported = ImportedRow{Line: row.Line, Action: ImportCreated}
+
+	if options.Upsert {
+		for _, registeredUser := range usersInDatabase.Users {
+			if registeredUser.Email == row.User.Email {
+				imported.Action = ImportUpdated
+				imported.User, err = s.prepareReplace(ctx, *usersInDatabase, registeredUser.Id, row.User)
+				if err != nil {
+					return imported, err
+				}
+
+				ptrUser, _ := GetUserById(registeredUser.Id, usersInDatabase)
+				*ptrUser = imported.User
+				imported.ID = imported.User.Id
+
+				return imported, nil
+			}
+		}
+	}
+
+	imported.User, err = s.prepareCreate(ctx, *usersInDatabase, row.User)
+	if err != nil {
+		if errors.Is(err, ErrEmailAlreadyExists) {
+			err = fmt.Errorf("%w(%s), use upsert para actualizarlo", err, row.User.Email)
+		}
+		return imported, err
+	}
+
+	usersInDatabase.Users = append(usersInDatabase.Users, imported.User)
+	imported.ID = imported.User.Id
+
+	return imported, nil
+}
+
+// sameImportedData tells whether importing user would leave registeredUser
+// as it is.
+func (s *service) sameImportedData(registeredUser User, user User) bool {
+	fechaDeNacimiento, err := s.resolveFechaDeNacimiento(user, s.withEdad(registeredUser))
+	if err != nil {
+		return false
+	}
+
+	return registeredUser.Nombre == user.Nombre && registeredUser.Apellido == user.Apellido &&
+		registeredUser.Altura == user.Altura && registeredUser.FechaDeNacimiento == fechaDeNacimiento
+}
+
+// ReadImportRows reads the users of a CSV or NDJSON file, mapping renames
+// the CSV columns to the json names of the User fields.
+func ReadImportRows(mediaType string, r io.Reader, mapping map[string]string) (rows []ImportRow, err error) {
+	newUser := func() interface{} {
+		return &User{}
+	}
+
+	err = web.DecodeRows(mediaType, r, mapping, newUser, func(line int, value interface{}, err error) {
+		row := ImportRow{Line: line, Err: err}
+		if user, ok := value.(*User); ok && err == nil {
+			row.User = *user
+		}
+		rows = append(rows, row)
+	})
+
+	return rows, err
+}
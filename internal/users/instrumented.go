@@ -0,0 +1,147 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/metrics"
+)
+
+// RepositoryMetrics are the metrics recorded by InstrumentRepository.
+type RepositoryMetrics struct {
+	Duration *metrics.HistogramVec
+	Errors   *metrics.CounterVec
+	Users    *metrics.GaugeVec
+}
+
+func NewRepositoryMetrics(registry *metrics.Registry) RepositoryMetrics {
+	return RepositoryMetrics{
+		Duration: registry.Histogram("users_repository_duration_seconds", "Duration of the operations of the users repository.", metrics.DurationBuckets, "operation"),
+		Errors:   registry.Counter("users_repository_errors_total", "Failed operations of the users repository.", "operation"),
+		Users:    registry.Gauge("users", "Users stored, erased ones included, as of the last operation of the repository."),
+	}
+}
+
+type instrumentedRepository struct {
+	next    Repository
+	metrics RepositoryMetrics
+}
+
+// InstrumentRepository returns a repository that records the duration and
+// errors of each operation of next and keeps the users gauge up to date.
+func InstrumentRepository(next Repository, m RepositoryMetrics) Repository {
+	return &instrumentedRepository{next: next, metrics: m}
+}
+
+// observe records an operation. A user not found is an answer of the
+// repository, not a failure.
+func (r *instrumentedRepository) observe(operation string, start time.Time, err error) {
+	r.metrics.Duration.Observe(time.Since(start).Seconds(), operation)
+	if err != nil && !errors.Is(err, ErrUserNotFound) {
+		r.metrics.Errors.Inc(operation)
+	}
+}
+
+func (r *instrumentedRepository) GetAll(ctx context.Context) (users *Users, err error) {
+	start := time.Now()
+	users, err = r.next.GetAll(ctx)
+	r.observe("get_all", start, err)
+	if err == nil {
+		r.metrics.Users.Set(float64(len(users.Users)))
+	}
+
+	return users, err
+}
+
+func (r *instrumentedRepository) Store(ctx context.Context, id int64, nombre string, apellido string, email string, edad int64, altura float64, activo bool, fecha_de_creacion string) (user User, err error) {
+	start := time.Now()
+	user, err = r.next.Store(ctx, id, nombre, apellido, email, edad, altura, activo, fecha_de_creacion)
+	r.observe("store", start, err)
+	if err == nil {
+		r.metrics.Users.Add(1)
+	}
+
+	return user, err
+}
+
+func (r *instrumentedRepository) FullUpdate(ctx context.Context, id int64, nombre string, apellido string, email string, edad int64, altura float64) (user User, err error) {
+	start := time.Now()
+	user, err = r.next.FullUpdate(ctx, id, nombre, apellido, email, edad, altura)
+	r.observe("full_update", start, err)
+
+	return user, err
+}
+
+func (r *instrumentedRepository) DeleteUserByID(ctx context.Context, id int64) (err error) {
+	start := time.Now()
+	err = r.next.DeleteUserByID(ctx, id)
+	r.observe("delete", start, err)
+	if err == nil {
+		r.metrics.Users.Add(-1)
+	}
+
+	return err
+}
+
+func (r *instrumentedRepository) UpdateUserLastName(ctx context.Context, id int64, apellido string) (user User, err error) {
+	start := time.Now()
+	user, err = r.next.UpdateUserLastName(ctx, id, apellido)
+	r.observe("update_last_name", start, err)
+
+	return user, err
+}
+
+func (r *instrumentedRepository) UpdateUserAge(ctx context.Context, id int64, edad int64) (user User, err error) {
+	start := time.Now()
+	user, err = r.next.UpdateUserAge(ctx, id, edad)
+	r.observe("update_age", start, err)
+
+	return user, err
+}
+
+func (r *instrumentedRepository) Insert(ctx context.Context, user User) (insertedUser User, err error) {
+	start := time.Now()
+	insertedUser, err = r.next.Insert(ctx, user)
+	r.observe("insert", start, err)
+	if err == nil {
+		r.metrics.Users.Add(1)
+	}
+
+	return insertedUser, err
+}
+
+func (r *instrumentedRepository) Update(ctx context.Context, user User) (updatedUser User, err error) {
+	start := time.Now()
+	updatedUser, err = r.next.Update(ctx, user)
+	r.observe("update", start, err)
+
+	return updatedUser, err
+}
+
+// Transaction counts the users left by apply, which is only known inside it.
+// The errors of apply, like the rollback of a dry run, are not failures of
+// the repository and are not counted as such.
+func (r *instrumentedRepository) Transaction(ctx context.Context, apply func(users *Users) error) (err error) {
+	start := time.Now()
+	count := -1
+	var applyErr error
+	err = r.next.Transaction(ctx, func(users *Users) error {
+		applyErr = apply(users)
+		if applyErr == nil {
+			count = len(users.Users)
+		}
+		return applyErr
+	})
+
+	repositoryErr := err
+	if applyErr != nil && err == applyErr {
+		repositoryErr = nil
+	}
+	r.observe("transaction", start, repositoryErr)
+	if err == nil && count >= 0 {
+		r.metrics.Users.Set(float64(count))
+	}
+
+	return err
+}
@@ -0,0 +1,74 @@
+package users
+
+import (
+	"time"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/store"
+)
+
+// MigrateTimestamps fills created_at/updated_at of the users saved before the
+// timestamps existed, parsing their legacy fecha_de_creacion. It returns the
+// ids whose fecha could not be parsed, those keep zero timestamps.
+func MigrateTimestamps(db store.Store) (migrated int, unparsed []int64, err error) {
+	var users Users
+	err = db.Read(&users)
+	if err != nil {
+		return migrated, unparsed, err
+	}
+
+	for idx := range users.Users {
+		user := &users.Users[idx]
+		if !user.CreatedAt.IsZero() {
+			continue
+		}
+
+		createdAt, err := ParseFecha(user.FechaDeCreacion)
+		if err != nil {
+			unparsed = append(unparsed, user.Id)
+			continue
+		}
+
+		user.CreatedAt = createdAt
+		if user.UpdatedAt.IsZero() {
+			user.UpdatedAt = createdAt
+		}
+		migrated++
+	}
+
+	if migrated == 0 {
+		return migrated, unparsed, nil
+	}
+
+	err = db.Write(&users)
+
+	return migrated, unparsed, err
+}
+
+// MigrateBirthDates derives an approximate fecha_de_nacimiento for the users
+// saved with only an edad. The edad is taken as the one the user has at now,
+// so the users keep the edad they had before the migration.
+func MigrateBirthDates(db store.Store, now time.Time) (migrated int, err error) {
+	var users Users
+	err = db.Read(&users)
+	if err != nil {
+		return migrated, err
+	}
+
+	for idx := range users.Users {
+		user := &users.Users[idx]
+		if user.FechaDeNacimiento != "" || user.Edad <= 0 {
+			continue
+		}
+
+		user.FechaDeNacimiento = FechaDeNacimientoAproximada(user.Edad, now)
+		migrated++
+	}
+
+	if migrated == 0 {
+		return migrated, nil
+	}
+
+	err = db.Write(&users)
+
+	return migrated, err
+}
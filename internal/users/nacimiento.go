@@ -0,0 +1,58 @@
+package users
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FechaDeNacimientoLayout is the yyyy-mm-dd format of fecha_de_nacimiento.
+const FechaDeNacimientoLayout = "2006-01-02"
+
+var (
+	ErrBirthDateRequired = errors.New("se requiere la fecha_de_nacimiento o la edad del usuario")
+	ErrInvalidBirthDate  = errors.New("la fecha_de_nacimiento no es valida")
+)
+
+// ParseFechaDeNacimiento parses a yyyy-mm-dd birth date, rejecting dates after now.
+func ParseFechaDeNacimiento(fecha string, now time.Time) (date time.Time, err error) {
+	date, err = time.Parse(FechaDeNacimientoLayout, strings.TrimSpace(fecha))
+	if err != nil {
+		return date, fmt.Errorf("%w, debe tener formato aaaa-mm-dd(recibido: %s)", ErrInvalidBirthDate, fecha)
+	}
+
+	if date.After(now) {
+		return date, fmt.Errorf("%w, no puede ser posterior a hoy(recibido: %s)", ErrInvalidBirthDate, fecha)
+	}
+
+	return date, nil
+}
+
+// EdadAt returns the age in whole years of someone born on birthDate at now.
+func EdadAt(birthDate time.Time, now time.Time) int64 {
+	edad := int64(now.Year() - birthDate.Year())
+	if now.Month() < birthDate.Month() || (now.Month() == birthDate.Month() && now.Day() < birthDate.Day()) {
+		edad--
+	}
+
+	return edad
+}
+
+// FechaDeNacimientoAproximada derives a birth date for clients that only send
+// the age. Half a year is subtracted so the computed edad matches the given
+// one for the next six months, instead of changing on the following day.
+func FechaDeNacimientoAproximada(edad int64, reference time.Time) string {
+	return reference.AddDate(-int(edad), -6, 0).Format(FechaDeNacimientoLayout)
+}
+
+// BirthDateRange returns the first and last birth dates (both inclusive) of
+// the people that have the given age at now.
+func BirthDateRange(edad int64, now time.Time) (from time.Time, to time.Time) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	to = today.AddDate(-int(edad), 0, 0)
+	from = today.AddDate(-int(edad)-1, 0, 1)
+
+	return from, to
+}
@@ -1,75 +1,104 @@
 package users
 
 import (
+	"context"
 	"errors"
+	"sync"
+	"time"
 
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/logger"
 	"github.com/EdigiraldoML/go-web-arquitecture/pkg/store"
 )
 
+var (
+	ErrUserNotFound       = errors.New("el usuario no fue encontrado")
+	ErrEmailAlreadyExists = errors.New("ya existe un usuario registrado con el email")
+)
+
 type Users struct {
-	Users []User `json:"users"`
+	Users []User `json:"users" xml:"user" yaml:"users"`
 }
 
 type User struct {
-	Id              int64   `json:"id"`
-	Nombre          string  `json:"nombre" binding:"required"`
-	Apellido        string  `json:"apellido" binding:"required"`
-	Email           string  `json:"email" binding:"required"`
-	Edad            int64   `json:"edad" binding:"required"`
-	Altura          float64 `json:"altura" binding:"required"`
-	Activo          bool    `json:"activo" binding:"required"`
-	FechaDeCreacion string  `json:"fecha_de_creacion" binding:"required"`
+	Id       int64  `json:"id" xml:"id" yaml:"id"`
+	Nombre   string `json:"nombre" xml:"nombre" yaml:"nombre" binding:"required"`
+	Apellido string `json:"apellido" xml:"apellido" yaml:"apellido" binding:"required"`
+	Email    string `json:"email" xml:"email" yaml:"email" binding:"required"`
+	Edad     int64  `json:"edad" xml:"edad" yaml:"edad"`
+	// FechaDeNacimiento is the source of Edad, which the service computes on every read.
+	FechaDeNacimiento string    `json:"fecha_de_nacimiento" xml:"fecha_de_nacimiento" yaml:"fecha_de_nacimiento"`
+	Altura            float64   `json:"altura" xml:"altura" yaml:"altura" binding:"required"`
+	Activo            bool      `json:"activo" xml:"activo" yaml:"activo" binding:"required"`
+	FechaDeCreacion   string    `json:"fecha_de_creacion" xml:"fecha_de_creacion" yaml:"fecha_de_creacion"`
+	CreatedAt         time.Time `json:"created_at" xml:"created_at" yaml:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at" xml:"updated_at" yaml:"updated_at"`
+	// ErasedAt is set when the personal data of the user was erased.
+	ErasedAt *time.Time `json:"erased_at,omitempty" xml:"erased_at,omitempty" yaml:"erased_at,omitempty"`
 }
 
 type Repository interface {
-	GetAll() (users *Users, err error)
-	Store(id int64, nombre string, apellido string, email string, edad int64, altura float64, activo bool, fecha_de_creacion string) (user User, err error)
-	FullUpdate(id int64, nombre string, apellido string, email string, edad int64, altura float64) (user User, err error)
-	DeleteUserByID(id int64) (err error)
-	UpdateUserLastName(id int64, apellido string) (user User, err error)
-	UpdateUserAge(id int64, edad int64) (user User, err error)
+	GetAll(ctx context.Context) (users *Users, err error)
+	Store(ctx context.Context, id int64, nombre string, apellido string, email string, edad int64, altura float64, activo bool, fecha_de_creacion string) (user User, err error)
+	FullUpdate(ctx context.Context, id int64, nombre string, apellido string, email string, edad int64, altura float64) (user User, err error)
+	DeleteUserByID(ctx context.Context, id int64) (err error)
+	UpdateUserLastName(ctx context.Context, id int64, apellido string) (user User, err error)
+	UpdateUserAge(ctx context.Context, id int64, edad int64) (user User, err error)
+	Insert(ctx context.Context, user User) (insertedUser User, err error)
+	Update(ctx context.Context, user User) (updatedUser User, err error)
+	Transaction(ctx context.Context, apply func(users *Users) error) (err error)
 }
 
+// repository keeps the users in a single document. mu serializes its
+// read-modify-writes, so that two concurrent writes don't erase each other's
+// changes.
 type repository struct {
-	db store.Store
+	db     store.Store
+	logger *logger.Logger
+	mu     sync.Mutex
 }
 
-func CreateRepository(db store.Store) Repository {
+func CreateRepository(db store.Store, log *logger.Logger) Repository {
 	newRepository := &repository{
-		db: db,
+		db:     db,
+		logger: log.With(logger.F("component", "users_repository")),
 	}
 
 	return newRepository
 }
 
-func (r *repository) GetAll() (users *Users, err error) {
+func (r *repository) GetAll(ctx context.Context) (users *Users, err error) {
 
 	users = &Users{}
 
-	err = r.db.Read(users)
+	err = store.WithContext(r.db).ReadContext(ctx, users)
 
 	return users, err
 }
 
-func (r *repository) Store(id int64, nombre string, apellido string, email string, edad int64, altura float64, activo bool, fecha_de_creacion string) (user User, err error) {
+func (r *repository) Store(ctx context.Context, id int64, nombre string, apellido string, email string, edad int64, altura float64, activo bool, fecha_de_creacion string) (user User, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
 	var usuarios Users
-	err = r.db.Read(&usuarios)
+	err = store.WithContext(r.db).ReadContext(ctx, &usuarios)
 	if err != nil {
 		return user, err
 	}
 
-	user = User{id, nombre, apellido, email, edad, altura, activo, fecha_de_creacion}
+	user = User{Id: id, Nombre: nombre, Apellido: apellido, Email: email, Edad: edad, Altura: altura, Activo: activo, FechaDeCreacion: fecha_de_creacion}
 
 	usuarios.Users = append(usuarios.Users, user)
 
-	err = r.db.Write(&usuarios)
+	err = r.write(ctx, &usuarios)
 
 	return user, err
 }
 
-func (r *repository) FullUpdate(id int64, nombre string, apellido string, email string, edad int64, altura float64) (user User, err error) {
-	users, err := r.GetAll()
+func (r *repository) FullUpdate(ctx context.Context, id int64, nombre string, apellido string, email string, edad int64, altura float64) (user User, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	users, err := r.GetAll(ctx)
 	if err != nil {
 		return user, err
 	}
@@ -87,13 +116,16 @@ func (r *repository) FullUpdate(id int64, nombre string, apellido string, email
 
 	user = *userToUpdate
 
-	err = r.db.Write(users)
+	err = r.write(ctx, users)
 
 	return user, err
 }
 
-func (r *repository) DeleteUserByID(id int64) (err error) {
-	users, err := r.GetAll()
+func (r *repository) DeleteUserByID(ctx context.Context, id int64) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	users, err := r.GetAll(ctx)
 	if err != nil {
 		return err
 	}
@@ -106,13 +138,16 @@ func (r *repository) DeleteUserByID(id int64) (err error) {
 	users.Users[userIndexToDelete].Activo = false
 	users.Users = append(users.Users[:userIndexToDelete], users.Users[userIndexToDelete+1:]...)
 
-	err = r.db.Write(users)
+	err = r.write(ctx, users)
 
 	return err
 }
 
-func (r *repository) UpdateUserLastName(id int64, apellido string) (user User, err error) {
-	users, err := r.GetAll()
+func (r *repository) UpdateUserLastName(ctx context.Context, id int64, apellido string) (user User, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	users, err := r.GetAll(ctx)
 	if err != nil {
 		return user, err
 	}
@@ -125,13 +160,16 @@ func (r *repository) UpdateUserLastName(id int64, apellido string) (user User, e
 	ptrUser.Apellido = apellido
 	user = *ptrUser
 
-	err = r.db.Write(users)
+	err = r.write(ctx, users)
 
 	return user, err
 }
 
-func (r *repository) UpdateUserAge(id int64, edad int64) (user User, err error) {
-	users, err := r.GetAll()
+func (r *repository) UpdateUserAge(ctx context.Context, id int64, edad int64) (user User, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	users, err := r.GetAll(ctx)
 	if err != nil {
 		return user, err
 	}
@@ -144,11 +182,56 @@ func (r *repository) UpdateUserAge(id int64, edad int64) (user User, err error)
 	ptrUser.Edad = edad
 	user = *ptrUser
 
-	err = r.db.Write(users)
+	err = r.write(ctx, users)
 
 	return user, err
 }
 
+// Insert appends the user as it is, the caller assigns its id and timestamps.
+func (r *repository) Insert(ctx context.Context, user User) (insertedUser User, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	users, err := r.GetAll(ctx)
+	if err != nil {
+		return insertedUser, err
+	}
+
+	users.Users = append(users.Users, user)
+
+	err = r.write(ctx, users)
+	if err != nil {
+		return insertedUser, err
+	}
+
+	return user, nil
+}
+
+// Update replaces every field of the user with the same id in a single write.
+func (r *repository) Update(ctx context.Context, user User) (updatedUser User, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	users, err := r.GetAll(ctx)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	ptrUser, err := GetUserById(user.Id, users)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	*ptrUser = user
+
+	err = r.write(ctx, users)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	return user, nil
+}
+
 func GetUserById(id int64, users *Users) (user *User, err error) {
 	for idx, user := range users.Users {
 		if user.Id == id {
@@ -156,7 +239,7 @@ func GetUserById(id int64, users *Users) (user *User, err error) {
 		}
 	}
 
-	err = errors.New("el usuario no fue encontrado")
+	err = ErrUserNotFound
 
 	return user, err
 }
@@ -168,7 +251,50 @@ func GetUserIndexById(id int64, users *Users) (index int64, err error) {
 		}
 	}
 
-	err = errors.New("el usuario no fue encontrado")
+	err = ErrUserNotFound
 
 	return index, err
 }
+
+// Transaction reads the users once, lets apply change them in memory and
+// writes them back in a single write. Nothing is written if apply fails, and
+// no other write of the repository runs until it ends, so apply must not
+// call the repository.
+func (r *repository) Transaction(ctx context.Context, apply func(users *Users) error) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	users, err := r.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = apply(users)
+	if err != nil {
+		return err
+	}
+
+	return r.write(ctx, users)
+}
+
+// write saves the users, logging how many were written.
+func (r *repository) write(ctx context.Context, users *Users) (err error) {
+	err = store.WithContext(r.db).WriteContext(ctx, users)
+	if err != nil {
+		r.log(ctx).Error("no se pudieron guardar los usuarios", logger.F("users", len(users.Users)), logger.Err(err))
+		return err
+	}
+
+	r.log(ctx).Debug("usuarios guardados", logger.F("users", len(users.Users)))
+
+	return nil
+}
+
+// log returns the logger of the request in ctx, or the one of the repository.
+func (r *repository) log(ctx context.Context) *logger.Logger {
+	if requestLogger := logger.FromContext(ctx, nil); requestLogger != nil {
+		return requestLogger.With(logger.F("component", "users_repository"))
+	}
+
+	return r.logger
+}
@@ -1,7 +1,17 @@
 package users
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/logger"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/metrics"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/store"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -33,7 +43,7 @@ func TestGetAll(t *testing.T) {
 		db: db,
 	}
 
-	users, err := repo.GetAll()
+	users, err := repo.GetAll(context.Background())
 	assert.Nil(t, err)
 	assert.Equal(t, expectedUsers, users)
 
@@ -74,10 +84,87 @@ func TestUpdateUserLastName(t *testing.T) {
 		db: db,
 	}
 
-	user, err := repo.UpdateUserLastName(idUserToUpdateLastName, "user last name after update")
+	user, err := repo.UpdateUserLastName(context.Background(), idUserToUpdateLastName, "user last name after update")
 	assert.Nil(t, err)
 	assert.Equal(t, expectedUser, user)
 
 	usedDb := repo.db.(*myDbUpdateLastName)
 	assert.True(t, usedDb.ReadCalled)
 }
+
+func TestInstrumentRepository(t *testing.T) {
+	registry := metrics.NewRegistry()
+	repo := InstrumentRepository(CreateRepository(&myDbGetAll{}, logger.Nop()), NewRepositoryMetrics(registry))
+
+	_, err := repo.GetAll(context.Background())
+	assert.Nil(t, err)
+
+	// Testea que un usuario no encontrado no cuente como error
+	err = repo.DeleteUserByID(context.Background(), 7)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	// Testea que el error de apply no cuente como error del repositorio
+	err = repo.Transaction(context.Background(), func(users *Users) error {
+		return ErrBatchRolledBack
+	})
+	assert.ErrorIs(t, err, ErrBatchRolledBack)
+
+	var out bytes.Buffer
+	err = registry.Write(&out)
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), "\nusers 2\n")
+	assert.Contains(t, out.String(), `users_repository_duration_seconds_count{operation="transaction"} 1`)
+	assert.NotContains(t, out.String(), "users_repository_errors_total{")
+}
+
+// slowStore pauses after each read, so that concurrent writes overlap.
+type slowStore struct {
+	store.Store
+}
+
+func (db slowStore) Read(data interface{}) (err error) {
+	err = db.Store.Read(data)
+	time.Sleep(time.Millisecond)
+
+	return err
+}
+
+func TestConcurrentWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	db := slowStore{store.NewStorage(store.FileType, path, logger.Nop())}
+	assert.Nil(t, db.Write(&Users{Users: []User{{Id: 1, Nombre: "user1", Apellido: "last1", Email: "user1@email.com", Edad: 0, FechaDeNacimiento: "1990-01-01", Altura: 1.7, Activo: true}}}))
+
+	repo := CreateRepository(db, logger.Nop())
+	service := CreateService(repo, logger.Nop())
+
+	// Testea que las altas y transacciones concurrentes no pisen los cambios de las otras
+	const writers = 20
+	var wg sync.WaitGroup
+	for idx := 0; idx < writers; idx++ {
+		wg.Add(2)
+		go func(idx int) {
+			defer wg.Done()
+			_, err := service.Create(context.Background(), User{Nombre: "nuevo", Apellido: "usuario", Email: fmt.Sprintf("nuevo%d@email.com", idx), FechaDeNacimiento: "1990-01-01", Altura: 1.6})
+			assert.Nil(t, err)
+		}(idx)
+		go func() {
+			defer wg.Done()
+			err := repo.Transaction(context.Background(), func(users *Users) error {
+				users.Users[0].Altura += 0.01
+				return nil
+			})
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+
+	users, err := repo.GetAll(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, users.Users, writers+1)
+	ids := map[int64]bool{}
+	for _, user := range users.Users {
+		ids[user.Id] = true
+	}
+	assert.Len(t, ids, writers+1)
+	assert.InDelta(t, 1.7+writers*0.01, users.Users[0].Altura, 0.0001)
+}
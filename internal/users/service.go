@@ -1,125 +1,635 @@
 package users
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strconv"
+	"time"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/logger"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/store"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/web"
 
 	"github.com/gin-gonic/gin"
 )
 
 type Service interface {
-	GetAll() (users *Users, err error)
-	Store(id int64, nombre string, apellido string, email string, edad int64, altura float64, activo bool, fecha_de_creacion string) (user User, err error)
+	GetAll(ctx context.Context) (users *Users, err error)
+	Store(ctx context.Context, id int64, nombre string, apellido string, email string, edad int64, altura float64, activo bool, fecha_de_creacion string) (user User, err error)
 	FilterByUrlParams(c *gin.Context) (filteredUsers Users, err error)
-	GetUserByID(id int64) (user User, err error)
+	Filter(ctx context.Context, filter Filter) (filteredUsers Users, err error)
+	Export(ctx context.Context, filter Filter, visit func(user User) error) (err error)
+	GetUserByID(ctx context.Context, id int64) (user User, err error)
 	NewUser(c *gin.Context) (user User, err error)
-	FullUpdate(id int64, nombre string, apellido string, email string, edad int64, altura float64) (user User, err error)
-	DeleteUserByID(id int64) (err error)
-	UpdateUserLastName(id int64, apellido string) (user User, err error)
-	UpdateUserAge(id int64, edad int64) (user User, err error)
+	Create(ctx context.Context, user User) (createdUser User, err error)
+	FullUpdate(ctx context.Context, id int64, nombre string, apellido string, email string, edad int64, altura float64) (user User, err error)
+	Replace(ctx context.Context, id int64, user User) (replacedUser User, err error)
+	DeleteUserByID(ctx context.Context, id int64) (err error)
+	UpdateUserLastName(ctx context.Context, id int64, apellido string) (user User, err error)
+	UpdateUserAge(ctx context.Context, id int64, edad int64) (user User, err error)
+	UpdateUserBirthDate(ctx context.Context, id int64, fechaDeNacimiento string) (user User, err error)
+	Patch(ctx context.Context, id int64, patch PatchFunc) (patchedUser User, err error)
+	Batch(ctx context.Context, operations []BatchOperation, atomic bool) (results []BatchResult, err error)
+	UpdateWhere(ctx context.Context, filter Filter, patch PatchFunc, options BulkOptions) (changes []BulkChange, err error)
+	DeleteWhere(ctx context.Context, filter Filter, options BulkOptions) (changes []BulkChange, err error)
+	Import(ctx context.Context, rows []ImportRow, options ImportOptions) (report ImportReport, err error)
+	Erase(ctx context.Context, id int64) (original User, tombstone User, err error)
+}
+
+var (
+	ErrInvalidUser    = errors.New("el usuario no es valido")
+	ErrImmutableField = errors.New("el campo no puede ser modificado")
+)
+
+// PatchFunc returns the user with the changes of a patch applied.
+type PatchFunc func(user User) (patchedUser User, err error)
+
+// Filter selects the users whose Params match the values in Searched and,
+// when set, that were created inside the (exclusive) date range.
+type Filter struct {
+	Params        []string
+	Searched      User
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
 }
 
 type service struct {
 	repository Repository
+	clock      Clock
+	logger     *logger.Logger
+}
+
+func CreateService(r Repository, log *logger.Logger) Service {
+	return CreateServiceWithClock(r, SystemClock, log)
 }
 
-func CreateService(r Repository) Service {
+func CreateServiceWithClock(r Repository, clock Clock, log *logger.Logger) Service {
 	newService := &service{
 		repository: r,
+		clock:      clock,
+		logger:     log.With(logger.F("component", "users")),
 	}
 
 	return newService
 }
 
-func (s *service) GetAll() (users *Users, err error) {
-	return s.repository.GetAll()
+func (s *service) GetAll(ctx context.Context) (users *Users, err error) {
+	users, err = s.repository.GetAll(ctx)
+	if err != nil {
+		return users, err
+	}
+
+	for idx := range users.Users {
+		users.Users[idx] = s.withEdad(users.Users[idx])
+	}
+
+	return users, nil
 }
 
-func (s *service) Store(id int64, nombre string, apellido string, email string, edad int64, altura float64, activo bool, fecha_de_creacion string) (user User, err error) {
-	user, err = s.repository.Store(id, nombre, apellido, email, edad, altura, activo, fecha_de_creacion)
+func (s *service) Store(ctx context.Context, id int64, nombre string, apellido string, email string, edad int64, altura float64, activo bool, fecha_de_creacion string) (user User, err error) {
+	user, err = s.repository.Store(ctx, id, nombre, apellido, email, edad, altura, activo, fecha_de_creacion)
 
 	return user, err
 }
 
 func (s *service) FilterByUrlParams(c *gin.Context) (filteredUsers Users, err error) {
+	ctx := c.Request.Context()
+	filter, err := FilterFromUrlParams(c)
+	if err != nil {
+		return filteredUsers, err
+	}
+
+	filteredUsers, err = s.Filter(ctx, filter)
+	if err == nil {
+		s.log(ctx).Debug("usuarios filtrados", logger.F("params", filter.Params), logger.F("users", len(filteredUsers.Users)))
+	}
+
+	return filteredUsers, err
+}
 
+// log returns the logger of the request in ctx, which carries its id, and
+// the logger of the service when there is none.
+func (s *service) log(ctx context.Context) *logger.Logger {
+	if requestLogger := logger.FromContext(ctx, nil); requestLogger != nil {
+		return requestLogger.With(logger.F("component", "users"))
+	}
+
+	return s.logger
+}
+
+// FilterFromUrlParams builds the filter of the query params understood by
+// FilterByUrlParams.
+func FilterFromUrlParams(c *gin.Context) (filter Filter, err error) {
 	availableParams := CheckAvailableParamsFromGinContext(c)
 	searchedUser, err := CreateSearchedUser(availableParams, c)
 	if err != nil {
-		fmt.Println(err)
+		return filter, err
+	}
+
+	filter = Filter{Params: availableParams, Searched: searchedUser}
+	if value := c.Query("created_after"); value != "" {
+		filter.CreatedAfter, err = ParseDateParam(value)
+		if err != nil {
+			return filter, err
+		}
 	}
-	fmt.Println(availableParams)
-	users, err := s.repository.GetAll()
+	if value := c.Query("created_before"); value != "" {
+		filter.CreatedBefore, err = ParseDateParam(value)
+		if err != nil {
+			return filter, err
+		}
+	}
+
+	return filter, nil
+}
+
+// IsEmpty tells whether the filter matches every user.
+func (f Filter) IsEmpty() bool {
+	return len(f.Params) == 0 && f.CreatedAfter.IsZero() && f.CreatedBefore.IsZero()
+}
+
+func (s *service) Filter(ctx context.Context, filter Filter) (filteredUsers Users, err error) {
+	users, err := s.GetAll(ctx)
 	if err != nil {
 		return filteredUsers, err
 	}
 
-	filteredUsers = GetUsersWithGivenParams(availableParams, *users, searchedUser)
+	filteredUsers.Users = s.filterUsers(*users, filter)
+
 	return filteredUsers, err
 }
 
-func (s *service) GetUserByID(id int64) (user User, err error) {
+// Export calls visit with each user matching the filter, in order, and stops
+// at the first error it returns.
+func (s *service) Export(ctx context.Context, filter Filter, visit func(user User) error) (err error) {
+	users, err := s.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	// A client that leaves stops the export.
+	for _, user := range s.filterUsers(*users, filter) {
+		err = store.CheckContext(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = visit(user)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// filterUsers returns the users matching the filter, with their edad computed.
+func (s *service) filterUsers(users Users, filter Filter) []User {
+	for idx := range users.Users {
+		users.Users[idx] = s.withEdad(users.Users[idx])
+	}
+
+	// The edad changes with time, so it is searched as the range of birth
+	// dates that give that edad today.
+	params, filterByEdad := []string{}, false
+	for _, param := range filter.Params {
+		if param == "edad" {
+			filterByEdad = true
+			continue
+		}
+		params = append(params, param)
+	}
+
+	filteredUsers := GetUsersWithGivenParams(params, users, filter.Searched)
+	filteredUsers.Users = GetUsersCreatedBetween(filteredUsers.Users, filter.CreatedAfter, filter.CreatedBefore)
+	if filterByEdad {
+		from, to := BirthDateRange(filter.Searched.Edad, s.clock.Now())
+		filteredUsers.Users = GetUsersBornBetween(filteredUsers.Users, from, to, filter.Searched.Edad)
+	}
+
+	return filteredUsers.Users
+}
+
+func (s *service) GetUserByID(ctx context.Context, id int64) (user User, err error) {
 
-	users, err := s.repository.GetAll()
+	users, err := s.repository.GetAll(ctx)
 	if err != nil {
 		return user, err
 	}
 
 	for _, user := range users.Users {
 		if user.Id == id {
-			return user, err
+			return s.withEdad(user), err
 		}
 	}
 
+	err = ErrUserNotFound
+
 	return user, err
 }
 
 func (s *service) NewUser(c *gin.Context) (user User, err error) {
-	err = c.ShouldBindJSON(&user)
+	err = c.ShouldBindBodyWith(&user, web.BodyBinding(c.ContentType()))
 	if err != nil {
 		return
 	}
 
-	usersInDatabase, err := s.repository.GetAll()
+	return s.Create(c.Request.Context(), user)
+}
+
+// Create adds the user in a transaction, so that the email check and the id
+// assigned hold against the concurrent writes.
+func (s *service) Create(ctx context.Context, user User) (createdUser User, err error) {
+	err = s.repository.Transaction(ctx, func(usersInDatabase *Users) error {
+		createdUser, err = s.prepareCreate(ctx, *usersInDatabase, user)
+		if err != nil {
+			return err
+		}
+
+		usersInDatabase.Users = append(usersInDatabase.Users, createdUser)
+
+		return nil
+	})
 	if err != nil {
-		return user, err
+		return User{}, err
 	}
 
-	lastRegisteredUserindex := len(usersInDatabase.Users) - 1
-	lastRegisteredUser := usersInDatabase.Users[lastRegisteredUserindex]
+	s.log(ctx).Debug("usuario creado", logger.F("user_id", createdUser.Id))
+
+	return createdUser, nil
+}
+
+// legacyUsersKey marks the contexts set by AllowLegacyUsers.
+type legacyUsersKey struct{}
+
+// AllowLegacyUsers returns ctx for the creates and replaces of version 1
+// clients, which could register an email that another user already has and
+// leave out the edad.
+func AllowLegacyUsers(ctx context.Context) context.Context {
+	return context.WithValue(ctx, legacyUsersKey{}, true)
+}
+
+func legacyUsersAllowed(ctx context.Context) bool {
+	allowed, _ := ctx.Value(legacyUsersKey{}).(bool)
+	return allowed
+}
+
+// prepareCreate checks the user can be added to usersInDatabase and assigns
+// the fields set by the server.
+func (s *service) prepareCreate(ctx context.Context, usersInDatabase Users, user User) (createdUser User, err error) {
+	for _, registeredUser := range usersInDatabase.Users {
+		if registeredUser.Email == user.Email && !legacyUsersAllowed(ctx) {
+			return createdUser, ErrEmailAlreadyExists
+		}
+	}
 
 	// Assign a new id
-	user.Id = lastRegisteredUser.Id + 1
+	user.Id = 1
+	if len(usersInDatabase.Users) > 0 {
+		lastRegisteredUser := usersInDatabase.Users[len(usersInDatabase.Users)-1]
+		user.Id = lastRegisteredUser.Id + 1
+	}
 	// User active
 	user.Activo = true
+	user.ErasedAt = nil
+
+	// Timestamps are assigned by the server, fecha_de_creacion is kept for version 1
+	now := s.clock.Now()
+	user.FechaDeNacimiento, err = s.resolveFechaDeNacimiento(user, User{})
+	if errors.Is(err, ErrBirthDateRequired) && legacyUsersAllowed(ctx) {
+		// Version 1 clients created users without edad, they keep edad 0.
+		err = nil
+	}
+	if err != nil {
+		return createdUser, err
+	}
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	user.FechaDeCreacion = FormatFecha(now)
 
-	user, err = s.repository.Store(user.Id, user.Nombre, user.Apellido, user.Email, user.Edad, user.Altura, user.Activo, user.FechaDeCreacion)
+	return s.withEdad(user), nil
+}
 
-	return user, err
+func (s *service) FullUpdate(ctx context.Context, id int64, nombre string, apellido string, email string, edad int64, altura float64) (user User, err error) {
+	user = User{Nombre: nombre, Apellido: apellido, Email: email, Edad: edad, Altura: altura}
+
+	return s.Replace(ctx, id, user)
 }
 
-func (s *service) FullUpdate(id int64, nombre string, apellido string, email string, edad int64, altura float64) (user User, err error) {
-	user, err = s.repository.FullUpdate(id, nombre, apellido, email, edad, altura)
+// Replace sets nombre, apellido, email, altura and fecha_de_nacimiento (or
+// edad) of the user with the given id. The rest of the fields are kept.
+func (s *service) Replace(ctx context.Context, id int64, user User) (replacedUser User, err error) {
+	err = s.repository.Transaction(ctx, func(usersInDatabase *Users) error {
+		replacedUser, err = s.prepareReplace(ctx, *usersInDatabase, id, user)
+		if err != nil {
+			return err
+		}
 
-	return user, err
+		ptrUser, _ := GetUserById(id, usersInDatabase)
+		*ptrUser = replacedUser
+
+		return nil
+	})
+	if err != nil {
+		return replacedUser, err
+	}
+
+	s.log(ctx).Debug("usuario reemplazado", logger.F("user_id", id))
+
+	return replacedUser, nil
 }
 
-func (s *service) DeleteUserByID(id int64) (err error) {
-	err = s.repository.DeleteUserByID(id)
+// prepareReplace returns the user with the given id of usersInDatabase with
+// the values of user applied.
+func (s *service) prepareReplace(ctx context.Context, usersInDatabase Users, id int64, user User) (replacedUser User, err error) {
+	for _, registeredUser := range usersInDatabase.Users {
+		if registeredUser.Email == user.Email && registeredUser.Id != id && !legacyUsersAllowed(ctx) {
+			return replacedUser, ErrEmailAlreadyExists
+		}
+	}
+
+	ptrUser, err := GetUserById(id, &usersInDatabase)
+	if err != nil {
+		return replacedUser, err
+	}
+
+	err = CheckNotErased(*ptrUser)
+	if err != nil {
+		return replacedUser, err
+	}
+
+	stored := s.withEdad(*ptrUser)
+
+	replacedUser = stored
+	replacedUser.Nombre = user.Nombre
+	replacedUser.Apellido = user.Apellido
+	replacedUser.Email = user.Email
+	replacedUser.Edad = user.Edad
+	replacedUser.Altura = user.Altura
+	replacedUser.FechaDeNacimiento = user.FechaDeNacimiento
+	replacedUser.FechaDeNacimiento, err = s.resolveFechaDeNacimiento(replacedUser, stored)
+	if err != nil {
+		return stored, err
+	}
+	replacedUser.UpdatedAt = s.clock.Now()
 
-	return err
+	return s.withEdad(replacedUser), nil
 }
 
-func (s *service) UpdateUserLastName(id int64, apellido string) (user User, err error) {
-	user, err = s.repository.UpdateUserLastName(id, apellido)
+func (s *service) DeleteUserByID(ctx context.Context, id int64) (err error) {
+	err = s.repository.Transaction(ctx, func(usersInDatabase *Users) error {
+		idx, err := GetUserIndexById(id, usersInDatabase)
+		if err != nil {
+			return err
+		}
 
-	return user, err
+		err = CheckNotErased(usersInDatabase.Users[idx])
+		if err != nil {
+			return err
+		}
+
+		usersInDatabase.Users = append(usersInDatabase.Users[:idx], usersInDatabase.Users[idx+1:]...)
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.log(ctx).Debug("usuario eliminado", logger.F("user_id", id))
+
+	return nil
 }
 
-func (s *service) UpdateUserAge(id int64, edad int64) (user User, err error) {
-	user, err = s.repository.UpdateUserAge(id, edad)
+func (s *service) UpdateUserLastName(ctx context.Context, id int64, apellido string) (user User, err error) {
+	user, err = s.updateUser(ctx, id, func(user User) (User, error) {
+		user.Apellido = apellido
+		user.UpdatedAt = s.clock.Now()
 
-	return user, err
+		return user, nil
+	})
+	if err != nil {
+		return user, err
+	}
+
+	s.log(ctx).Debug("apellido actualizado", logger.F("user_id", id))
+
+	return user, nil
+}
+
+func (s *service) UpdateUserAge(ctx context.Context, id int64, edad int64) (user User, err error) {
+	user, err = s.updateUser(ctx, id, func(user User) (User, error) {
+		stored := user
+		user.Edad = edad
+		user.FechaDeNacimiento, err = s.resolveFechaDeNacimiento(user, stored)
+		if err != nil {
+			return user, err
+		}
+		user.UpdatedAt = s.clock.Now()
+
+		return s.withEdad(user), nil
+	})
+	if err != nil {
+		return user, err
+	}
+
+	s.log(ctx).Debug("edad actualizada", logger.F("user_id", id))
+
+	return user, nil
+}
+
+// updateUser applies change to the user with the given id and saves it in a
+// transaction, so that no other write gets between the read and the write.
+// Erased users are not changed.
+func (s *service) updateUser(ctx context.Context, id int64, change func(user User) (User, error)) (updatedUser User, err error) {
+	err = s.repository.Transaction(ctx, func(usersInDatabase *Users) error {
+		ptrUser, err := GetUserById(id, usersInDatabase)
+		if err != nil {
+			return err
+		}
+
+		err = CheckNotErased(*ptrUser)
+		if err != nil {
+			return err
+		}
+
+		updatedUser, err = change(s.withEdad(*ptrUser))
+		if err != nil {
+			return err
+		}
+
+		*ptrUser = updatedUser
+
+		return nil
+	})
+
+	return updatedUser, err
+}
+
+// Patch applies the patch to the stored user, validates the whole result and
+// saves it in a single write, so a patch is either fully applied or not at all.
+func (s *service) Patch(ctx context.Context, id int64, patch PatchFunc) (patchedUser User, err error) {
+	err = s.repository.Transaction(ctx, func(usersInDatabase *Users) error {
+		ptrUser, err := GetUserById(id, usersInDatabase)
+		if err != nil {
+			return err
+		}
+
+		patchedUser, err = s.preparePatch(*usersInDatabase, *ptrUser, patch)
+		if err != nil {
+			return err
+		}
+
+		*ptrUser = patchedUser
+
+		return nil
+	})
+	if err != nil {
+		return patchedUser, err
+	}
+
+	s.log(ctx).Debug("usuario actualizado", logger.F("user_id", id))
+
+	return patchedUser, nil
+}
+
+// preparePatch applies the patch to the stored user and validates the result
+// against usersInDatabase. On error it returns the stored user.
+func (s *service) preparePatch(usersInDatabase Users, storedUser User, patch PatchFunc) (patchedUser User, err error) {
+	stored := s.withEdad(storedUser)
+
+	err = CheckNotErased(stored)
+	if err != nil {
+		return stored, err
+	}
+
+	patchedUser, err = patch(stored)
+	if err != nil {
+		return stored, err
+	}
+
+	err = CheckImmutableFields(stored, patchedUser)
+	if err != nil {
+		return stored, err
+	}
+
+	err = ValidateUser(patchedUser)
+	if err != nil {
+		return stored, err
+	}
+
+	for _, registeredUser := range usersInDatabase.Users {
+		if registeredUser.Email == patchedUser.Email && registeredUser.Id != stored.Id {
+			return stored, ErrEmailAlreadyExists
+		}
+	}
+
+	patchedUser.FechaDeNacimiento, err = s.resolveFechaDeNacimiento(patchedUser, stored)
+	if err != nil {
+		return stored, err
+	}
+	patchedUser.UpdatedAt = s.clock.Now()
+
+	return s.withEdad(patchedUser), nil
+}
+
+// CheckImmutableFields rejects changes to the fields assigned by the server.
+func CheckImmutableFields(stored User, patched User) (err error) {
+	switch {
+	case patched.Id != stored.Id:
+		return fmt.Errorf("%w(campo: id)", ErrImmutableField)
+	case patched.FechaDeCreacion != stored.FechaDeCreacion:
+		return fmt.Errorf("%w(campo: fecha_de_creacion)", ErrImmutableField)
+	case !patched.CreatedAt.Equal(stored.CreatedAt):
+		return fmt.Errorf("%w(campo: created_at)", ErrImmutableField)
+	case !patched.UpdatedAt.Equal(stored.UpdatedAt):
+		return fmt.Errorf("%w(campo: updated_at)", ErrImmutableField)
+	case (patched.ErasedAt == nil) != (stored.ErasedAt == nil):
+		return fmt.Errorf("%w(campo: erased_at)", ErrImmutableField)
+	}
+
+	return nil
+}
+
+// ValidateUser checks the fields a patch could leave empty.
+func ValidateUser(user User) (err error) {
+	switch {
+	case user.Nombre == "":
+		return fmt.Errorf("%w, el nombre es requerido", ErrInvalidUser)
+	case user.Apellido == "":
+		return fmt.Errorf("%w, el apellido es requerido", ErrInvalidUser)
+	case user.Email == "":
+		return fmt.Errorf("%w, el email es requerido", ErrInvalidUser)
+	case user.Altura <= 0.0:
+		return fmt.Errorf("%w, la altura debe ser mayor a cero", ErrInvalidUser)
+	}
+
+	return nil
+}
+
+func (s *service) UpdateUserBirthDate(ctx context.Context, id int64, fechaDeNacimiento string) (user User, err error) {
+	user, err = s.updateUser(ctx, id, func(user User) (User, error) {
+		birthDate, err := ParseFechaDeNacimiento(fechaDeNacimiento, s.clock.Now())
+		if err != nil {
+			return user, err
+		}
+
+		user.FechaDeNacimiento = birthDate.Format(FechaDeNacimientoLayout)
+		user.UpdatedAt = s.clock.Now()
+
+		return s.withEdad(user), nil
+	})
+	if err != nil {
+		return user, err
+	}
+
+	s.log(ctx).Debug("fecha de nacimiento actualizada", logger.F("user_id", id))
+
+	return user, nil
+}
+
+// resolveFechaDeNacimiento returns the birth date to store for user. A birth
+// date sent by the client wins, otherwise it is derived from the edad sent by
+// version 1 clients. The stored birth date is kept when the edad sent is the
+// one it already gives, so resending a user doesn't make it less precise.
+func (s *service) resolveFechaDeNacimiento(user User, stored User) (fecha string, err error) {
+	now := s.clock.Now()
+
+	if user.FechaDeNacimiento != "" && user.FechaDeNacimiento != stored.FechaDeNacimiento {
+		birthDate, err := ParseFechaDeNacimiento(user.FechaDeNacimiento, now)
+		if err != nil {
+			return fecha, err
+		}
+
+		return birthDate.Format(FechaDeNacimientoLayout), nil
+	}
+
+	switch {
+	case user.Edad < 0:
+		return fecha, fmt.Errorf("%w, la edad no puede ser negativa(recibido: %d)", ErrInvalidBirthDate, user.Edad)
+	case user.Edad == 0 || (stored.FechaDeNacimiento != "" && user.Edad == stored.Edad):
+		// Users saved without edad have no birth date until one is sent.
+		if stored.FechaDeNacimiento == "" && stored.Id == 0 {
+			return fecha, ErrBirthDateRequired
+		}
+		return stored.FechaDeNacimiento, nil
+	}
+
+	return FechaDeNacimientoAproximada(user.Edad, now), nil
+}
+
+// withEdad computes the edad of the user from its birth date. Users saved
+// before fecha_de_nacimiento existed keep their stored edad.
+func (s *service) withEdad(user User) User {
+	birthDate, err := time.Parse(FechaDeNacimientoLayout, user.FechaDeNacimiento)
+	if err != nil {
+		return user
+	}
+
+	user.Edad = EdadAt(birthDate, s.clock.Now())
+
+	return user
 }
 
 func CreateSearchedUser(availableParams []string, c *gin.Context) (searchedUser User, err error) {
@@ -128,7 +638,7 @@ func CreateSearchedUser(availableParams []string, c *gin.Context) (searchedUser
 		case "id":
 			id, err := strconv.ParseInt(c.Query("id"), 10, 64)
 			if err != nil {
-				return searchedUser, err
+				return searchedUser, fmt.Errorf("el parametro %s no es valido(recibido: %s)", param, c.Query(param))
 			}
 			searchedUser.Id = id
 		case "nombre":
@@ -140,24 +650,26 @@ func CreateSearchedUser(availableParams []string, c *gin.Context) (searchedUser
 		case "edad":
 			edad, err := strconv.ParseInt(c.Query("edad"), 10, 64)
 			if err != nil {
-				return searchedUser, err
+				return searchedUser, fmt.Errorf("el parametro %s no es valido(recibido: %s)", param, c.Query(param))
 			}
 			searchedUser.Edad = edad
 		case "altura":
 			altura, err := strconv.ParseFloat(c.Query("altura"), 64)
 			if err != nil {
-				return searchedUser, err
+				return searchedUser, fmt.Errorf("el parametro %s no es valido(recibido: %s)", param, c.Query(param))
 			}
 			searchedUser.Altura = altura
 		case "activo":
 			activo, err := strconv.ParseBool(c.Query("activo"))
 			if err != nil {
-				return searchedUser, err
+				return searchedUser, fmt.Errorf("el parametro %s no es valido(recibido: %s)", param, c.Query(param))
 			}
 			searchedUser.Activo = activo
 
 		case "fecha_de_creacion":
 			searchedUser.FechaDeCreacion = c.Query("fecha_de_creacion")
+		case "fecha_de_nacimiento":
+			searchedUser.FechaDeNacimiento = c.Query("fecha_de_nacimiento")
 		}
 	}
 
@@ -165,9 +677,16 @@ func CreateSearchedUser(availableParams []string, c *gin.Context) (searchedUser
 
 }
 
+// searchParams are the query params compared with the fields of the users.
+var searchParams = []string{"id", "nombre", "apellido", "email", "edad", "altura", "activo", "fecha_de_creacion", "fecha_de_nacimiento"}
+
+// FilterParams returns the query params understood by FilterFromUrlParams.
+func FilterParams() (params []string) {
+	return append(append(params, searchParams...), "created_after", "created_before")
+}
+
 func CheckAvailableParamsFromGinContext(c *gin.Context) (availableParams []string) {
-	allParams := []string{"id", "nombre", "apellido", "email", "edad", "altura", "fecha_de_creacion"}
-	for _, param := range allParams {
+	for _, param := range searchParams {
 		if c.Query(param) != "" {
 			availableParams = append(availableParams, param)
 		}
@@ -213,6 +732,10 @@ func GetUsersWithGivenParams(availableParams []string, users Users, searchedUser
 				if user.FechaDeCreacion != searchedUser.FechaDeCreacion {
 					areCompatible = false
 				}
+			case "fecha_de_nacimiento":
+				if user.FechaDeNacimiento != searchedUser.FechaDeNacimiento {
+					areCompatible = false
+				}
 			}
 			if !areCompatible {
 				break
@@ -225,3 +748,43 @@ func GetUsersWithGivenParams(availableParams []string, users Users, searchedUser
 
 	return filteredUsers
 }
+
+// GetUsersCreatedBetween keeps the users created after and before the given
+// instants, a zero instant leaves that side of the range open.
+func GetUsersCreatedBetween(users []User, after time.Time, before time.Time) (filteredUsers []User) {
+	if after.IsZero() && before.IsZero() {
+		return users
+	}
+
+	for _, user := range users {
+		if !after.IsZero() && !user.CreatedAt.After(after) {
+			continue
+		}
+		if !before.IsZero() && !user.CreatedAt.Before(before) {
+			continue
+		}
+		filteredUsers = append(filteredUsers, user)
+	}
+
+	return filteredUsers
+}
+
+// GetUsersBornBetween keeps the users born between from and to (both
+// inclusive). Users without fecha_de_nacimiento are compared by their stored edad.
+func GetUsersBornBetween(users []User, from time.Time, to time.Time, edad int64) (filteredUsers []User) {
+	for _, user := range users {
+		birthDate, err := time.Parse(FechaDeNacimientoLayout, user.FechaDeNacimiento)
+		if err != nil {
+			if user.FechaDeNacimiento == "" && user.Edad == edad {
+				filteredUsers = append(filteredUsers, user)
+			}
+			continue
+		}
+
+		if !birthDate.Before(from) && !birthDate.After(to) {
+			filteredUsers = append(filteredUsers, user)
+		}
+	}
+
+	return filteredUsers
+}
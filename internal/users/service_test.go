@@ -1,8 +1,16 @@
 package users
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/logger"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/store"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/web"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -30,7 +38,8 @@ func (db *myDbFullUpdate) Write(data interface{}) (err error) {
 
 func TestFullUpdate(t *testing.T) {
 	var idUserToUpdateLastName int64 = 1
-	expectedUser := User{Id: idUserToUpdateLastName, Nombre: "user name after update", Apellido: "user last name after update", Email: "userAfterUpdate@email.com", Edad: 36, Altura: 1.59, Activo: true, FechaDeCreacion: "13/12/2021"}
+	now := time.Date(2021, 12, 22, 10, 0, 0, 0, time.UTC)
+	expectedUser := User{Id: idUserToUpdateLastName, Nombre: "user name after update", Apellido: "user last name after update", Email: "userAfterUpdate@email.com", Edad: 36, FechaDeNacimiento: "1985-06-22", Altura: 1.59, Activo: true, FechaDeCreacion: "13/12/2021", UpdatedAt: now}
 	userBefore := User{Id: idUserToUpdateLastName, Nombre: "user name before update", Apellido: "user last name before update", Email: "userBeforeUpdate@email.com", Edad: 35, Altura: 1.58, Activo: true, FechaDeCreacion: "13/12/2021"}
 
 	db := &myDbFullUpdate{
@@ -38,10 +47,10 @@ func TestFullUpdate(t *testing.T) {
 		ReadCalled: false,
 	}
 
-	repo := CreateRepository(db)
-	service := CreateService(repo)
+	repo := CreateRepository(db, logger.Nop())
+	service := CreateServiceWithClock(repo, FixedClock(now), logger.Nop())
 
-	user, err := service.FullUpdate(idUserToUpdateLastName, expectedUser.Nombre, expectedUser.Apellido, expectedUser.Email, expectedUser.Edad, expectedUser.Altura)
+	user, err := service.FullUpdate(context.Background(), idUserToUpdateLastName, expectedUser.Nombre, expectedUser.Apellido, expectedUser.Email, expectedUser.Edad, expectedUser.Altura)
 	assert.Nil(t, err)
 	assert.Equal(t, expectedUser, user)
 
@@ -79,20 +88,449 @@ func TestDeleteUserByID(t *testing.T) {
 		Users: []User{user},
 	}
 
-	repo := CreateRepository(db)
-	service := CreateService(repo)
+	repo := CreateRepository(db, logger.Nop())
+	service := CreateService(repo, logger.Nop())
 
 	// Testea el caso de eliminar un usuario inexistente
-	err := service.DeleteUserByID(idNonExistentUserToDelete)
+	err := service.DeleteUserByID(context.Background(), idNonExistentUserToDelete)
 	if assert.Error(t, err) {
 		assert.Equal(t, expectedError, err)
 	}
 
 	// Testea el caso de eliminar un usuario existente
-	err = service.DeleteUserByID(idExistentUserToDelete)
+	err = service.DeleteUserByID(context.Background(), idExistentUserToDelete)
 	assert.Nil(t, err)
 
 	usedRepo := (repo).(*repository)
 	usedDb := (usedRepo.db).(*myDbDeleteUserByID)
 	assert.Empty(t, usedDb.Users)
 }
+
+type myDbCreate struct {
+	Users []User
+}
+
+func (db *myDbCreate) Read(data interface{}) (err error) {
+	data2 := data.(*Users)
+	data2.Users = append([]User{}, db.Users...)
+
+	return nil
+}
+func (db *myDbCreate) Write(data interface{}) (err error) {
+
+	data2 := data.(*Users)
+	db.Users = data2.Users
+
+	return nil
+}
+
+func TestCreate(t *testing.T) {
+	now := time.Date(2021, 12, 22, 10, 0, 0, 0, time.UTC)
+	user1 := User{Id: 1, Nombre: "user1 name", Apellido: "user1 last name", Email: "user1@email.com", Edad: 35, Altura: 1.58, Activo: true, FechaDeCreacion: "13/12/2021"}
+	newUser := User{Nombre: "user2 name", Apellido: "user2 last name", Email: "user2@email.com", Edad: 30, Altura: 1.7, FechaDeCreacion: "01/01/1990"}
+	expectedUser := User{Id: 2, Nombre: "user2 name", Apellido: "user2 last name", Email: "user2@email.com", Edad: 30, FechaDeNacimiento: "1991-06-22", Altura: 1.7, Activo: true, FechaDeCreacion: "22/12/2021", CreatedAt: now, UpdatedAt: now}
+
+	db := &myDbCreate{
+		Users: []User{user1},
+	}
+
+	service := CreateServiceWithClock(CreateRepository(db, logger.Nop()), FixedClock(now), logger.Nop())
+
+	// Testea que el servidor asigne el id y los timestamps
+	user, err := service.Create(context.Background(), newUser)
+	assert.Nil(t, err)
+	assert.Equal(t, expectedUser, user)
+	assert.Equal(t, []User{user1, expectedUser}, db.Users)
+
+	// Testea que no se repitan emails
+	_, err = service.Create(context.Background(), newUser)
+	assert.Equal(t, ErrEmailAlreadyExists, err)
+
+	// Testea que solo la version 1 cree usuarios sin edad ni fecha de nacimiento
+	withoutEdad := User{Nombre: "user3 name", Apellido: "user3 last name", Email: "user3@email.com", Altura: 1.6}
+	_, err = service.Create(context.Background(), withoutEdad)
+	assert.ErrorIs(t, err, ErrBirthDateRequired)
+
+	user, err = service.Create(AllowLegacyUsers(context.Background()), withoutEdad)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), user.Edad)
+	assert.Equal(t, "", user.FechaDeNacimiento)
+
+	// Testea que se pueda modificar un usuario sin fecha de nacimiento
+	user, err = service.Patch(context.Background(), user.Id, func(user User) (User, error) {
+		user.Apellido = "otro apellido"
+		return user, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "", user.FechaDeNacimiento)
+}
+
+func TestFilterByCreationDate(t *testing.T) {
+	user1 := User{Id: 1, Email: "user1@email.com", CreatedAt: time.Date(2021, 12, 13, 0, 0, 0, 0, time.UTC)}
+	user2 := User{Id: 2, Email: "user2@email.com", CreatedAt: time.Date(2021, 12, 22, 0, 0, 0, 0, time.UTC)}
+	user3 := User{Id: 3, Email: "user3@email.com", CreatedAt: time.Date(2022, 1, 5, 0, 0, 0, 0, time.UTC)}
+
+	db := &myDbCreate{
+		Users: []User{user1, user2, user3},
+	}
+
+	service := CreateService(CreateRepository(db, logger.Nop()), logger.Nop())
+
+	filter := Filter{
+		CreatedAfter:  time.Date(2021, 12, 20, 0, 0, 0, 0, time.UTC),
+		CreatedBefore: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	filteredUsers, err := service.Filter(context.Background(), filter)
+	assert.Nil(t, err)
+	assert.Equal(t, []User{user2}, filteredUsers.Users)
+}
+
+func TestMigrateTimestamps(t *testing.T) {
+	createdAt := time.Date(2022, 1, 5, 10, 0, 0, 0, time.UTC)
+	user1 := User{Id: 1, Email: "user1@email.com", FechaDeCreacion: "22/12/21"}
+	user2 := User{Id: 2, Email: "user2@email.com", FechaDeCreacion: "13/12/2021"}
+	user3 := User{Id: 3, Email: "user3@email.com", FechaDeCreacion: "ayer"}
+	user4 := User{Id: 4, Email: "user4@email.com", FechaDeCreacion: "05/01/2022", CreatedAt: createdAt, UpdatedAt: createdAt}
+
+	db := &myDbCreate{
+		Users: []User{user1, user2, user3, user4},
+	}
+
+	migrated, unparsed, err := MigrateTimestamps(db)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, migrated)
+	assert.Equal(t, []int64{3}, unparsed)
+
+	assert.Equal(t, time.Date(2021, 12, 22, 0, 0, 0, 0, time.UTC), db.Users[0].CreatedAt)
+	assert.Equal(t, time.Date(2021, 12, 13, 0, 0, 0, 0, time.UTC), db.Users[1].UpdatedAt)
+	assert.True(t, db.Users[2].CreatedAt.IsZero())
+	assert.Equal(t, createdAt, db.Users[3].CreatedAt)
+}
+
+func TestMigrateBirthDates(t *testing.T) {
+	now := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+	user1 := User{Id: 1, Email: "user1@email.com", Edad: 25, CreatedAt: time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC)}
+	user2 := User{Id: 2, Email: "user2@email.com", Edad: 0}
+
+	db := &myDbCreate{
+		Users: []User{user1, user2},
+	}
+
+	// Testea que los usuarios conserven la edad que tenian antes de migrar
+	migrated, err := MigrateBirthDates(db, now)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, migrated)
+	assert.Equal(t, "", db.Users[1].FechaDeNacimiento)
+
+	service := CreateServiceWithClock(CreateRepository(db, logger.Nop()), FixedClock(now), logger.Nop())
+	user, err := service.GetUserByID(context.Background(), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(25), user.Edad)
+}
+
+func TestEdadFromBirthDate(t *testing.T) {
+	now := time.Date(2021, 12, 22, 10, 0, 0, 0, time.UTC)
+	user1 := User{Id: 1, Email: "user1@email.com", Edad: 20, FechaDeNacimiento: "1990-12-22"}
+	user2 := User{Id: 2, Email: "user2@email.com", Edad: 20, FechaDeNacimiento: "1990-12-23"}
+	user3 := User{Id: 3, Email: "user3@email.com", Edad: 31}
+
+	db := &myDbCreate{
+		Users: []User{user1, user2, user3},
+	}
+
+	service := CreateServiceWithClock(CreateRepository(db, logger.Nop()), FixedClock(now), logger.Nop())
+
+	// Testea que la edad se calcule con el reloj del servicio
+	user, err := service.GetUserByID(context.Background(), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(31), user.Edad)
+
+	user, err = service.GetUserByID(context.Background(), 2)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(30), user.Edad)
+
+	// Testea que el filtro por edad use el rango de fechas de nacimiento
+	filteredUsers, err := service.Filter(context.Background(), Filter{Params: []string{"edad"}, Searched: User{Edad: 31}})
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1, 3}, []int64{filteredUsers.Users[0].Id, filteredUsers.Users[1].Id})
+	assert.Len(t, filteredUsers.Users, 2)
+
+	// Testea que reenviar la misma edad conserve la fecha de nacimiento
+	user, err = service.UpdateUserAge(context.Background(), 1, 31)
+	assert.Nil(t, err)
+	assert.Equal(t, "1990-12-22", user.FechaDeNacimiento)
+
+	_, err = service.UpdateUserBirthDate(context.Background(), 1, "2030-01-01")
+	assert.ErrorIs(t, err, ErrInvalidBirthDate)
+}
+
+func TestPatch(t *testing.T) {
+	now := time.Date(2021, 12, 22, 10, 0, 0, 0, time.UTC)
+	user1 := User{Id: 1, Nombre: "user1 name", Apellido: "user1 last name", Email: "user1@email.com", Edad: 31, FechaDeNacimiento: "1990-12-22", Altura: 1.58, Activo: true, FechaDeCreacion: "13/12/2021"}
+	user2 := User{Id: 2, Nombre: "user2 name", Apellido: "user2 last name", Email: "user2@email.com", Edad: 20, FechaDeNacimiento: "2001-01-01", Altura: 1.7, Activo: true, FechaDeCreacion: "13/12/2021"}
+
+	db := &myDbCreate{
+		Users: []User{user1, user2},
+	}
+
+	service := CreateServiceWithClock(CreateRepository(db, logger.Nop()), FixedClock(now), logger.Nop())
+
+	// Testea que se puedan asignar valores cero como activo=false
+	user, err := service.Patch(context.Background(), 1, func(user User) (User, error) {
+		user.Activo = false
+		user.Altura = 1.6
+		return user, nil
+	})
+	assert.Nil(t, err)
+	assert.False(t, user.Activo)
+	assert.Equal(t, 1.6, db.Users[0].Altura)
+	assert.Equal(t, now, db.Users[0].UpdatedAt)
+
+	// Testea que un patch invalido no aplique ningun cambio
+	patches := map[error]PatchFunc{
+		ErrImmutableField: func(user User) (User, error) {
+			user.Apellido = "changed"
+			user.Id = 7
+			return user, nil
+		},
+		ErrInvalidUser: func(user User) (User, error) {
+			user.Apellido = "changed"
+			user.Nombre = ""
+			return user, nil
+		},
+		ErrEmailAlreadyExists: func(user User) (User, error) {
+			user.Apellido = "changed"
+			user.Email = "user2@email.com"
+			return user, nil
+		},
+	}
+	for expectedErr, patch := range patches {
+		_, err = service.Patch(context.Background(), 1, patch)
+		assert.ErrorIs(t, err, expectedErr)
+		assert.Equal(t, "user1 last name", db.Users[0].Apellido)
+	}
+
+	_, err = service.Patch(context.Background(), 3, func(user User) (User, error) { return user, nil })
+	assert.Equal(t, ErrUserNotFound, err)
+
+	// Testea que si el cliente se va antes de guardar no se escriba nada
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err = service.Patch(ctx, 1, func(user User) (User, error) {
+		cancel()
+		user.Apellido = "changed"
+		return user, nil
+	})
+	assert.ErrorIs(t, err, store.ErrCanceled)
+	assert.Equal(t, "user1 last name", db.Users[0].Apellido)
+}
+
+type myDbBatch struct {
+	Users  []User
+	Writes int
+}
+
+func (db *myDbBatch) Read(data interface{}) (err error) {
+	data2 := data.(*Users)
+	data2.Users = append([]User{}, db.Users...)
+
+	return nil
+}
+func (db *myDbBatch) Write(data interface{}) (err error) {
+	db.Writes++
+
+	data2 := data.(*Users)
+	db.Users = data2.Users
+
+	return nil
+}
+
+func TestBatch(t *testing.T) {
+	now := time.Date(2021, 12, 22, 10, 0, 0, 0, time.UTC)
+	user1 := User{Id: 1, Nombre: "user1 name", Apellido: "user1 last name", Email: "user1@email.com", Edad: 31, FechaDeNacimiento: "1990-12-22", Altura: 1.58, Activo: true, FechaDeCreacion: "13/12/2021"}
+	user2 := User{Id: 2, Nombre: "user2 name", Apellido: "user2 last name", Email: "user2@email.com", Edad: 20, FechaDeNacimiento: "2001-01-01", Altura: 1.7, Activo: true, FechaDeCreacion: "13/12/2021"}
+	newUser := User{Nombre: "user3 name", Apellido: "user3 last name", Email: "user3@email.com", FechaDeNacimiento: "2000-01-01", Altura: 1.6}
+
+	operations := []BatchOperation{
+		{Op: BatchCreate, User: newUser},
+		{Op: BatchUpdate, ID: 1, User: User{Nombre: "new name", Apellido: "new last name", Email: "user1@email.com", Edad: 31, Altura: 1.6}},
+		{Op: BatchDelete, ID: 2},
+		{Op: BatchDelete, ID: 9},
+	}
+
+	// Testea que las operaciones validas se apliquen en una sola escritura
+	db := &myDbBatch{Users: []User{user1, user2}}
+	service := CreateServiceWithClock(CreateRepository(db, logger.Nop()), FixedClock(now), logger.Nop())
+
+	results, err := service.Batch(context.Background(), operations, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, db.Writes)
+	assert.Len(t, results, 4)
+	assert.Equal(t, int64(3), results[0].ID)
+	assert.Nil(t, results[1].Err)
+	assert.Equal(t, "new name", results[1].User.Nombre)
+	assert.Equal(t, ErrUserNotFound, results[3].Err)
+	assert.Equal(t, []int64{1, 3}, []int64{db.Users[0].Id, db.Users[1].Id})
+	assert.Equal(t, "1990-12-22", db.Users[0].FechaDeNacimiento)
+
+	// Testea que en modo atomico no se aplique ninguna operacion
+	db = &myDbBatch{Users: []User{user1, user2}}
+	service = CreateServiceWithClock(CreateRepository(db, logger.Nop()), FixedClock(now), logger.Nop())
+
+	results, err = service.Batch(context.Background(), operations, true)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, db.Writes)
+	assert.Equal(t, []User{user1, user2}, db.Users)
+	assert.Equal(t, ErrBatchRolledBack, results[0].Err)
+	assert.Equal(t, int64(0), results[0].ID)
+	assert.Nil(t, results[0].User)
+	assert.Equal(t, ErrUserNotFound, results[3].Err)
+}
+
+func TestUpdateAndDeleteWhere(t *testing.T) {
+	now := time.Date(2021, 12, 22, 10, 0, 0, 0, time.UTC)
+	user1 := User{Id: 1, Nombre: "user1 name", Apellido: "cohort", Email: "user1@email.com", Edad: 31, FechaDeNacimiento: "1990-12-22", Altura: 1.58, Activo: true, FechaDeCreacion: "13/12/2021"}
+	user2 := User{Id: 2, Nombre: "user2 name", Apellido: "cohort", Email: "user2@email.com", Edad: 20, FechaDeNacimiento: "2001-01-01", Altura: 1.7, Activo: true, FechaDeCreacion: "13/12/2021"}
+	user3 := User{Id: 3, Nombre: "user3 name", Apellido: "other", Email: "user3@email.com", Edad: 20, FechaDeNacimiento: "2001-01-01", Altura: 1.7, Activo: true, FechaDeCreacion: "13/12/2021"}
+
+	db := &myDbBatch{Users: []User{user1, user2, user3}}
+	service := CreateServiceWithClock(CreateRepository(db, logger.Nop()), FixedClock(now), logger.Nop())
+
+	filter := Filter{Params: []string{"apellido"}, Searched: User{Apellido: "cohort"}}
+	deactivate := func(user User) (User, error) {
+		user.Activo = false
+		return user, nil
+	}
+
+	// Testea que la simulacion no guarde cambios
+	changes, err := service.UpdateWhere(context.Background(), filter, deactivate, BulkOptions{DryRun: true})
+	assert.Nil(t, err)
+	assert.Len(t, changes, 2)
+	assert.False(t, changes[1].After.Activo)
+	assert.Equal(t, 0, db.Writes)
+
+	// Testea que se exija confirmar la cantidad y al menos un filtro
+	_, err = service.UpdateWhere(context.Background(), filter, deactivate, BulkOptions{ConfirmCount: 3})
+	assert.ErrorIs(t, err, ErrCountMismatch)
+
+	_, err = service.DeleteWhere(context.Background(), Filter{}, BulkOptions{ConfirmCount: 3})
+	assert.Equal(t, ErrEmptyFilter, err)
+	assert.Equal(t, 0, db.Writes)
+
+	changes, err = service.UpdateWhere(context.Background(), filter, deactivate, BulkOptions{ConfirmCount: 2})
+	assert.Nil(t, err)
+	assert.Len(t, changes, 2)
+	assert.Equal(t, 1, db.Writes)
+	assert.Equal(t, []bool{false, false, true}, []bool{db.Users[0].Activo, db.Users[1].Activo, db.Users[2].Activo})
+
+	changes, err = service.DeleteWhere(context.Background(), filter, BulkOptions{ConfirmCount: 2})
+	assert.Nil(t, err)
+	assert.Nil(t, changes[0].After)
+	assert.Equal(t, []User{user3}, db.Users)
+}
+
+func TestImport(t *testing.T) {
+	now := time.Date(2021, 12, 22, 10, 0, 0, 0, time.UTC)
+	user1 := User{Id: 1, Nombre: "user1 name", Apellido: "user1 last name", Email: "user1@email.com", Edad: 31, FechaDeNacimiento: "1990-12-22", Altura: 1.58, Activo: true, FechaDeCreacion: "13/12/2021"}
+
+	body := "nombre,apellido,email,fecha_de_nacimiento,altura\n" +
+		"user2,last2,user2@email.com,2000-01-01,1.7\n" +
+		"user1,new last name,user1@email.com,1990-12-22,1.6\n" +
+		"user3,last3,,2000-01-01,1.7\n"
+	rows, err := ReadImportRows(web.MIMECSV, strings.NewReader(body), nil)
+	assert.Nil(t, err)
+
+	// Testea que el modo por defecto no guarde nada si hay filas invalidas
+	db := &myDbBatch{Users: []User{user1}}
+	service := CreateServiceWithClock(CreateRepository(db, logger.Nop()), FixedClock(now), logger.Nop())
+
+	report, err := service.Import(context.Background(), rows, ImportOptions{})
+	assert.Nil(t, err)
+	assert.False(t, report.Applied)
+	assert.Equal(t, 0, db.Writes)
+	assert.Equal(t, []int{3, 4}, []int{report.Errors[0].Line, report.Errors[1].Line})
+	assert.ErrorIs(t, report.Errors[0].Err, ErrEmailAlreadyExists)
+	assert.ErrorIs(t, report.Errors[1].Err, ErrInvalidUser)
+
+	// Testea que se guarden las filas validas y se actualice por email
+	report, err = service.Import(context.Background(), rows, ImportOptions{SkipInvalid: true, Upsert: true})
+	assert.Nil(t, err)
+	assert.True(t, report.Applied)
+	assert.Equal(t, 1, db.Writes)
+	assert.Len(t, report.Errors, 1)
+	assert.Equal(t, []ImportedRow{
+		{Line: 2, ID: 2, Action: ImportCreated, User: db.Users[1]},
+		{Line: 3, ID: 1, Action: ImportUpdated, User: db.Users[0]},
+	}, report.Accepted)
+	assert.Equal(t, "new last name", db.Users[0].Apellido)
+
+	// Testea que al reanudar una importacion ya guardada sus filas queden sin cambios
+	report, err = service.Import(context.Background(), rows[:2], ImportOptions{Resume: true})
+	assert.Nil(t, err)
+	assert.True(t, report.Applied)
+	assert.Empty(t, report.Errors)
+	assert.Equal(t, []string{ImportUnchanged, ImportUnchanged}, []string{report.Accepted[0].Action, report.Accepted[1].Action})
+	assert.Len(t, db.Users, 2)
+}
+
+func TestFsck(t *testing.T) {
+	now := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	document := `{"users": [
+		{"id": 1, "nombre": "a", "apellido": "b", "email": " a@x.com ", "altura": "1.7", "activo": "si", "fecha_de_creacion": "22/12/21", "created_at": "2021-12-22T00:00:00Z", "updated_at": "2021-12-22T00:00:00Z", "apodo": "z"},
+		{"id": 1, "nombre": "c", "apellido": "d", "email": "A@x.com", "altura": 1.6, "activo": true, "fecha_de_creacion": "xx", "created_at": "2021-12-22T00:00:00Z", "updated_at": "2021-12-22T00:00:00Z", "fecha_de_nacimiento": "1990-01-01"},
+		{"id": 0, "nombre": "borrado", "apellido": "borrado", "email": "borrado-0@invalid", "altura": 1.6, "activo": true, "fecha_de_creacion": "22/12/21", "erased_at": "2022-01-01T00:00:00Z"}
+	]}`
+
+	report, fixed := Fsck([]byte(document), now)
+
+	codes := map[string]int{}
+	for _, issue := range report.Issues {
+		codes[issue.Code]++
+	}
+	assert.Equal(t, map[string]int{
+		IssueUnknownField:      1,
+		IssueWrongType:         2,
+		IssueInvalidEmail:      1,
+		IssueDuplicateId:       1,
+		IssueInvalidDate:       1,
+		IssueDuplicateEmail:    1,
+		IssueInvalidId:         1,
+		IssueInconsistentState: 1,
+		IssuePendingMigration:  2,
+	}, codes)
+	assert.Equal(t, 3, report.UsersWithErrors)
+	assert.True(t, report.Failed())
+
+	// Testea que se corrijan los problemas con correccion y queden los que requieren una decision
+	assert.NotNil(t, fixed)
+	assert.Equal(t, int64(2), fixed.Users[1].Id)
+	assert.Equal(t, int64(3), fixed.Users[2].Id)
+	assert.Equal(t, "a@x.com", fixed.Users[0].Email)
+	assert.Equal(t, 1.7, fixed.Users[0].Altura)
+	assert.True(t, fixed.Users[0].Activo)
+	assert.False(t, fixed.Users[2].Activo)
+
+	data, _ := json.Marshal(fixed)
+	remaining, _ := Fsck(data, now)
+	assert.Equal(t, 1, remaining.UsersWithErrors)
+
+	// Testea que un tipo sin conversion impida escribir el documento
+	report, fixed = Fsck([]byte(`{"users": [{"id": 1, "edad": "muchos", "extra": 1}]}`), now)
+	assert.True(t, report.Failed())
+	assert.Nil(t, fixed)
+
+	// Testea que un tipo sin conversion no saltee los demas controles del usuario
+	document = `{"users": [
+		{"id": 1, "nombre": "a", "apellido": "b", "email": "a@x.com", "altura": 1.7, "activo": true, "edad": "muchos", "created_at": "2021-12-22T00:00:00Z", "updated_at": "2021-12-22T00:00:00Z", "fecha_de_nacimiento": "1990-01-01"},
+		{"id": 1, "nombre": "", "apellido": "d", "email": "a@x.com", "altura": [1.6], "activo": true, "created_at": "2021-12-22T00:00:00Z", "updated_at": "2021-12-22T00:00:00Z", "fecha_de_nacimiento": "01/13/1990"}
+	]}`
+	report, fixed = Fsck([]byte(document), now)
+	assert.Nil(t, fixed)
+	issues := map[string]string{}
+	for _, issue := range report.Issues {
+		if issue.Position == 1 {
+			issues[issue.Code] = issue.Message
+		}
+	}
+	assert.Equal(t, []string{IssueDuplicateEmail, IssueDuplicateId, IssueInvalidDate, IssueInvalidUser, IssueWrongType}, sortedKeys(issues))
+	assert.Contains(t, issues[IssueInvalidUser], "el nombre es requerido")
+}
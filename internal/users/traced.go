@@ -0,0 +1,286 @@
+package users
+
+import (
+	"context"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/trace"
+
+	"github.com/gin-gonic/gin"
+)
+
+type tracedRepository struct {
+	next Repository
+}
+
+// TraceRepository returns a repository that wraps each operation of next in
+// a span, child of the span in the context of the call.
+func TraceRepository(next Repository) Repository {
+	return &tracedRepository{next: next}
+}
+
+// startSpan starts the span of an operation, named as
+// users.Repository/GetAll or users.Service/GetAll.
+func startSpan(ctx context.Context, component string, operation string) (context.Context, *trace.Span) {
+	return trace.Start(ctx, "users."+component+"/"+operation)
+}
+
+// endSpan ends the span of an operation that returned err.
+func endSpan(span *trace.Span, err error) {
+	span.RecordError(err)
+	span.End()
+}
+
+func (r *tracedRepository) GetAll(ctx context.Context) (users *Users, err error) {
+	ctx, span := startSpan(ctx, "Repository", "GetAll")
+	defer func() { endSpan(span, err) }()
+
+	return r.next.GetAll(ctx)
+}
+
+func (r *tracedRepository) Store(ctx context.Context, id int64, nombre string, apellido string, email string, edad int64, altura float64, activo bool, fecha_de_creacion string) (user User, err error) {
+	ctx, span := startSpan(ctx, "Repository", "Store")
+	span.SetAttribute("user.id", id)
+	defer func() { endSpan(span, err) }()
+
+	return r.next.Store(ctx, id, nombre, apellido, email, edad, altura, activo, fecha_de_creacion)
+}
+
+func (r *tracedRepository) FullUpdate(ctx context.Context, id int64, nombre string, apellido string, email string, edad int64, altura float64) (user User, err error) {
+	ctx, span := startSpan(ctx, "Repository", "FullUpdate")
+	span.SetAttribute("user.id", id)
+	defer func() { endSpan(span, err) }()
+
+	return r.next.FullUpdate(ctx, id, nombre, apellido, email, edad, altura)
+}
+
+func (r *tracedRepository) DeleteUserByID(ctx context.Context, id int64) (err error) {
+	ctx, span := startSpan(ctx, "Repository", "DeleteUserByID")
+	span.SetAttribute("user.id", id)
+	defer func() { endSpan(span, err) }()
+
+	return r.next.DeleteUserByID(ctx, id)
+}
+
+func (r *tracedRepository) UpdateUserLastName(ctx context.Context, id int64, apellido string) (user User, err error) {
+	ctx, span := startSpan(ctx, "Repository", "UpdateUserLastName")
+	span.SetAttribute("user.id", id)
+	defer func() { endSpan(span, err) }()
+
+	return r.next.UpdateUserLastName(ctx, id, apellido)
+}
+
+func (r *tracedRepository) UpdateUserAge(ctx context.Context, id int64, edad int64) (user User, err error) {
+	ctx, span := startSpan(ctx, "Repository", "UpdateUserAge")
+	span.SetAttribute("user.id", id)
+	defer func() { endSpan(span, err) }()
+
+	return r.next.UpdateUserAge(ctx, id, edad)
+}
+
+func (r *tracedRepository) Insert(ctx context.Context, user User) (insertedUser User, err error) {
+	ctx, span := startSpan(ctx, "Repository", "Insert")
+	defer func() { endSpan(span, err) }()
+
+	return r.next.Insert(ctx, user)
+}
+
+func (r *tracedRepository) Update(ctx context.Context, user User) (updatedUser User, err error) {
+	ctx, span := startSpan(ctx, "Repository", "Update")
+	span.SetAttribute("user.id", user.Id)
+	defer func() { endSpan(span, err) }()
+
+	return r.next.Update(ctx, user)
+}
+
+func (r *tracedRepository) Transaction(ctx context.Context, apply func(users *Users) error) (err error) {
+	ctx, span := startSpan(ctx, "Repository", "Transaction")
+	defer func() { endSpan(span, err) }()
+
+	return r.next.Transaction(ctx, apply)
+}
+
+type tracedService struct {
+	next Service
+}
+
+// TraceService returns a service that wraps each method of next in a span,
+// child of the span of the request.
+func TraceService(next Service) Service {
+	return &tracedService{next: next}
+}
+
+// withSpan runs call with the request of c carrying the span, so the service
+// below, which reads the context of the request, starts its spans under it.
+func withSpan(c *gin.Context, operation string, call func() error) {
+	request := c.Request
+	ctx, span := startSpan(request.Context(), "Service", operation)
+	c.Request = request.WithContext(ctx)
+	defer func() { c.Request = request }()
+
+	endSpan(span, call())
+}
+
+func (s *tracedService) GetAll(ctx context.Context) (users *Users, err error) {
+	ctx, span := startSpan(ctx, "Service", "GetAll")
+	defer func() { endSpan(span, err) }()
+
+	return s.next.GetAll(ctx)
+}
+
+func (s *tracedService) Store(ctx context.Context, id int64, nombre string, apellido string, email string, edad int64, altura float64, activo bool, fecha_de_creacion string) (user User, err error) {
+	ctx, span := startSpan(ctx, "Service", "Store")
+	span.SetAttribute("user.id", id)
+	defer func() { endSpan(span, err) }()
+
+	return s.next.Store(ctx, id, nombre, apellido, email, edad, altura, activo, fecha_de_creacion)
+}
+
+func (s *tracedService) FilterByUrlParams(c *gin.Context) (filteredUsers Users, err error) {
+	withSpan(c, "FilterByUrlParams", func() error {
+		filteredUsers, err = s.next.FilterByUrlParams(c)
+		return err
+	})
+
+	return filteredUsers, err
+}
+
+func (s *tracedService) Filter(ctx context.Context, filter Filter) (filteredUsers Users, err error) {
+	ctx, span := startSpan(ctx, "Service", "Filter")
+	defer func() { endSpan(span, err) }()
+
+	return s.next.Filter(ctx, filter)
+}
+
+func (s *tracedService) Export(ctx context.Context, filter Filter, visit func(user User) error) (err error) {
+	ctx, span := startSpan(ctx, "Service", "Export")
+	defer func() { endSpan(span, err) }()
+
+	return s.next.Export(ctx, filter, visit)
+}
+
+func (s *tracedService) GetUserByID(ctx context.Context, id int64) (user User, err error) {
+	ctx, span := startSpan(ctx, "Service", "GetUserByID")
+	span.SetAttribute("user.id", id)
+	defer func() { endSpan(span, err) }()
+
+	return s.next.GetUserByID(ctx, id)
+}
+
+func (s *tracedService) NewUser(c *gin.Context) (user User, err error) {
+	withSpan(c, "NewUser", func() error {
+		user, err = s.next.NewUser(c)
+		return err
+	})
+
+	return user, err
+}
+
+func (s *tracedService) Create(ctx context.Context, user User) (createdUser User, err error) {
+	ctx, span := startSpan(ctx, "Service", "Create")
+	defer func() { endSpan(span, err) }()
+
+	return s.next.Create(ctx, user)
+}
+
+func (s *tracedService) FullUpdate(ctx context.Context, id int64, nombre string, apellido string, email string, edad int64, altura float64) (user User, err error) {
+	ctx, span := startSpan(ctx, "Service", "FullUpdate")
+	span.SetAttribute("user.id", id)
+	defer func() { endSpan(span, err) }()
+
+	return s.next.FullUpdate(ctx, id, nombre, apellido, email, edad, altura)
+}
+
+func (s *tracedService) Replace(ctx context.Context, id int64, user User) (replacedUser User, err error) {
+	ctx, span := startSpan(ctx, "Service", "Replace")
+	span.SetAttribute("user.id", id)
+	defer func() { endSpan(span, err) }()
+
+	return s.next.Replace(ctx, id, user)
+}
+
+func (s *tracedService) DeleteUserByID(ctx context.Context, id int64) (err error) {
+	ctx, span := startSpan(ctx, "Service", "DeleteUserByID")
+	span.SetAttribute("user.id", id)
+	defer func() { endSpan(span, err) }()
+
+	return s.next.DeleteUserByID(ctx, id)
+}
+
+func (s *tracedService) UpdateUserLastName(ctx context.Context, id int64, apellido string) (user User, err error) {
+	ctx, span := startSpan(ctx, "Service", "UpdateUserLastName")
+	span.SetAttribute("user.id", id)
+	defer func() { endSpan(span, err) }()
+
+	return s.next.UpdateUserLastName(ctx, id, apellido)
+}
+
+func (s *tracedService) UpdateUserAge(ctx context.Context, id int64, edad int64) (user User, err error) {
+	ctx, span := startSpan(ctx, "Service", "UpdateUserAge")
+	span.SetAttribute("user.id", id)
+	defer func() { endSpan(span, err) }()
+
+	return s.next.UpdateUserAge(ctx, id, edad)
+}
+
+func (s *tracedService) UpdateUserBirthDate(ctx context.Context, id int64, fechaDeNacimiento string) (user User, err error) {
+	ctx, span := startSpan(ctx, "Service", "UpdateUserBirthDate")
+	span.SetAttribute("user.id", id)
+	defer func() { endSpan(span, err) }()
+
+	return s.next.UpdateUserBirthDate(ctx, id, fechaDeNacimiento)
+}
+
+func (s *tracedService) Patch(ctx context.Context, id int64, patch PatchFunc) (patchedUser User, err error) {
+	ctx, span := startSpan(ctx, "Service", "Patch")
+	span.SetAttribute("user.id", id)
+	defer func() { endSpan(span, err) }()
+
+	return s.next.Patch(ctx, id, patch)
+}
+
+func (s *tracedService) Batch(ctx context.Context, operations []BatchOperation, atomic bool) (results []BatchResult, err error) {
+	ctx, span := startSpan(ctx, "Service", "Batch")
+	span.SetAttribute("batch.operations", len(operations))
+	span.SetAttribute("batch.atomic", atomic)
+	defer func() { endSpan(span, err) }()
+
+	return s.next.Batch(ctx, operations, atomic)
+}
+
+func (s *tracedService) UpdateWhere(ctx context.Context, filter Filter, patch PatchFunc, options BulkOptions) (changes []BulkChange, err error) {
+	ctx, span := startSpan(ctx, "Service", "UpdateWhere")
+	span.SetAttribute("bulk.dry_run", options.DryRun)
+	defer func() {
+		span.SetAttribute("bulk.changes", len(changes))
+		endSpan(span, err)
+	}()
+
+	return s.next.UpdateWhere(ctx, filter, patch, options)
+}
+
+func (s *tracedService) DeleteWhere(ctx context.Context, filter Filter, options BulkOptions) (changes []BulkChange, err error) {
+	ctx, span := startSpan(ctx, "Service", "DeleteWhere")
+	span.SetAttribute("bulk.dry_run", options.DryRun)
+	defer func() {
+		span.SetAttribute("bulk.changes", len(changes))
+		endSpan(span, err)
+	}()
+
+	return s.next.DeleteWhere(ctx, filter, options)
+}
+
+func (s *tracedService) Import(ctx context.Context, rows []ImportRow, options ImportOptions) (report ImportReport, err error) {
+	ctx, span := startSpan(ctx, "Service", "Import")
+	span.SetAttribute("import.rows", len(rows))
+	defer func() { endSpan(span, err) }()
+
+	return s.next.Import(ctx, rows, options)
+}
+
+func (s *tracedService) Erase(ctx context.Context, id int64) (original User, tombstone User, err error) {
+	ctx, span := startSpan(ctx, "Service", "Erase")
+	span.SetAttribute("user.id", id)
+	defer func() { endSpan(span, err) }()
+
+	return s.next.Erase(ctx, id)
+}
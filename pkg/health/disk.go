@@ -0,0 +1,18 @@
+//go:build !windows
+// +build !windows
+
+package health
+
+import "syscall"
+
+// FreeSpace returns the bytes available to unprivileged users in the
+// filesystem holding path.
+func FreeSpace(path string) (free uint64, err error) {
+	var stat syscall.Statfs_t
+	err = syscall.Statfs(path, &stat)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
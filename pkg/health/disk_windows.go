@@ -0,0 +1,24 @@
+package health
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// FreeSpace returns the bytes available to the user of the service in the
+// volume holding path.
+func FreeSpace(path string) (free uint64, err error) {
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+
+	kernel32 := syscall.NewLazyDLL("kernel32.dll")
+	getDiskFreeSpaceEx := kernel32.NewProc("GetDiskFreeSpaceExW")
+	ret, _, callErr := getDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(pathPtr)), uintptr(unsafe.Pointer(&free)), 0, 0)
+	if ret == 0 {
+		return 0, callErr
+	}
+
+	return free, nil
+}
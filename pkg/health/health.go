@@ -0,0 +1,138 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Check probes a dependency, returning why it is not usable.
+type Check func(ctx context.Context) (err error)
+
+// Result is the outcome of a check, as reported by /readyz.
+type Result struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of every check. Its status is ok only when all the
+// checks passed.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker runs the readiness checks, each bounded by timeout so a hung disk
+// fails the probe instead of blocking it.
+type Checker struct {
+	timeout time.Duration
+	checks  []namedCheck
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add registers a check. Names must be unique, they key the report.
+func (c *Checker) Add(name string, check Check) {
+	for _, registered := range c.checks {
+		if registered.name == name {
+			panic(fmt.Sprintf("health: el chequeo %s ya esta registrado", name))
+		}
+	}
+
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+	sort.Slice(c.checks, func(i, j int) bool { return c.checks[i].name < c.checks[j].name })
+}
+
+// Run runs every check at once and waits for all of them.
+func (c *Checker) Run(ctx context.Context) Report {
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(c.checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, registered := range c.checks {
+		wg.Add(1)
+		go func(registered namedCheck) {
+			defer wg.Done()
+
+			result := c.run(ctx, registered.check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[registered.name] = result
+			if result.Status != StatusOK {
+				report.Status = StatusFail
+			}
+		}(registered)
+	}
+	wg.Wait()
+
+	return report
+}
+
+// run runs a check until it returns or its timeout passes. A check that
+// outlives its timeout goes on in the background and its result is dropped.
+func (c *Checker) run(ctx context.Context, check Check) (result Result) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				done <- fmt.Errorf("el chequeo fallo inesperadamente: %v", recovered)
+			}
+		}()
+		done <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("el chequeo no respondio a tiempo: %w", ctx.Err())
+	}
+
+	result = Result{Status: StatusOK, LatencyMs: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// DiskSpace fails when the filesystem holding path has less than minFree
+// bytes available to the service.
+func DiskSpace(path string, minFree uint64) Check {
+	return func(ctx context.Context) (err error) {
+		free, err := FreeSpace(path)
+		if err != nil {
+			return err
+		}
+
+		if free < minFree {
+			return fmt.Errorf("queda poco espacio en disco: %d MB libres, se requieren %d MB", free>>20, minFree>>20)
+		}
+
+		return nil
+	}
+}
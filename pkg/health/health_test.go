@@ -0,0 +1,49 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker(t *testing.T) {
+	checker := NewChecker(50 * time.Millisecond)
+	checker.Add("store", func(ctx context.Context) error { return nil })
+	checker.Add("disk", DiskSpace(t.TempDir(), 0))
+
+	// Testea que el reporte este ok cuando pasan todos los chequeos
+	report := checker.Run(context.Background())
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Equal(t, 2, len(report.Checks))
+	assert.Equal(t, StatusOK, report.Checks["disk"].Status)
+
+	// Testea que falle un chequeo con error, uno colgado y uno que entra en panico
+	checker.Add("users_file", func(ctx context.Context) error { return errors.New("el archivo no es JSON valido") })
+	checker.Add("jobs_flush", func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(time.Second)
+		return nil
+	})
+	checker.Add("panic", func(ctx context.Context) error { panic("sin disco") })
+
+	start := time.Now()
+	report = checker.Run(context.Background())
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, StatusOK, report.Checks["store"].Status)
+	assert.Equal(t, StatusFail, report.Checks["users_file"].Status)
+	assert.Equal(t, "el archivo no es JSON valido", report.Checks["users_file"].Error)
+	assert.Contains(t, report.Checks["jobs_flush"].Error, "no respondio a tiempo")
+	assert.Contains(t, report.Checks["panic"].Error, "sin disco")
+
+	// Testea que los nombres repetidos se rechacen
+	assert.Panics(t, func() { checker.Add("store", func(ctx context.Context) error { return nil }) })
+
+	// Testea que falte espacio cuando el minimo supera al disco
+	err := DiskSpace(t.TempDir(), math.MaxUint64)(context.Background())
+	assert.Contains(t, err.Error(), "queda poco espacio en disco")
+}
@@ -0,0 +1,398 @@
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MIMEMergePatch is the media type of RFC 7396 merge patches.
+	MIMEMergePatch = "application/merge-patch+json"
+	// MIMEJSONPatch is the media type of RFC 6902 patches.
+	MIMEJSONPatch = "application/json-patch+json"
+)
+
+var (
+	ErrInvalidPatch = errors.New("el patch no es valido")
+	ErrPathNotFound = errors.New("la ruta del patch no existe")
+	ErrTestFailed   = errors.New("la operacion test del patch fallo")
+)
+
+// Operation is a single RFC 6902 operation.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Apply applies the patch to the document according to its media type.
+func Apply(mediaType string, document []byte, patch []byte) (patched []byte, err error) {
+	switch mediaType {
+	case MIMEMergePatch:
+		return MergePatch(document, patch)
+	case MIMEJSONPatch:
+		return JSONPatch(document, patch)
+	}
+
+	return nil, fmt.Errorf("%w, tipo de patch no soportado(recibido: %s)", ErrInvalidPatch, mediaType)
+}
+
+// MergePatch applies an RFC 7396 merge patch: objects are merged recursively,
+// null removes a member and any other value replaces the target.
+func MergePatch(document []byte, patch []byte) (patched []byte, err error) {
+	target, err := decode(document)
+	if err != nil {
+		return nil, err
+	}
+
+	patchValue, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	return json.Marshal(mergeValue(target, patchValue))
+}
+
+func mergeValue(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = mergeValue(targetObject[name], value)
+	}
+
+	return targetObject
+}
+
+// JSONPatch applies an RFC 6902 patch. The operations are applied in order on
+// a copy of the document, so when one of them fails nothing is applied.
+func JSONPatch(document []byte, patch []byte) (patched []byte, err error) {
+	target, err := decode(document)
+	if err != nil {
+		return nil, err
+	}
+
+	var operations []Operation
+	err = json.Unmarshal(patch, &operations)
+	if err != nil {
+		return nil, fmt.Errorf("%w, debe ser una lista de operaciones: %v", ErrInvalidPatch, err)
+	}
+
+	for idx, operation := range operations {
+		target, err = applyOperation(target, operation)
+		if err != nil {
+			return nil, fmt.Errorf("operacion %d(%s %s): %w", idx, operation.Op, operation.Path, err)
+		}
+	}
+
+	return json.Marshal(target)
+}
+
+func applyOperation(target interface{}, operation Operation) (result interface{}, err error) {
+	path, err := parsePointer(operation.Path)
+	if err != nil {
+		return target, err
+	}
+
+	needsValue := operation.Op == "add" || operation.Op == "replace" || operation.Op == "test"
+	var value interface{}
+	if needsValue {
+		if operation.Value == nil {
+			return target, fmt.Errorf("%w, falta el campo value", ErrInvalidPatch)
+		}
+		value, err = decode(operation.Value)
+		if err != nil {
+			return target, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+	}
+
+	switch operation.Op {
+	case "add":
+		return add(target, path, value)
+	case "remove":
+		result, _, err = remove(target, path)
+		return result, err
+	case "replace":
+		result, _, err = remove(target, path)
+		if err != nil {
+			return target, err
+		}
+		return add(result, path, value)
+	case "move", "copy":
+		from, err := parsePointer(operation.From)
+		if err != nil {
+			return target, err
+		}
+		if operation.Op == "move" && isPrefix(from, path) && len(from) < len(path) {
+			return target, fmt.Errorf("%w, no se puede mover un valor dentro de si mismo", ErrInvalidPatch)
+		}
+
+		var moved interface{}
+		if operation.Op == "move" {
+			target, moved, err = remove(target, from)
+		} else {
+			moved, err = get(target, from)
+			moved = deepCopy(moved)
+		}
+		if err != nil {
+			return target, err
+		}
+		return add(target, path, moved)
+	case "test":
+		current, err := get(target, path)
+		if err != nil {
+			return target, err
+		}
+		if !equal(current, value) {
+			return target, ErrTestFailed
+		}
+		return target, nil
+	}
+
+	return target, fmt.Errorf("%w, operacion desconocida(recibido: %s)", ErrInvalidPatch, operation.Op)
+}
+
+// parsePointer splits an RFC 6901 JSON pointer in its unescaped tokens.
+func parsePointer(pointer string) (tokens []string, err error) {
+	if pointer == "" {
+		return tokens, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w, la ruta debe empezar con /(recibido: %s)", ErrInvalidPatch, pointer)
+	}
+
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.ReplaceAll(token, "~1", "/")
+		token = strings.ReplaceAll(token, "~0", "~")
+		tokens = append(tokens, token)
+	}
+
+	return tokens, nil
+}
+
+func get(target interface{}, path []string) (value interface{}, err error) {
+	value = target
+	for _, token := range path {
+		switch node := value.(type) {
+		case map[string]interface{}:
+			child, ok := node[token]
+			if !ok {
+				return nil, ErrPathNotFound
+			}
+			value = child
+		case []interface{}:
+			idx, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			value = node[idx]
+		default:
+			return nil, ErrPathNotFound
+		}
+	}
+
+	return value, nil
+}
+
+func add(target interface{}, path []string, value interface{}) (result interface{}, err error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := get(target, path[:len(path)-1])
+	if err != nil {
+		return target, err
+	}
+	token := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[token] = value
+		return target, nil
+	case []interface{}:
+		idx := len(node)
+		if token != "-" {
+			idx, err = arrayIndex(token, len(node))
+			if err != nil {
+				return target, err
+			}
+		}
+
+		node = append(node, nil)
+		copy(node[idx+1:], node[idx:])
+		node[idx] = value
+
+		return replaceParent(target, path[:len(path)-1], node)
+	}
+
+	return target, ErrPathNotFound
+}
+
+func remove(target interface{}, path []string) (result interface{}, removed interface{}, err error) {
+	if len(path) == 0 {
+		return nil, target, nil
+	}
+
+	parent, err := get(target, path[:len(path)-1])
+	if err != nil {
+		return target, nil, err
+	}
+	token := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		removed, ok := node[token]
+		if !ok {
+			return target, nil, ErrPathNotFound
+		}
+		delete(node, token)
+		return target, removed, nil
+	case []interface{}:
+		idx, err := arrayIndex(token, len(node)-1)
+		if err != nil {
+			return target, nil, err
+		}
+		removed = node[idx]
+		node = append(node[:idx:idx], node[idx+1:]...)
+
+		result, err = replaceParent(target, path[:len(path)-1], node)
+		return result, removed, err
+	}
+
+	return target, nil, ErrPathNotFound
+}
+
+// replaceParent stores a resized array back in its parent, slices can't be
+// changed in place.
+func replaceParent(target interface{}, path []string, array []interface{}) (result interface{}, err error) {
+	if len(path) == 0 {
+		return array, nil
+	}
+
+	parent, err := get(target, path[:len(path)-1])
+	if err != nil {
+		return target, err
+	}
+	token := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[token] = array
+	case []interface{}:
+		idx, err := arrayIndex(token, len(node)-1)
+		if err != nil {
+			return target, err
+		}
+		node[idx] = array
+	}
+
+	return target, nil
+}
+
+func arrayIndex(token string, max int) (idx int, err error) {
+	idx, err = strconv.Atoi(token)
+	if err != nil || idx < 0 || idx > max || (len(token) > 1 && token[0] == '0') {
+		return idx, ErrPathNotFound
+	}
+
+	return idx, nil
+}
+
+func isPrefix(prefix []string, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+
+	for idx := range prefix {
+		if prefix[idx] != path[idx] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func decode(data []byte) (value interface{}, err error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	err = decoder.Decode(&value)
+
+	return value, err
+}
+
+func deepCopy(value interface{}) interface{} {
+	switch node := value.(type) {
+	case map[string]interface{}:
+		copied := map[string]interface{}{}
+		for name, child := range node {
+			copied[name] = deepCopy(child)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(node))
+		for idx, child := range node {
+			copied[idx] = deepCopy(child)
+		}
+		return copied
+	}
+
+	return value
+}
+
+// equal compares two decoded values, numbers are compared by their value so
+// 1 and 1.0 are equal as RFC 6902 requires.
+func equal(a interface{}, b interface{}) bool {
+	numberA, okA := a.(json.Number)
+	numberB, okB := b.(json.Number)
+	if okA && okB {
+		floatA, errA := numberA.Float64()
+		floatB, errB := numberB.Float64()
+		return errA == nil && errB == nil && floatA == floatB
+	}
+
+	switch nodeA := a.(type) {
+	case map[string]interface{}:
+		nodeB, ok := b.(map[string]interface{})
+		if !ok || len(nodeA) != len(nodeB) {
+			return false
+		}
+		for name, child := range nodeA {
+			other, found := nodeB[name]
+			if !found || !equal(child, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		nodeB, ok := b.([]interface{})
+		if !ok || len(nodeA) != len(nodeB) {
+			return false
+		}
+		for idx := range nodeA {
+			if !equal(nodeA[idx], nodeB[idx]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return reflect.DeepEqual(a, b)
+}
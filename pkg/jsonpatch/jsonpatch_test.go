@@ -0,0 +1,49 @@
+package jsonpatch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergePatch(t *testing.T) {
+	document := `{"nombre":"user1","activo":true,"tags":{"a":1,"b":2}}`
+
+	patched, err := MergePatch([]byte(document), []byte(`{"activo":false,"nombre":null,"tags":{"b":null,"c":3}}`))
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"activo":false,"tags":{"a":1,"c":3}}`, string(patched))
+
+	// Testea un patch que no es json
+	_, err = MergePatch([]byte(document), []byte(`{`))
+	assert.ErrorIs(t, err, ErrInvalidPatch)
+}
+
+func TestJSONPatch(t *testing.T) {
+	document := `{"nombre":"user1","edad":25,"tags":["a","b"]}`
+
+	patch := `[
+		{"op":"test","path":"/edad","value":25.0},
+		{"op":"replace","path":"/nombre","value":"user2"},
+		{"op":"add","path":"/tags/1","value":"c"},
+		{"op":"remove","path":"/tags/0"},
+		{"op":"copy","from":"/nombre","path":"/apellido"},
+		{"op":"move","from":"/edad","path":"/age"}
+	]`
+	patched, err := JSONPatch([]byte(document), []byte(patch))
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"nombre":"user2","apellido":"user2","age":25,"tags":["c","b"]}`, string(patched))
+
+	// Testea que un test fallido cancele todo el patch
+	_, err = JSONPatch([]byte(document), []byte(`[{"op":"replace","path":"/nombre","value":"x"},{"op":"test","path":"/edad","value":30}]`))
+	assert.ErrorIs(t, err, ErrTestFailed)
+
+	// Testea rutas inexistentes y operaciones invalidas
+	_, err = JSONPatch([]byte(document), []byte(`[{"op":"remove","path":"/altura"}]`))
+	assert.ErrorIs(t, err, ErrPathNotFound)
+
+	_, err = JSONPatch([]byte(document), []byte(`[{"op":"rename","path":"/nombre"}]`))
+	assert.ErrorIs(t, err, ErrInvalidPatch)
+
+	_, err = JSONPatch([]byte(document), []byte(`{"op":"remove","path":"/nombre"}`))
+	assert.ErrorIs(t, err, ErrInvalidPatch)
+}
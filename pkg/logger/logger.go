@@ -0,0 +1,273 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Level int32
+
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+var levelNames = map[Level]string{
+	DebugLevel: "debug",
+	InfoLevel:  "info",
+	WarnLevel:  "warn",
+	ErrorLevel: "error",
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// ParseLevel reads the LOG_LEVEL values: debug, info, warn or error.
+func ParseLevel(value string) (level Level, err error) {
+	for level, name := range levelNames {
+		if strings.EqualFold(value, name) {
+			return level, nil
+		}
+	}
+
+	return InfoLevel, fmt.Errorf("el nivel de log no es valido, use debug, info, warn o error(recibido: %s)", value)
+}
+
+type Format string
+
+const (
+	JSONFormat    Format = "json"
+	ConsoleFormat Format = "console"
+)
+
+// ParseFormat reads the LOG_FORMAT values: json or console.
+func ParseFormat(value string) (format Format, err error) {
+	format = Format(strings.ToLower(value))
+	if format != JSONFormat && format != ConsoleFormat {
+		return JSONFormat, fmt.Errorf("el formato de log no es valido, use json o console(recibido: %s)", value)
+	}
+
+	return format, nil
+}
+
+// Field is a key and value added to a log line.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Err is the error field of a log line, left out when err is nil.
+func Err(err error) Field {
+	return Field{Key: "error", Value: err}
+}
+
+// Logger writes leveled lines with structured fields. The loggers returned
+// by With share the writer and the level of their parent. A nil Logger
+// discards every line.
+type Logger struct {
+	core   *core
+	fields []Field
+}
+
+type core struct {
+	mu     sync.Mutex
+	out    io.Writer
+	format Format
+	level  int32
+	now    func() time.Time
+}
+
+func New(out io.Writer, level Level, format Format) *Logger {
+	return &Logger{core: &core{out: out, format: format, level: int32(level), now: time.Now}}
+}
+
+// Nop returns a logger that discards every line, for tests and tools.
+func Nop() *Logger {
+	return New(ioutil.Discard, ErrorLevel+1, JSONFormat)
+}
+
+// With returns a logger that adds fields to every line.
+func (l *Logger) With(fields ...Field) *Logger {
+	if l == nil {
+		return nil
+	}
+
+	merged := make([]Field, 0, len(l.fields)+len(fields))
+	merged = append(merged, l.fields...)
+	merged = append(merged, fields...)
+
+	return &Logger{core: l.core, fields: merged}
+}
+
+func (l *Logger) Level() Level {
+	if l == nil {
+		return ErrorLevel + 1
+	}
+
+	return Level(atomic.LoadInt32(&l.core.level))
+}
+
+// SetLevel changes the level of the logger and of every logger sharing its
+// writer.
+func (l *Logger) SetLevel(level Level) {
+	if l == nil {
+		return
+	}
+
+	atomic.StoreInt32(&l.core.level, int32(level))
+}
+
+func (l *Logger) Enabled(level Level) bool {
+	return l != nil && level >= l.Level()
+}
+
+func (l *Logger) Debug(msg string, fields ...Field) {
+	l.log(DebugLevel, msg, fields)
+}
+
+func (l *Logger) Info(msg string, fields ...Field) {
+	l.log(InfoLevel, msg, fields)
+}
+
+func (l *Logger) Warn(msg string, fields ...Field) {
+	l.log(WarnLevel, msg, fields)
+}
+
+func (l *Logger) Error(msg string, fields ...Field) {
+	l.log(ErrorLevel, msg, fields)
+}
+
+// Log writes a line at a level chosen at runtime.
+func (l *Logger) Log(level Level, msg string, fields ...Field) {
+	l.log(level, msg, fields)
+}
+
+func (l *Logger) log(level Level, msg string, fields []Field) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	all := make([]Field, 0, len(l.fields)+len(fields))
+	all = append(all, l.fields...)
+	for _, field := range fields {
+		if field.Value != nil {
+			all = append(all, field)
+		}
+	}
+
+	var line bytes.Buffer
+	now := l.core.now()
+	if l.core.format == ConsoleFormat {
+		writeConsole(&line, now, level, msg, all)
+	} else {
+		writeJSON(&line, now, level, msg, all)
+	}
+
+	l.core.mu.Lock()
+	defer l.core.mu.Unlock()
+	_, _ = l.core.out.Write(line.Bytes())
+}
+
+func writeJSON(line *bytes.Buffer, now time.Time, level Level, msg string, fields []Field) {
+	line.WriteString(`{"time":`)
+	writeJSONValue(line, now.UTC().Format(time.RFC3339Nano))
+	line.WriteString(`,"level":`)
+	writeJSONValue(line, level.String())
+	line.WriteString(`,"msg":`)
+	writeJSONValue(line, msg)
+
+	for _, field := range fields {
+		line.WriteByte(',')
+		writeJSONValue(line, field.Key)
+		line.WriteByte(':')
+		writeJSONValue(line, plain(field.Value))
+	}
+
+	line.WriteString("}\n")
+}
+
+func writeJSONValue(line *bytes.Buffer, value interface{}) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		encoded, _ = json.Marshal(fmt.Sprint(value))
+	}
+	line.Write(encoded)
+}
+
+func writeConsole(line *bytes.Buffer, now time.Time, level Level, msg string, fields []Field) {
+	line.WriteString(now.Format("2006-01-02T15:04:05.000Z07:00"))
+	line.WriteByte(' ')
+	line.WriteString(strings.ToUpper(level.String()))
+	line.WriteByte(' ')
+	line.WriteString(msg)
+
+	for _, field := range fields {
+		line.WriteByte(' ')
+		line.WriteString(field.Key)
+		line.WriteByte('=')
+
+		value := plain(field.Value)
+		text, isText := value.(string)
+		if !isText {
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				encoded = []byte(fmt.Sprint(value))
+			}
+			text = string(encoded)
+		}
+		if isText && strings.ContainsAny(text, " =\"") {
+			text = fmt.Sprintf("%q", text)
+		}
+		line.WriteString(text)
+	}
+
+	line.WriteByte('\n')
+}
+
+// plain turns errors and durations into text so that both formats show them
+// the same way.
+func plain(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case error:
+		return typed.Error()
+	case time.Duration:
+		return typed.String()
+	}
+
+	return value
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the logger, like the one of a
+// request with its id.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, or fallback when there is
+// none.
+func FromContext(ctx context.Context, fallback *Logger) *Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(contextKey{}).(*Logger); ok && l != nil {
+			return l
+		}
+	}
+
+	return fallback
+}
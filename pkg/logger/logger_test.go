@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger(t *testing.T) {
+	now := time.Date(2021, 12, 22, 10, 0, 0, 0, time.UTC)
+
+	var out bytes.Buffer
+	log := New(&out, InfoLevel, JSONFormat)
+	log.core.now = func() time.Time { return now }
+
+	// Testea que las lineas por debajo del nivel se descarten
+	log.Debug("descartada")
+	assert.Equal(t, 0, out.Len())
+
+	// Testea que los campos de With se agreguen a cada linea en formato JSON
+	requestLog := log.With(F("request_id", "abc"))
+	requestLog.Error("fallo", F("user_id", 7), Err(errors.New("sin disco")))
+
+	var line map[string]interface{}
+	err := json.Unmarshal(out.Bytes(), &line)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"time": "2021-12-22T10:00:00Z", "level": "error", "msg": "fallo",
+		"request_id": "abc", "user_id": float64(7), "error": "sin disco",
+	}, line)
+
+	// Testea que SetLevel alcance a los loggers derivados
+	out.Reset()
+	log.SetLevel(DebugLevel)
+	requestLog.Debug("visible")
+	assert.Contains(t, out.String(), `"msg":"visible"`)
+
+	// Testea el formato de consola
+	out.Reset()
+	console := New(&out, DebugLevel, ConsoleFormat)
+	console.core.now = func() time.Time { return now }
+	console.Info("solicitud", F("route", "/v2/users/:id"), F("latency", 1500*time.Microsecond), F("error", "no existe"))
+	assert.Equal(t, "2021-12-22T10:00:00.000Z INFO solicitud route=/v2/users/:id latency=1.5ms error=\"no existe\"\n", out.String())
+
+	// Testea que el logger viaje en el contexto
+	ctx := NewContext(context.Background(), requestLog)
+	assert.Equal(t, requestLog, FromContext(ctx, log))
+	assert.Equal(t, log, FromContext(context.Background(), log))
+
+	// Testea que un logger nil descarte las lineas
+	var nilLog *Logger
+	nilLog.With(F("a", 1)).Info("nada")
+
+	// Testea que un error nil no agregue el campo
+	out.Reset()
+	console.Info("ok", Err(nil))
+	assert.Equal(t, "2021-12-22T10:00:00.000Z INFO ok\n", out.String())
+
+	_, err = ParseLevel("verbose")
+	assert.NotNil(t, err)
+	level, err := ParseLevel("WARN")
+	assert.Nil(t, err)
+	assert.Equal(t, WarnLevel, level)
+}
@@ -0,0 +1,281 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the media type of the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var (
+	// DurationBuckets are the upper bounds, in seconds, of latency histograms.
+	DurationBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	// SizeBuckets are the upper bounds, in bytes, of size histograms.
+	SizeBuckets = []float64{256, 1024, 4096, 16384, 65536, 262144, 1048576, 4194304, 16777216}
+)
+
+// Registry holds the metrics of the service and writes them in the
+// Prometheus text exposition format.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+	names   map[string]bool
+}
+
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{names: map[string]bool{}}
+}
+
+func (r *Registry) register(name string, m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.names[name] {
+		panic(fmt.Sprintf("la metrica %s ya fue registrada", name))
+	}
+	r.names[name] = true
+	r.metrics = append(r.metrics, m)
+}
+
+// Counter registers a counter with the given label names.
+func (r *Registry) Counter(name string, help string, labels ...string) *CounterVec {
+	counter := &CounterVec{vec: newVec(name, help, "counter", labels)}
+	r.register(name, counter)
+
+	return counter
+}
+
+// Gauge registers a gauge with the given label names.
+func (r *Registry) Gauge(name string, help string, labels ...string) *GaugeVec {
+	gauge := &GaugeVec{vec: newVec(name, help, "gauge", labels)}
+	r.register(name, gauge)
+
+	return gauge
+}
+
+// Histogram registers a histogram with the given bucket upper bounds, in
+// increasing order, and label names.
+func (r *Registry) Histogram(name string, help string, buckets []float64, labels ...string) *HistogramVec {
+	histogram := &HistogramVec{vec: newVec(name, help, "histogram", labels), buckets: buckets}
+	r.register(name, histogram)
+
+	return histogram
+}
+
+// Write writes every metric in the text exposition format.
+func (r *Registry) Write(w io.Writer) (err error) {
+	r.mu.Lock()
+	metrics := append([]metric{}, r.metrics...)
+	r.mu.Unlock()
+
+	buffered := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(buffered)
+	}
+
+	return buffered.Flush()
+}
+
+// ServeHTTP serves the metrics, to be scraped from /metrics.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	_ = r.Write(w)
+}
+
+// vec keeps the series of a metric by their label values.
+type vec struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+	counts      []uint64
+	sum         float64
+	count       uint64
+}
+
+func newVec(name string, help string, kind string, labels []string) vec {
+	return vec{name: name, help: help, kind: kind, labels: labels, series: map[string]*series{}}
+}
+
+// get returns the series with the label values, creating it. The caller
+// holds the lock.
+func (v *vec) get(labelValues []string) *series {
+	if len(labelValues) != len(v.labels) {
+		panic(fmt.Sprintf("la metrica %s espera %d etiquetas(recibidas: %d)", v.name, len(v.labels), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+	s, found := v.series[key]
+	if !found {
+		s = &series{labelValues: append([]string{}, labelValues...)}
+		v.series[key] = s
+	}
+
+	return s
+}
+
+// sorted returns a copy of the series ordered by their label values.
+func (v *vec) sorted() []series {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	sorted := make([]series, 0, len(keys))
+	for _, key := range keys {
+		s := *v.series[key]
+		s.counts = append([]uint64{}, s.counts...)
+		sorted = append(sorted, s)
+	}
+
+	return sorted
+}
+
+func (v *vec) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", v.name, strings.NewReplacer("\\", `\\`, "\n", `\n`).Replace(v.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", v.name, v.kind)
+}
+
+func (v *vec) writeSample(w *bufio.Writer, name string, labelValues []string, extraLabel string, extraValue string, value float64) {
+	w.WriteString(name)
+
+	pairs := make([]string, 0, len(labelValues)+1)
+	for idx, labelValue := range labelValues {
+		pairs = append(pairs, v.labels[idx]+`="`+escapeLabel(labelValue)+`"`)
+	}
+	if extraLabel != "" {
+		pairs = append(pairs, extraLabel+`="`+extraValue+`"`)
+	}
+	if len(pairs) > 0 {
+		w.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+
+	w.WriteString(" " + formatValue(value) + "\n")
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer("\\", `\\`, "\"", `\"`, "\n", `\n`).Replace(value)
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// CounterVec is a value that only goes up, like the requests served.
+type CounterVec struct {
+	vec
+}
+
+// Add adds delta, which must not be negative, to the series with the label
+// values.
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		panic(fmt.Sprintf("el contador %s no puede disminuir", c.name))
+	}
+
+	c.mu.Lock()
+	c.get(labelValues).value += delta
+	c.mu.Unlock()
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.writeHeader(w)
+	for _, s := range c.sorted() {
+		c.writeSample(w, c.name, s.labelValues, "", "", s.value)
+	}
+}
+
+// GaugeVec is a value that goes up and down, like the users stored.
+type GaugeVec struct {
+	vec
+}
+
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	g.mu.Lock()
+	g.get(labelValues).value = value
+	g.mu.Unlock()
+}
+
+func (g *GaugeVec) Add(delta float64, labelValues ...string) {
+	g.mu.Lock()
+	g.get(labelValues).value += delta
+	g.mu.Unlock()
+}
+
+func (g *GaugeVec) write(w *bufio.Writer) {
+	g.writeHeader(w)
+	for _, s := range g.sorted() {
+		g.writeSample(w, g.name, s.labelValues, "", "", s.value)
+	}
+}
+
+// HistogramVec counts observations, like latencies, in buckets.
+type HistogramVec struct {
+	vec
+	buckets []float64
+}
+
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.get(labelValues)
+	if s.counts == nil {
+		s.counts = make([]uint64, len(h.buckets))
+	}
+
+	for idx, bound := range h.buckets {
+		if value <= bound {
+			s.counts[idx]++
+		}
+	}
+	s.sum += value
+	s.count++
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.writeHeader(w)
+	for _, s := range h.sorted() {
+		for idx, bound := range h.buckets {
+			h.writeSample(w, h.name+"_bucket", s.labelValues, "le", formatValue(bound), float64(s.counts[idx]))
+		}
+		h.writeSample(w, h.name+"_bucket", s.labelValues, "le", "+Inf", float64(s.count))
+		h.writeSample(w, h.name+"_sum", s.labelValues, "", "", s.sum)
+		h.writeSample(w, h.name+"_count", s.labelValues, "", "", float64(s.count))
+	}
+}
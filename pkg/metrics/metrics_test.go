@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	requests := registry.Counter("http_requests_total", "Requests served.", "route", "status")
+	users := registry.Gauge("users", "Users stored.")
+	latency := registry.Histogram("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+
+	requests.Inc("/v2/users/:id", "200")
+	requests.Inc("/v2/users/:id", "200")
+	requests.Inc(`/a"b`, "404")
+	users.Set(10)
+	users.Add(-1)
+	latency.Observe(0.05, "/v2/users")
+	latency.Observe(0.5, "/v2/users")
+	latency.Observe(3, "/v2/users")
+
+	var out bytes.Buffer
+	err := registry.Write(&out)
+	assert.Nil(t, err)
+
+	// Testea el formato de exposicion de Prometheus
+	expected := `# HELP http_requests_total Requests served.
+# TYPE http_requests_total counter
+http_requests_total{route="/a\"b",status="404"} 1
+http_requests_total{route="/v2/users/:id",status="200"} 2
+# HELP users Users stored.
+# TYPE users gauge
+users 9
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/v2/users",le="0.1"} 1
+latency_seconds_bucket{route="/v2/users",le="1"} 2
+latency_seconds_bucket{route="/v2/users",le="+Inf"} 3
+latency_seconds_sum{route="/v2/users"} 3.55
+latency_seconds_count{route="/v2/users"} 3
+`
+	assert.Equal(t, expected, out.String())
+
+	// Testea que los contadores no puedan disminuir ni registrarse dos veces
+	assert.Panics(t, func() { requests.Add(-1, "/", "200") })
+	assert.Panics(t, func() { registry.Gauge("users", "Users stored.") })
+}
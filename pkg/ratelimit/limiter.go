@@ -0,0 +1,225 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Class string
+
+const (
+	Read  Class = "read"
+	Write Class = "write"
+
+	DefaultTier = "default"
+)
+
+// Limit describes a token bucket: Requests tokens are refilled every Period,
+// and at most Burst tokens can be accumulated.
+type Limit struct {
+	Requests int64
+	Period   time.Duration
+	Burst    int64
+}
+
+// Tier groups the limits applied to read and write routes for a set of keys.
+type Tier struct {
+	Name  string
+	Read  Limit
+	Write Limit
+}
+
+// Result is the outcome of a call to Allow, used to fill the RateLimit-* headers.
+type Result struct {
+	Allowed    bool
+	Limit      int64
+	Remaining  int64
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+type Limiter struct {
+	mu        sync.Mutex
+	tiers     map[string]Tier
+	keyTiers  map[string]string
+	buckets   map[string]*bucket
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+func CreateLimiter(tiers []Tier, keyTiers map[string]string) *Limiter {
+	newLimiter := &Limiter{
+		buckets: map[string]*bucket{},
+		now:     time.Now,
+	}
+
+	newLimiter.Reconfigure(tiers, keyTiers)
+
+	return newLimiter
+}
+
+// Reconfigure replaces the tiers and the keys assigned to them while the
+// limiter is in use. The buckets are kept, the ones over the new burst are
+// capped on their next request.
+func (l *Limiter) Reconfigure(tiers []Tier, keyTiers map[string]string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.tiers = map[string]Tier{}
+	for _, tier := range tiers {
+		l.tiers[tier.Name] = tier
+	}
+	l.keyTiers = map[string]string{}
+	for key, tier := range keyTiers {
+		l.keyTiers[key] = tier
+	}
+}
+
+// TierFor returns the tier assigned to the key, falling back to the default tier.
+func (l *Limiter) TierFor(key string) (tier Tier, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.tierFor(key)
+}
+
+// HasKey tells whether the key was assigned a tier.
+func (l *Limiter) HasKey(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, found := l.keyTiers[key]
+
+	return found
+}
+
+func (l *Limiter) tierFor(key string) (tier Tier, ok bool) {
+	tierName, found := l.keyTiers[key]
+	if !found {
+		tierName = DefaultTier
+	}
+
+	tier, ok = l.tiers[tierName]
+	if !ok && tierName != DefaultTier {
+		tier, ok = l.tiers[DefaultTier]
+	}
+
+	return tier, ok
+}
+
+// Allow consumes a token from the bucket identified by key and class.
+// Keys without a configured tier (and no default tier) are never limited.
+func (l *Limiter) Allow(key string, class Class) (result Result) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	tier, ok := l.tierFor(key)
+	if !ok {
+		result.Allowed = true
+		return result
+	}
+
+	limit := tier.Read
+	if class == Write {
+		limit = tier.Write
+	}
+	if limit.Requests <= 0 || limit.Period <= 0 {
+		result.Allowed = true
+		return result
+	}
+
+	burst := limit.Burst
+	if burst <= 0 {
+		burst = limit.Requests
+	}
+	ratePerSecond := float64(limit.Requests) / limit.Period.Seconds()
+
+	now := l.now()
+	l.sweep(now)
+
+	bucketKey := string(class) + ":" + key
+	b, found := l.buckets[bucketKey]
+	if !found {
+		b = &bucket{tokens: float64(burst), lastSeen: now}
+		l.buckets[bucketKey] = b
+	}
+
+	elapsed := now.Sub(b.lastSeen).Seconds()
+	b.tokens = math.Min(float64(burst), b.tokens+elapsed*ratePerSecond)
+	b.lastSeen = now
+
+	result.Limit = burst
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		missing := 1 - b.tokens
+		result.RetryAfter = time.Duration(math.Ceil(missing/ratePerSecond)) * time.Second
+	}
+
+	result.Remaining = int64(math.Floor(b.tokens))
+	result.Reset = time.Duration(math.Ceil((float64(burst)-b.tokens)/ratePerSecond)) * time.Second
+
+	return result
+}
+
+// sweep drops buckets that have been idle long enough to be full again,
+// so that clients rotating IPs can't grow the map without bound.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) > 10*time.Minute {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// ParseLimit parses limits written as "requests/period[:burst]", e.g. "60/1m" or "10/1s:20".
+func ParseLimit(value string) (limit Limit, err error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return limit, nil
+	}
+
+	rateAndBurst := strings.SplitN(value, ":", 2)
+	requestsAndPeriod := strings.SplitN(rateAndBurst[0], "/", 2)
+	if len(requestsAndPeriod) != 2 {
+		err = fmt.Errorf("limite invalido %q, el formato esperado es solicitudes/periodo[:rafaga]", value)
+		return limit, err
+	}
+
+	limit.Requests, err = strconv.ParseInt(requestsAndPeriod[0], 10, 64)
+	if err != nil || limit.Requests <= 0 {
+		err = fmt.Errorf("limite invalido %q, las solicitudes deben ser un entero mayor a cero", value)
+		return limit, err
+	}
+
+	limit.Period, err = time.ParseDuration(requestsAndPeriod[1])
+	if err != nil || limit.Period <= 0 {
+		err = fmt.Errorf("limite invalido %q, el periodo debe ser una duracion mayor a cero", value)
+		return limit, err
+	}
+
+	if len(rateAndBurst) == 2 {
+		limit.Burst, err = strconv.ParseInt(rateAndBurst[1], 10, 64)
+		if err != nil || limit.Burst <= 0 {
+			err = fmt.Errorf("limite invalido %q, la rafaga debe ser un entero mayor a cero", value)
+			return limit, err
+		}
+	}
+
+	return limit, nil
+}
@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllow(t *testing.T) {
+	now := time.Date(2021, 12, 22, 10, 0, 0, 0, time.UTC)
+	tiers := []Tier{
+		{Name: DefaultTier, Read: Limit{Requests: 2, Period: time.Minute}, Write: Limit{Requests: 1, Period: time.Minute}},
+		{Name: "batch", Read: Limit{Requests: 10, Period: time.Minute}, Write: Limit{Requests: 5, Period: time.Minute}},
+	}
+
+	limiter := CreateLimiter(tiers, map[string]string{"token-batch": "batch"})
+	limiter.now = func() time.Time { return now }
+
+	// Testea que las escrituras se agoten sin afectar las lecturas
+	result := limiter.Allow("10.0.0.1", Write)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(0), result.Remaining)
+
+	result = limiter.Allow("10.0.0.1", Write)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Minute, result.RetryAfter)
+
+	result = limiter.Allow("10.0.0.1", Read)
+	assert.True(t, result.Allowed)
+
+	// Testea que cada clave tenga su propio bucket y su nivel
+	result = limiter.Allow("token-batch", Write)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(5), result.Limit)
+
+	// Testea que los tokens se recarguen con el tiempo
+	now = now.Add(time.Minute)
+	result = limiter.Allow("10.0.0.1", Write)
+	assert.True(t, result.Allowed)
+
+	// Testea que al reconfigurar se apliquen los nuevos limites sin reiniciar los buckets
+	limiter.Reconfigure([]Tier{
+		{Name: DefaultTier, Read: Limit{Requests: 2, Period: time.Minute}, Write: Limit{Requests: 3, Period: time.Minute}},
+	}, nil)
+	result = limiter.Allow("token-batch", Write)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(3), result.Limit)
+	assert.Equal(t, int64(2), result.Remaining)
+
+	result = limiter.Allow("10.0.0.1", Write)
+	assert.False(t, result.Allowed)
+}
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ParseLimit("10/1s:20")
+	assert.Nil(t, err)
+	assert.Equal(t, Limit{Requests: 10, Period: time.Second, Burst: 20}, limit)
+
+	_, err = ParseLimit("10")
+	assert.Error(t, err)
+
+	_, err = ParseLimit("0/1m")
+	assert.Error(t, err)
+}
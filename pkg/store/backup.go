@@ -0,0 +1,66 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// backupLayout is the time in the name of the backups.
+const backupLayout = "20060102-150405"
+
+// Backup copies data, the document of the store kept in name, to a file of
+// dir named after the store and now. The stores hold personal data, so only
+// their owner can read the backups.
+func Backup(dir string, name string, data []byte, now time.Time) (backup string, err error) {
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return "", err
+	}
+
+	backup = filepath.Join(dir, fmt.Sprintf("%s.%s.bak", filepath.Base(name), now.Format(backupLayout)))
+	err = os.WriteFile(backup, data, 0600)
+	if err != nil {
+		return "", err
+	}
+
+	return backup, nil
+}
+
+// PruneBackups removes the backups of the store kept in name made in dir
+// before the given time and returns them. A dir that does not exist holds
+// none.
+func PruneBackups(dir string, name string, before time.Time) (removed []string, err error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := filepath.Base(name) + "."
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), prefix) || !strings.HasSuffix(entry.Name(), ".bak") {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(entry.Name(), prefix), ".bak")
+
+		madeAt, parseErr := time.ParseInLocation(backupLayout, stamp, before.Location())
+		if parseErr != nil || !madeAt.Before(before) {
+			continue
+		}
+
+		backup := filepath.Join(dir, entry.Name())
+		err = os.Remove(backup)
+		if err != nil {
+			return removed, err
+		}
+		removed = append(removed, backup)
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Checker is implemented by the stores that can tell whether they are
+// readable and writable without changing their data.
+type Checker interface {
+	Check(ctx context.Context, document interface{}) (err error)
+}
+
+// Check probes s, decoding its data into document, a pointer to the type the
+// store keeps: a Checker checks itself, any other store is only read.
+func Check(ctx context.Context, s Store, document interface{}) (err error) {
+	if checker, ok := s.(Checker); ok {
+		return checker.Check(ctx, document)
+	}
+
+	return WithContext(s).ReadContext(ctx, document)
+}
+
+// Check decodes the file into document, then creates and removes a file in
+// its directory. A file not written yet reads as an empty document. Writes
+// replace the file with one renamed over it, so it is the directory, not the
+// file, that has to be writable.
+func (fs *FileStore) Check(ctx context.Context, document interface{}) (err error) {
+	file, err := readFile(ctx, fs.FileName)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no se puede leer %s: %w", fs.FileName, err)
+	}
+	if err == nil {
+		if !json.Valid(file) {
+			return fmt.Errorf("el archivo %s no es JSON valido", fs.FileName)
+		}
+		err = json.Unmarshal(file, document)
+		if err != nil {
+			return fmt.Errorf("el archivo %s no tiene el formato esperado: %w", fs.FileName, err)
+		}
+	}
+
+	probe, err := os.CreateTemp(filepath.Dir(fs.FileName), ".check-*")
+	if err != nil {
+		return fmt.Errorf("no se puede escribir %s: %w", fs.FileName, err)
+	}
+	probe.Close()
+
+	return os.Remove(probe.Name())
+}
+
+func (s *instrumentedStore) Check(ctx context.Context, document interface{}) (err error) {
+	return Check(ctx, s.next, document)
+}
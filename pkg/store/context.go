@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrTimeout  = errors.New("se agoto el tiempo de la solicitud")
+	ErrCanceled = errors.New("la solicitud fue cancelada")
+)
+
+// ContextStore is a Store whose reads and writes take the context of the
+// request they serve, which carries its deadline, span and logger. A read or
+// write whose context already ended fails without touching the data.
+type ContextStore interface {
+	Store
+	ReadContext(ctx context.Context, data interface{}) (err error)
+	WriteContext(ctx context.Context, data interface{}) (err error)
+}
+
+// WithContext returns s as a ContextStore. The stores that take no context,
+// like the test doubles, are only called while the context is alive.
+func WithContext(s Store) ContextStore {
+	if cs, ok := s.(ContextStore); ok {
+		return cs
+	}
+
+	return contextAdapter{Store: s}
+}
+
+type contextAdapter struct {
+	Store
+}
+
+func (a contextAdapter) ReadContext(ctx context.Context, data interface{}) (err error) {
+	err = CheckContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return a.Read(data)
+}
+
+func (a contextAdapter) WriteContext(ctx context.Context, data interface{}) (err error) {
+	err = CheckContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return a.Write(data)
+}
+
+// contextError reports a context that ended with a message for the client,
+// while errors.Is still finds context.DeadlineExceeded or context.Canceled.
+type contextError struct {
+	message error
+	cause   error
+}
+
+func (e *contextError) Error() string {
+	return e.message.Error()
+}
+
+func (e *contextError) Unwrap() error {
+	return e.cause
+}
+
+// Is matches ErrTimeout and ErrCanceled too.
+func (e *contextError) Is(target error) bool {
+	return target == e.message
+}
+
+// CheckContext returns nil while ctx is alive, ErrTimeout once its deadline
+// passed and ErrCanceled once it was canceled, like by a client that left.
+func CheckContext(ctx context.Context) (err error) {
+	switch ctx.Err() {
+	case nil:
+		return nil
+	case context.DeadlineExceeded:
+		return &contextError{message: ErrTimeout, cause: context.DeadlineExceeded}
+	}
+
+	return &contextError{message: ErrCanceled, cause: ctx.Err()}
+}
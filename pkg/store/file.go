@@ -1,9 +1,15 @@
 package store
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/logger"
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/trace"
 )
 
 type Store interface {
@@ -19,15 +25,33 @@ const (
 
 type FileStore struct {
 	FileName string
+	Logger   *logger.Logger
 }
 
 func (fs *FileStore) Read(data interface{}) (err error) {
-	file, err := os.ReadFile(fs.FileName)
+	return fs.ReadContext(context.Background(), data)
+}
+
+func (fs *FileStore) ReadContext(ctx context.Context, data interface{}) (err error) {
+	ctx, span := trace.Start(ctx, "FileStore.Read")
+	span.SetAttribute("file", fs.FileName)
+	start := time.Now()
+	var file []byte
+	defer func() {
+		span.SetAttribute("bytes", len(file))
+		span.RecordError(err)
+		span.End()
+		fs.logger(ctx).Debug("lectura del archivo", logger.F("file", fs.FileName), logger.F("bytes", len(file)), logger.F("latency", time.Since(start)), logger.Err(err))
+	}()
+
+	// A file not written yet holds an empty document, whatever its shape, so
+	// data is left as it is.
+	file, err = readFile(ctx, fs.FileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
 	if err != nil {
-		if !errors.Is(err, os.ErrNotExist) {
-			return err
-		}
-		file = []byte("[]")
+		return err
 	}
 
 	err = json.Unmarshal(file, data)
@@ -36,25 +60,136 @@ func (fs *FileStore) Read(data interface{}) (err error) {
 }
 
 func (fs *FileStore) Write(data interface{}) (err error) {
-	fileData, err := json.MarshalIndent(data, "", "  ")
+	return fs.WriteContext(context.Background(), data)
+}
+
+func (fs *FileStore) WriteContext(ctx context.Context, data interface{}) (err error) {
+	ctx, span := trace.Start(ctx, "FileStore.Write")
+	span.SetAttribute("file", fs.FileName)
+	start := time.Now()
+	var fileData []byte
+	defer func() {
+		span.SetAttribute("bytes", len(fileData))
+		span.RecordError(err)
+		span.End()
+		fs.logger(ctx).Debug("escritura del archivo", logger.F("file", fs.FileName), logger.F("bytes", len(fileData)), logger.F("latency", time.Since(start)), logger.Err(err))
+	}()
+
+	fileData, err = json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Once started the write is not abandoned, so the context is only checked
+	// before.
+	err = CheckContext(ctx)
 	if err != nil {
 		return err
 	}
-	f, err := os.OpenFile(fs.FileName, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0755)
+
+	return writeFile(fs.FileName, fileData)
+}
+
+// writeFile replaces name with data through a synced temporary file renamed
+// over it, so a crash or a kill in the middle of a write leaves either the
+// old file or the new one, never a truncated one. The directory is synced
+// after the rename, so nothing is left buffered once it returns.
+func writeFile(name string, data []byte) (err error) {
+	mode := os.FileMode(0755)
+	if info, statErr := os.Stat(name); statErr == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	_, err = tmp.Write(data)
 	if err != nil {
 		return err
 	}
-	_, err = f.Write(fileData)
+	err = tmp.Chmod(mode)
 	if err != nil {
 		return err
 	}
-	return nil
+	err = tmp.Sync()
+	if err != nil {
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmp.Name(), name)
+	if err != nil {
+		return err
+	}
+
+	return syncDir(filepath.Dir(name))
+}
+
+// syncDir flushes the entries of dir, like the name of a file just renamed
+// into it.
+func syncDir(dir string) (err error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return f.Sync()
+}
+
+// readFile reads name unless ctx ends first. The read of a slow disk can't be
+// interrupted, it goes on in the background and its result is dropped.
+func readFile(ctx context.Context, name string) (file []byte, err error) {
+	err = CheckContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if ctx.Done() == nil {
+		return os.ReadFile(name)
+	}
+
+	type result struct {
+		file []byte
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		file, err := os.ReadFile(name)
+		done <- result{file: file, err: err}
+	}()
+
+	select {
+	case read := <-done:
+		return read.file, read.err
+	case <-ctx.Done():
+		return nil, CheckContext(ctx)
+	}
+}
+
+// logger returns the logger of the request in ctx, or the one of the store.
+func (fs *FileStore) logger(ctx context.Context) *logger.Logger {
+	if requestLogger := logger.FromContext(ctx, nil); requestLogger != nil {
+		return requestLogger.With(logger.F("component", "store"))
+	}
+
+	return fs.Logger
 }
 
-func NewStorage(storageType Type, fileName string) Store {
+func NewStorage(storageType Type, fileName string, log *logger.Logger) Store {
 	switch storageType {
 	case FileType:
-		return &FileStore{FileName: fileName}
+		return &FileStore{FileName: fileName, Logger: log.With(logger.F("component", "store"))}
 	}
 
 	return nil
@@ -0,0 +1,156 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// myDb counts the calls of a store that takes no context.
+type myDb struct {
+	reads  int
+	writes int
+}
+
+func (db *myDb) Read(data interface{}) (err error) {
+	db.reads++
+	return nil
+}
+
+func (db *myDb) Write(data interface{}) (err error) {
+	db.writes++
+	return nil
+}
+
+func TestFileStoreContext(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "users.json")
+	fs := NewStorage(FileType, fileName, logger.Nop()).(*FileStore)
+
+	err := fs.WriteContext(context.Background(), []int{1, 2})
+	assert.Nil(t, err)
+
+	// Testea que una solicitud cancelada no escriba el archivo
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = fs.WriteContext(canceled, []int{3})
+	assert.True(t, errors.Is(err, ErrCanceled))
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, "la solicitud fue cancelada", err.Error())
+
+	var data []int
+	err = fs.ReadContext(canceled, &data)
+	assert.True(t, errors.Is(err, ErrCanceled))
+	assert.Nil(t, data)
+
+	// Testea que una solicitud vencida falle con ErrTimeout
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+	err = fs.ReadContext(expired, &data)
+	assert.True(t, errors.Is(err, ErrTimeout))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	// Testea que con la solicitud viva se lea lo escrito antes de la cancelacion
+	alive, cancelAlive := context.WithTimeout(context.Background(), time.Minute)
+	defer cancelAlive()
+	err = fs.ReadContext(alive, &data)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 2}, data)
+
+	file, err := os.ReadFile(fileName)
+	assert.Nil(t, err)
+	assert.Contains(t, string(file), "2")
+
+	// Testea que el adaptador respete el contexto de los stores sin contexto
+	db := &myDb{}
+	adapted := WithContext(db)
+	assert.Nil(t, adapted.ReadContext(context.Background(), &data))
+	assert.True(t, errors.Is(adapted.WriteContext(canceled, data), ErrCanceled))
+	assert.Equal(t, 1, db.reads)
+	assert.Equal(t, 0, db.writes)
+	assert.Equal(t, fs, WithContext(fs))
+}
+
+func TestFileStoreMissingFile(t *testing.T) {
+	fs := NewStorage(FileType, filepath.Join(t.TempDir(), "jobs.json"), logger.Nop())
+
+	// Testea que un archivo que aun no existe se lea como un documento vacio,
+	// sea cual sea su forma
+	var document struct {
+		Jobs []int `json:"jobs"`
+	}
+	assert.Nil(t, fs.Read(&document))
+	assert.Nil(t, document.Jobs)
+
+	var list []int
+	assert.Nil(t, fs.Read(&list))
+	assert.Nil(t, list)
+}
+
+func TestFileStoreCheck(t *testing.T) {
+	dir := t.TempDir()
+	fs := NewStorage(FileType, filepath.Join(dir, "users.json"), logger.Nop()).(*FileStore)
+
+	// Testea que un archivo que aun no existe se pueda crear
+	assert.Nil(t, Check(context.Background(), fs, &[]int{}))
+	entries, _ := os.ReadDir(dir)
+	assert.Equal(t, 0, len(entries))
+
+	// Testea que un archivo de solo lectura se pueda reemplazar, como hacen
+	// las escrituras, sin dejar archivos de la prueba
+	assert.Nil(t, fs.Write([]int{1}))
+	assert.Nil(t, os.Chmod(fs.FileName, 0444))
+	assert.Nil(t, Check(context.Background(), fs, &[]int{}))
+	entries, _ = os.ReadDir(dir)
+	assert.Equal(t, 1, len(entries))
+	assert.Nil(t, fs.Write([]int{2}))
+
+	// Testea que un archivo corrupto falle sin ser modificado
+	assert.Nil(t, os.WriteFile(fs.FileName, []byte(`[{"id": 1`), 0644))
+	err := Check(context.Background(), fs, &[]int{})
+	assert.Contains(t, err.Error(), "no es JSON valido")
+	file, _ := os.ReadFile(fs.FileName)
+	assert.Equal(t, `[{"id": 1`, string(file))
+
+	// Testea que un archivo con otro formato que el del almacenamiento falle
+	assert.Nil(t, os.WriteFile(fs.FileName, []byte(`{"users": []}`), 0644))
+	err = Check(context.Background(), fs, &[]int{})
+	assert.Contains(t, err.Error(), "no tiene el formato esperado")
+
+	// Testea que los stores sin Check solo se lean
+	db := &myDb{}
+	assert.Nil(t, Check(context.Background(), db, &[]int{}))
+	assert.Equal(t, 1, db.reads)
+	assert.Equal(t, 0, db.writes)
+}
+
+func TestBackups(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "backups")
+	now := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	old, err := Backup(dir, "data/users.json", []byte(`[1]`), now.Add(-48*time.Hour))
+	assert.Nil(t, err)
+	recent, err := Backup(dir, "data/users.json", []byte(`[2]`), now)
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "users.json.20220601-100000.bak"), recent)
+	info, _ := os.Stat(recent)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "jobs.json.20200101-000000.bak"), nil, 0600))
+
+	// Testea que se borren solo las copias del almacenamiento pasada la retencion
+	removed, err := PruneBackups(dir, "users.json", now.Add(-24*time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{old}, removed)
+	entries, _ := os.ReadDir(dir)
+	assert.Equal(t, 2, len(entries))
+
+	removed, err = PruneBackups(filepath.Join(dir, "no-existe"), "users.json", now)
+	assert.Nil(t, err)
+	assert.Empty(t, removed)
+}
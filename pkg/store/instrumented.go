@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/EdigiraldoML/go-web-arquitecture/pkg/metrics"
+)
+
+// Sizer is implemented by the stores that can tell how many bytes they hold.
+type Sizer interface {
+	Size() (size int64, err error)
+}
+
+// Size returns the size of the file, zero when it was not written yet.
+func (fs *FileStore) Size() (size int64, err error) {
+	info, err := os.Stat(fs.FileName)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return info.Size(), nil
+}
+
+// Metrics are the metrics shared by the instrumented stores, labeled with
+// the name of each store and the operation, read or write.
+type Metrics struct {
+	Duration *metrics.HistogramVec
+	Bytes    *metrics.HistogramVec
+	Errors   *metrics.CounterVec
+}
+
+func NewMetrics(registry *metrics.Registry) Metrics {
+	return Metrics{
+		Duration: registry.Histogram("store_operation_duration_seconds", "Duration of the reads and writes of the stores.", metrics.DurationBuckets, "store", "operation"),
+		Bytes:    registry.Histogram("store_operation_bytes", "Size of the data read or written by the stores.", metrics.SizeBuckets, "store", "operation"),
+		Errors:   registry.Counter("store_errors_total", "Failed reads and writes of the stores.", "store", "operation"),
+	}
+}
+
+type instrumentedStore struct {
+	next    ContextStore
+	sizer   Sizer
+	name    string
+	metrics Metrics
+}
+
+// Instrument returns a store that records the duration, errors and, for the
+// stores that are a Sizer, the size of each operation of next.
+func Instrument(next Store, name string, m Metrics) ContextStore {
+	sizer, _ := next.(Sizer)
+
+	return &instrumentedStore{next: WithContext(next), sizer: sizer, name: name, metrics: m}
+}
+
+func (s *instrumentedStore) Read(data interface{}) (err error) {
+	return s.ReadContext(context.Background(), data)
+}
+
+func (s *instrumentedStore) ReadContext(ctx context.Context, data interface{}) (err error) {
+	start := time.Now()
+	err = s.next.ReadContext(ctx, data)
+	s.observe("read", start, err)
+
+	return err
+}
+
+func (s *instrumentedStore) Write(data interface{}) (err error) {
+	return s.WriteContext(context.Background(), data)
+}
+
+func (s *instrumentedStore) WriteContext(ctx context.Context, data interface{}) (err error) {
+	start := time.Now()
+	err = s.next.WriteContext(ctx, data)
+	s.observe("write", start, err)
+
+	return err
+}
+
+func (s *instrumentedStore) observe(operation string, start time.Time, err error) {
+	s.metrics.Duration.Observe(time.Since(start).Seconds(), s.name, operation)
+	if err != nil {
+		s.metrics.Errors.Inc(s.name, operation)
+		return
+	}
+
+	if s.sizer != nil {
+		size, sizeErr := s.sizer.Size()
+		if sizeErr == nil {
+			s.metrics.Bytes.Observe(float64(size), s.name, operation)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+//go:build !windows
+// +build !windows
+
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// ErrLocked is returned by Lock when another process holds the lock.
+var ErrLocked = errors.New("el almacenamiento esta bloqueado por otro proceso")
+
+// Lock takes, without waiting, the exclusive lock of the store kept in name,
+// held on the file name.lock next to it by the processes that write it: the
+// server while it runs and the commands that change the data. The lock is
+// released by unlock or when the process ends, however it ends.
+func Lock(name string) (unlock func() error, err error) {
+	file, err := os.OpenFile(name+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err != nil {
+		file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, fmt.Errorf("%w(%s)", ErrLocked, name)
+		}
+		return nil, err
+	}
+
+	return file.Close, nil
+}
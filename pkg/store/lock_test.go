@@ -0,0 +1,27 @@
+//go:build !windows
+// +build !windows
+
+package store
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLock(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "users.json")
+
+	unlock, err := Lock(name)
+	assert.Nil(t, err)
+
+	// Testea que el bloqueo sea exclusivo hasta que se libere
+	_, err = Lock(name)
+	assert.ErrorIs(t, err, ErrLocked)
+
+	assert.Nil(t, unlock())
+	unlock, err = Lock(name)
+	assert.Nil(t, err)
+	assert.Nil(t, unlock())
+}
@@ -0,0 +1,12 @@
+package store
+
+import "errors"
+
+// ErrLocked is returned by Lock when another process holds the lock.
+var ErrLocked = errors.New("el almacenamiento esta bloqueado por otro proceso")
+
+// Lock does not lock on Windows, where the server and the commands that
+// change the data must not run at the same time.
+func Lock(name string) (unlock func() error, err error) {
+	return func() error { return nil }, nil
+}
@@ -0,0 +1,215 @@
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// NewExporter returns the exporter named by TRACE_EXPORTER: none, stdout or
+// otlp-file, which appends to path. The returned close func releases the
+// file.
+func NewExporter(name string, path string, serviceName string) (exporter Exporter, close func() error, err error) {
+	noClose := func() error { return nil }
+
+	switch strings.ToLower(name) {
+	case "", "none":
+		return nil, noClose, nil
+	case "stdout":
+		return NewStdoutExporter(os.Stdout), noClose, nil
+	case "otlp-file":
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, noClose, err
+		}
+		return NewOTLPFileExporter(file, serviceName), file.Close, nil
+	}
+
+	return nil, noClose, fmt.Errorf("el exportador de trazas no es valido, use none, stdout u otlp-file(recibido: %s)", name)
+}
+
+// StdoutExporter writes a JSON line per span, easy to read and to grep by
+// trace_id.
+type StdoutExporter struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func NewStdoutExporter(out io.Writer) *StdoutExporter {
+	return &StdoutExporter{out: out}
+}
+
+type stdoutSpan struct {
+	TraceID    string                 `json:"trace_id"`
+	SpanID     string                 `json:"span_id"`
+	ParentID   string                 `json:"parent_span_id,omitempty"`
+	Name       string                 `json:"name"`
+	Start      string                 `json:"start"`
+	DurationMs float64                `json:"duration_ms"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+func (e *StdoutExporter) Export(span SpanData) (err error) {
+	line := stdoutSpan{
+		TraceID:    span.SpanContext.TraceID.String(),
+		SpanID:     span.SpanContext.SpanID.String(),
+		Name:       span.Name,
+		Start:      span.Start.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		DurationMs: float64(span.End.Sub(span.Start).Microseconds()) / 1000,
+		Attributes: span.Attributes,
+		Error:      span.StatusMessage,
+	}
+	if span.Parent.IsValid() {
+		line.ParentID = span.Parent.String()
+	}
+
+	encoded, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err = e.out.Write(append(encoded, '\n'))
+
+	return err
+}
+
+// OTLPFileExporter writes each span as a line of OTLP/JSON, an
+// ExportTraceServiceRequest, the format of the file exporter of the
+// OpenTelemetry collector, so the file can be replayed into any backend.
+type OTLPFileExporter struct {
+	mu          sync.Mutex
+	out         io.Writer
+	serviceName string
+}
+
+func NewOTLPFileExporter(out io.Writer, serviceName string) *OTLPFileExporter {
+	return &OTLPFileExporter{out: out, serviceName: serviceName}
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              Kind            `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+// OTLP status codes.
+const (
+	otlpStatusOK    = 1
+	otlpStatusError = 2
+)
+
+func (e *OTLPFileExporter) Export(span SpanData) (err error) {
+	exported := otlpSpan{
+		TraceID:           span.SpanContext.TraceID.String(),
+		SpanID:            span.SpanContext.SpanID.String(),
+		Name:              span.Name,
+		Kind:              span.Kind,
+		StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+		Attributes:        otlpAttributes(span.Attributes),
+		Status:            otlpStatus{Code: otlpStatusOK},
+	}
+	if span.Parent.IsValid() {
+		exported.ParentSpanID = span.Parent.String()
+	}
+	if span.Failed {
+		exported.Status = otlpStatus{Code: otlpStatusError, Message: span.StatusMessage}
+	}
+
+	request := otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: otlpAttributes(map[string]interface{}{"service.name": e.serviceName})},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "github.com/EdigiraldoML/go-web-arquitecture/pkg/trace"},
+			Spans: []otlpSpan{exported},
+		}},
+	}}}
+
+	encoded, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err = e.out.Write(append(encoded, '\n'))
+
+	return err
+}
+
+// otlpAttributes sorts the attributes by key and types their values as OTLP
+// does, 64 bit integers go as strings.
+func otlpAttributes(attributes map[string]interface{}) []otlpAttribute {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	converted := make([]otlpAttribute, 0, len(keys))
+	for _, key := range keys {
+		var value map[string]interface{}
+		switch typed := attributes[key].(type) {
+		case string:
+			value = map[string]interface{}{"stringValue": typed}
+		case bool:
+			value = map[string]interface{}{"boolValue": typed}
+		case int:
+			value = map[string]interface{}{"intValue": strconv.Itoa(typed)}
+		case int64:
+			value = map[string]interface{}{"intValue": strconv.FormatInt(typed, 10)}
+		case float64:
+			value = map[string]interface{}{"doubleValue": typed}
+		default:
+			value = map[string]interface{}{"stringValue": fmt.Sprint(typed)}
+		}
+		converted = append(converted, otlpAttribute{Key: key, Value: value})
+	}
+
+	return converted
+}
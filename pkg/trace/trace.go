@@ -0,0 +1,268 @@
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrInvalidTraceparent = errors.New("el encabezado traceparent no es valido")
+
+type TraceID [16]byte
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+type SpanID [8]byte
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+// SpanContext identifies a span across processes, as carried by the W3C
+// traceparent header.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Traceparent formats the span context as a W3C traceparent header.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID, sc.SpanID, flags)
+}
+
+// ParseTraceparent reads a W3C traceparent header, like
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01.
+func ParseTraceparent(header string) (sc SpanContext, err error) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return sc, fmt.Errorf("%w(recibido: %s)", ErrInvalidTraceparent, header)
+	}
+
+	traceID, errTrace := hex.DecodeString(parts[1])
+	spanID, errSpan := hex.DecodeString(parts[2])
+	flags, errFlags := hex.DecodeString(parts[3])
+	if errTrace != nil || errSpan != nil || errFlags != nil || len(traceID) != 16 || len(spanID) != 8 || len(flags) != 1 {
+		return sc, fmt.Errorf("%w(recibido: %s)", ErrInvalidTraceparent, header)
+	}
+
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Sampled = flags[0]&1 == 1
+	if !sc.IsValid() {
+		return sc, fmt.Errorf("%w(recibido: %s)", ErrInvalidTraceparent, header)
+	}
+
+	return sc, nil
+}
+
+type Kind int
+
+const (
+	KindInternal Kind = iota + 1
+	KindServer
+)
+
+// Span is a timed operation of a trace. A nil Span, returned when there is
+// no tracer, ignores every call.
+type Span struct {
+	tracer *Tracer
+
+	mu   sync.Mutex
+	data SpanData
+	done bool
+}
+
+// SpanData is what the exporters receive of an ended span.
+type SpanData struct {
+	Name          string
+	Kind          Kind
+	SpanContext   SpanContext
+	Parent        SpanID
+	Start         time.Time
+	End           time.Time
+	Attributes    map[string]interface{}
+	Failed        bool
+	StatusMessage string
+}
+
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+
+	return s.data.SpanContext
+}
+
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if !s.done {
+		s.data.Attributes[key] = value
+	}
+	s.mu.Unlock()
+}
+
+// RecordError marks the span as failed, nil errors are ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.data.Failed = true
+	s.data.StatusMessage = err.Error()
+	s.mu.Unlock()
+}
+
+// End ends the span and hands it to the exporter, once.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.done {
+		s.mu.Unlock()
+		return
+	}
+	s.done = true
+	s.data.End = s.tracer.now()
+	data := s.data
+	s.mu.Unlock()
+
+	if data.SpanContext.Sampled {
+		s.tracer.export(data)
+	}
+}
+
+// Exporter sends the ended spans somewhere, like a file.
+type Exporter interface {
+	Export(span SpanData) (err error)
+}
+
+// Tracer starts the root spans of the requests. Child spans are started
+// with Start from the context of their parent.
+type Tracer struct {
+	exporter Exporter
+	onError  func(err error)
+	now      func() time.Time
+}
+
+// NewTracer returns a tracer handing the spans to exporter. onError, when
+// set, receives the errors of the exporter.
+func NewTracer(exporter Exporter, onError func(err error)) *Tracer {
+	return &Tracer{exporter: exporter, onError: onError, now: time.Now}
+}
+
+func (t *Tracer) export(data SpanData) {
+	err := t.exporter.Export(data)
+	if err != nil && t.onError != nil {
+		t.onError(err)
+	}
+}
+
+// StartRoot starts the span of a request, child of remote when the request
+// carried a valid traceparent, or the root of a new trace otherwise.
+func (t *Tracer) StartRoot(ctx context.Context, name string, kind Kind, remote SpanContext) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	sc := SpanContext{TraceID: remote.TraceID, Sampled: true}
+	var parent SpanID
+	if remote.IsValid() {
+		parent = remote.SpanID
+		sc.Sampled = remote.Sampled
+	} else {
+		sc.TraceID = newTraceID()
+	}
+
+	return t.start(ctx, name, kind, sc, parent)
+}
+
+func (t *Tracer) start(ctx context.Context, name string, kind Kind, sc SpanContext, parent SpanID) (context.Context, *Span) {
+	sc.SpanID = newSpanID()
+	span := &Span{
+		tracer: t,
+		data: SpanData{
+			Name:        name,
+			Kind:        kind,
+			SpanContext: sc,
+			Parent:      parent,
+			Start:       t.now(),
+			Attributes:  map[string]interface{}{},
+		},
+	}
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+type spanKey struct{}
+
+// Start starts a child of the span in ctx. Without one there is nothing to
+// trace and the returned span is nil, which ignores every call.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	parent := FromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+
+	sc := SpanContext{TraceID: parent.data.SpanContext.TraceID, Sampled: parent.data.SpanContext.Sampled}
+
+	return parent.tracer.start(ctx, name, KindInternal, sc, parent.data.SpanContext.SpanID)
+}
+
+// FromContext returns the span in ctx, nil when there is none.
+func FromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+
+	span, _ := ctx.Value(spanKey{}).(*Span)
+
+	return span
+}
+
+func newTraceID() (id TraceID) {
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+
+	return id
+}
+
+func newSpanID() (id SpanID) {
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+
+	return id
+}
@@ -0,0 +1,154 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// myExporter keeps the exported spans.
+type myExporter struct {
+	spans []SpanData
+}
+
+func (e *myExporter) Export(span SpanData) (err error) {
+	e.spans = append(e.spans, span)
+	return nil
+}
+
+func TestParseTraceparent(t *testing.T) {
+	// Testea la lectura de un traceparent valido
+	sc, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.Nil(t, err)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+	assert.True(t, sc.Sampled)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", sc.Traceparent())
+
+	// Testea que se acepten versiones futuras con campos extra
+	_, err = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra")
+	assert.Nil(t, err)
+
+	// Testea el rechazo de encabezados invalidos
+	for _, header := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+		"00-zzf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		_, err = ParseTraceparent(header)
+		assert.True(t, errors.Is(err, ErrInvalidTraceparent), header)
+	}
+}
+
+func TestTracer(t *testing.T) {
+	exporter := &myExporter{}
+	tracer := NewTracer(exporter, nil)
+
+	// Testea que sin span en el contexto no se trace nada
+	ctx, span := Start(context.Background(), "huerfano")
+	assert.Nil(t, span)
+	span.SetAttribute("ignorado", true)
+	span.End()
+	assert.Nil(t, FromContext(ctx))
+
+	// Testea que el span de la solicitud continue la traza remota
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx, root := tracer.StartRoot(context.Background(), "GET /v2/users/:id", KindServer, remote)
+	childCtx, child := Start(ctx, "users.Service/GetUserByID")
+	_, grandchild := Start(childCtx, "FileStore.Read")
+	grandchild.RecordError(errors.New("sin disco"))
+	grandchild.End()
+	child.End()
+	root.SetAttribute("http.status_code", 200)
+	root.End()
+	root.End()
+
+	assert.Equal(t, 3, len(exporter.spans))
+	read, service, request := exporter.spans[0], exporter.spans[1], exporter.spans[2]
+	assert.Equal(t, remote.TraceID, request.SpanContext.TraceID)
+	assert.Equal(t, remote.SpanID, request.Parent)
+	assert.Equal(t, KindServer, request.Kind)
+	assert.Equal(t, 200, request.Attributes["http.status_code"])
+	assert.Equal(t, remote.TraceID, service.SpanContext.TraceID)
+	assert.Equal(t, request.SpanContext.SpanID, service.Parent)
+	assert.Equal(t, service.SpanContext.SpanID, read.Parent)
+	assert.True(t, read.Failed)
+	assert.Equal(t, "sin disco", read.StatusMessage)
+
+	// Testea que sin traceparent se inicie una traza nueva
+	_, root = tracer.StartRoot(context.Background(), "GET /v2/users", KindServer, SpanContext{})
+	assert.True(t, root.SpanContext().IsValid())
+	assert.NotEqual(t, remote.TraceID, root.SpanContext().TraceID)
+
+	// Testea que las trazas no muestreadas no se exporten
+	exporter.spans = nil
+	remote.Sampled = false
+	ctx, root = tracer.StartRoot(context.Background(), "GET /v2/users", KindServer, remote)
+	_, child = Start(ctx, "users.Service/Filter")
+	child.End()
+	root.End()
+	assert.Equal(t, 0, len(exporter.spans))
+
+	// Testea que un tracer nil no trace nada
+	var noTracer *Tracer
+	ctx, root = noTracer.StartRoot(context.Background(), "GET /v2/users", KindServer, SpanContext{})
+	assert.Nil(t, root)
+	assert.Nil(t, FromContext(ctx))
+}
+
+func TestOTLPFileExporter(t *testing.T) {
+	start := time.Date(2021, 12, 22, 10, 0, 0, 0, time.UTC)
+	var out bytes.Buffer
+	exporter := NewOTLPFileExporter(&out, "users")
+
+	sc, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	err := exporter.Export(SpanData{
+		Name:          "FileStore.Write",
+		Kind:          KindInternal,
+		SpanContext:   sc,
+		Parent:        SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		Start:         start,
+		End:           start.Add(time.Millisecond),
+		Attributes:    map[string]interface{}{"file": "users.json", "bytes": 42},
+		Failed:        true,
+		StatusMessage: "sin disco",
+	})
+	assert.Nil(t, err)
+
+	// Testea que cada span sea una linea de OTLP/JSON
+	var line map[string]interface{}
+	err = json.Unmarshal(out.Bytes(), &line)
+	assert.Nil(t, err)
+	assert.Equal(t, byte('\n'), out.Bytes()[out.Len()-1])
+
+	resourceSpans := line["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"attributes": []interface{}{
+		map[string]interface{}{"key": "service.name", "value": map[string]interface{}{"stringValue": "users"}},
+	}}, resourceSpans["resource"])
+
+	span := resourceSpans["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})[0]
+	assert.Equal(t, map[string]interface{}{
+		"traceId":           "4bf92f3577b34da6a3ce929d0e0e4736",
+		"spanId":            "00f067aa0ba902b7",
+		"parentSpanId":      "0102030405060708",
+		"name":              "FileStore.Write",
+		"kind":              float64(1),
+		"startTimeUnixNano": "1640167200000000000",
+		"endTimeUnixNano":   "1640167200001000000",
+		"attributes": []interface{}{
+			map[string]interface{}{"key": "bytes", "value": map[string]interface{}{"intValue": "42"}},
+			map[string]interface{}{"key": "file", "value": map[string]interface{}{"stringValue": "users.json"}},
+		},
+		"status": map[string]interface{}{"code": float64(2), "message": "sin disco"},
+	}, span)
+}
@@ -0,0 +1,67 @@
+package web
+
+// BatchResult is the outcome of the operation at Index, with the status it
+// would have had as a single request.
+type BatchResult struct {
+	Index  int         `json:"index" xml:"index" yaml:"index"`
+	Op     string      `json:"op" xml:"op" yaml:"op"`
+	ID     int64       `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
+	Status int         `json:"status" xml:"status" yaml:"status"`
+	Data   interface{} `json:"data,omitempty" xml:"data,omitempty" yaml:"data,omitempty"`
+	Error  string      `json:"error,omitempty" xml:"error,omitempty" yaml:"error,omitempty"`
+}
+
+type BatchResults struct {
+	Results []BatchResult `json:"results" xml:"result" yaml:"results"`
+}
+
+// FieldChange is the value of a field before and after a bulk change.
+type FieldChange struct {
+	Field string      `json:"field" xml:"field" yaml:"field"`
+	From  interface{} `json:"from" xml:"from" yaml:"from"`
+	To    interface{} `json:"to" xml:"to" yaml:"to"`
+}
+
+// BulkChange lists the fields changed on a user, Deleted users list none.
+type BulkChange struct {
+	ID      int64         `json:"id" xml:"id" yaml:"id"`
+	Deleted bool          `json:"deleted,omitempty" xml:"deleted,omitempty" yaml:"deleted,omitempty"`
+	Changes []FieldChange `json:"changes,omitempty" xml:"change,omitempty" yaml:"changes,omitempty"`
+}
+
+// BulkResult is the response of the updates and deletes by filter.
+type BulkResult struct {
+	DryRun  bool         `json:"dry_run" xml:"dry_run" yaml:"dry_run"`
+	Matched int          `json:"matched" xml:"matched" yaml:"matched"`
+	IDs     []int64      `json:"ids" xml:"id" yaml:"ids"`
+	Changes []BulkChange `json:"changes" xml:"change" yaml:"changes"`
+}
+
+// ImportedRow is a row saved by an import.
+type ImportedRow struct {
+	Line   int    `json:"line" xml:"line" yaml:"line"`
+	ID     int64  `json:"id" xml:"id" yaml:"id"`
+	Action string `json:"action" xml:"action" yaml:"action"`
+}
+
+// RowError is a row rejected by an import, with the line it starts on.
+type RowError struct {
+	Line  int    `json:"line" xml:"line" yaml:"line"`
+	Error string `json:"error" xml:"error" yaml:"error"`
+}
+
+// ImportReport is the response of an import. Applied is false when nothing
+// was saved.
+type ImportReport struct {
+	Total    int           `json:"total" xml:"total" yaml:"total"`
+	Applied  bool          `json:"applied" xml:"applied" yaml:"applied"`
+	Accepted []ImportedRow `json:"accepted" xml:"accepted" yaml:"accepted"`
+	Errors   []RowError    `json:"errors" xml:"error" yaml:"errors"`
+}
+
+// ImportProblem is the problem of an import that saved nothing, the report
+// goes along as an extension member.
+type ImportProblem struct {
+	Problem
+	Report ImportReport `json:"report"`
+}
@@ -0,0 +1,264 @@
+package web
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+// CSV binds request bodies made of a header row and a single record.
+var CSV = csvBinding{}
+
+type csvBinding struct{}
+
+func (csvBinding) Name() string {
+	return "csv"
+}
+
+func (b csvBinding) Bind(req *http.Request, obj interface{}) error {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+
+	return b.BindBody(body, obj)
+}
+
+func (csvBinding) BindBody(body []byte, obj interface{}) error {
+	reader := csv.NewReader(bytes.NewReader(body))
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("el cuerpo csv debe tener una fila de encabezado: %w", err)
+	}
+
+	record, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("el cuerpo csv debe tener una fila de datos: %w", err)
+	}
+
+	err = DecodeCSVRecord(header, record, obj)
+	if err != nil {
+		return err
+	}
+
+	if binding.Validator == nil {
+		return nil
+	}
+
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// MarshalCSV writes a header row with the json names of the struct fields and
+// one row per element. It accepts a struct, a slice of structs or a struct
+// wrapping a single slice of structs (like users.Users).
+func MarshalCSV(v interface{}) (data []byte, err error) {
+	rows := reflect.Indirect(reflect.ValueOf(v))
+	if rows.Kind() == reflect.Struct {
+		if inner, ok := singleSliceField(rows); ok {
+			rows = inner
+		}
+	}
+
+	var elemType reflect.Type
+	switch rows.Kind() {
+	case reflect.Struct:
+		elemType = rows.Type()
+	case reflect.Slice, reflect.Array:
+		elemType = rows.Type().Elem()
+		if elemType.Kind() == reflect.Ptr {
+			elemType = elemType.Elem()
+		}
+	}
+	if elemType == nil || elemType.Kind() != reflect.Struct {
+		return nil, ErrNotAcceptable
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	err = writer.Write(CSVHeader(elemType))
+	if err != nil {
+		return nil, err
+	}
+
+	writeRow := func(row reflect.Value) error {
+		row = reflect.Indirect(row)
+		if !row.IsValid() {
+			return nil
+		}
+
+		return writer.Write(csvRecord(row))
+	}
+
+	if rows.Kind() == reflect.Struct {
+		err = writeRow(rows)
+	} else {
+		for idx := 0; idx < rows.Len() && err == nil; idx++ {
+			err = writeRow(rows.Index(idx))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	writer.Flush()
+
+	return buf.Bytes(), writer.Error()
+}
+
+// CSVHeader returns the column names used for a struct type.
+func CSVHeader(t reflect.Type) (header []string) {
+	for idx := 0; idx < t.NumField(); idx++ {
+		if name, ok := csvFieldName(t.Field(idx)); ok {
+			header = append(header, name)
+		}
+	}
+
+	return header
+}
+
+// DecodeCSVRecord fills the fields of obj whose json name appears in the header.
+func DecodeCSVRecord(header []string, record []string, obj interface{}) error {
+	value := reflect.ValueOf(obj)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("no se puede decodificar csv en %T", obj)
+	}
+	value = value.Elem()
+
+	columns := map[string]int{}
+	for idx, name := range header {
+		columns[strings.TrimSpace(name)] = idx
+	}
+
+	for idx := 0; idx < value.NumField(); idx++ {
+		name, ok := csvFieldName(value.Type().Field(idx))
+		if !ok {
+			continue
+		}
+
+		column, found := columns[name]
+		if !found || column >= len(record) {
+			continue
+		}
+
+		err := setCSVValue(value.Field(idx), strings.TrimSpace(record[column]))
+		if err != nil {
+			return fmt.Errorf("valor invalido para %s(recibido: %s): %w", name, record[column], err)
+		}
+	}
+
+	return nil
+}
+
+func csvFieldName(field reflect.StructField) (name string, ok bool) {
+	if field.PkgPath != "" {
+		return "", false
+	}
+
+	name = strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	if name == "-" {
+		return "", false
+	}
+	if name == "" {
+		name = field.Name
+	}
+
+	return name, true
+}
+
+func singleSliceField(v reflect.Value) (inner reflect.Value, ok bool) {
+	if v.NumField() != 1 || v.Field(0).Kind() != reflect.Slice {
+		return inner, false
+	}
+
+	return v.Field(0), true
+}
+
+func csvRecord(row reflect.Value) (record []string) {
+	for idx := 0; idx < row.NumField(); idx++ {
+		if _, ok := csvFieldName(row.Type().Field(idx)); !ok {
+			continue
+		}
+
+		record = append(record, formatCSVValue(row.Field(idx)))
+	}
+
+	return record
+}
+
+func formatCSVValue(field reflect.Value) string {
+	if (field.Kind() == reflect.Ptr || field.Kind() == reflect.Interface) && field.IsNil() {
+		return ""
+	}
+
+	if field.CanInterface() {
+		if marshaler, ok := field.Interface().(encoding.TextMarshaler); ok {
+			text, err := marshaler.MarshalText()
+			if err == nil {
+				return string(text)
+			}
+		}
+	}
+
+	switch field.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return formatCSVValue(field.Elem())
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(field.Float(), 'f', -1, 64)
+	}
+
+	return fmt.Sprint(field.Interface())
+}
+
+func setCSVValue(field reflect.Value, text string) (err error) {
+	if field.CanAddr() {
+		if unmarshaler, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			if text == "" {
+				return nil
+			}
+			return unmarshaler.UnmarshalText([]byte(text))
+		}
+	}
+
+	if text == "" {
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(text)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var number int64
+		number, err = strconv.ParseInt(text, 10, 64)
+		field.SetInt(number)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var number uint64
+		number, err = strconv.ParseUint(text, 10, 64)
+		field.SetUint(number)
+	case reflect.Float32, reflect.Float64:
+		var number float64
+		number, err = strconv.ParseFloat(text, 64)
+		field.SetFloat(number)
+	case reflect.Bool:
+		var flag bool
+		flag, err = strconv.ParseBool(text)
+		field.SetBool(flag)
+	case reflect.Ptr:
+		ptr := reflect.New(field.Type().Elem())
+		err = setCSVValue(ptr.Elem(), text)
+		field.Set(ptr)
+	default:
+		err = fmt.Errorf("tipo %s no soportado en csv", field.Type())
+	}
+
+	return err
+}
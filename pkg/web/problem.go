@@ -0,0 +1,73 @@
+package web
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const (
+	ProblemMediaType = "application/problem+json"
+
+	// ProblemTypeBase prefixes the type URI of every problem, each type
+	// documents one kind of error.
+	ProblemTypeBase = "/problems/"
+)
+
+// Problem is an RFC 7807 problem details document.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes why a single field of the request was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+var problemTypes = map[int]string{
+	http.StatusBadRequest:            "bad-request",
+	http.StatusForbidden:             "forbidden",
+	http.StatusNotFound:              "not-found",
+	http.StatusMethodNotAllowed:      "method-not-allowed",
+	http.StatusNotAcceptable:         "not-acceptable",
+	http.StatusConflict:              "conflict",
+	http.StatusGone:                  "gone",
+	http.StatusPreconditionFailed:    "precondition-failed",
+	http.StatusRequestEntityTooLarge: "payload-too-large",
+	http.StatusUnsupportedMediaType:  "unsupported-media-type",
+	http.StatusUnprocessableEntity:   "validation-error",
+	http.StatusPreconditionRequired:  "precondition-required",
+	http.StatusTooManyRequests:       "rate-limited",
+	http.StatusServiceUnavailable:    "unavailable",
+}
+
+func NewProblem(code int, detail string, instance string) (p Problem) {
+	p.Type = "about:blank"
+	if slug, ok := problemTypes[code]; ok {
+		p.Type = ProblemTypeBase + slug
+	}
+	p.Title = http.StatusText(code)
+	p.Status = code
+	p.Detail = detail
+	p.Instance = instance
+
+	return p
+}
+
+// AcceptsProblem reports whether the Accept header explicitly asks for problem+json.
+func AcceptsProblem(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err == nil && mediaType == ProblemMediaType {
+			return true
+		}
+	}
+
+	return false
+}
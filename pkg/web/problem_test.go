@@ -0,0 +1,31 @@
+package web
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewProblem(t *testing.T) {
+	expectedProblem := Problem{
+		Type:     "/problems/not-found",
+		Title:    "Not Found",
+		Status:   404,
+		Detail:   "el usuario no fue encontrado",
+		Instance: "/users/7",
+	}
+
+	problem := NewProblem(404, "el usuario no fue encontrado", "/users/7")
+	assert.Equal(t, expectedProblem, problem)
+
+	// Testea un codigo sin tipo documentado
+	problem = NewProblem(418, "", "")
+	assert.Equal(t, "about:blank", problem.Type)
+}
+
+func TestAcceptsProblem(t *testing.T) {
+	assert.True(t, AcceptsProblem("application/problem+json"))
+	assert.True(t, AcceptsProblem("application/json, application/problem+json;q=0.9"))
+	assert.False(t, AcceptsProblem("application/json"))
+	assert.False(t, AcceptsProblem(""))
+}
@@ -0,0 +1,186 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/ugorji/go/codec"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	MIMEJSON    = "application/json"
+	MIMEXML     = "application/xml"
+	MIMEYAML    = "application/yaml"
+	MIMECSV     = "text/csv"
+	MIMEMsgPack = "application/msgpack"
+)
+
+var ErrNotAcceptable = errors.New("ninguno de los formatos solicitados en Accept es soportado")
+
+var (
+	// ListFormats are offered by routes returning lists of records.
+	ListFormats = []string{MIMEJSON, MIMEXML, MIMEYAML, MIMECSV, MIMEMsgPack}
+	// RecordFormats are offered by every other route, CSV only makes sense for lists.
+	RecordFormats = []string{MIMEJSON, MIMEXML, MIMEYAML, MIMEMsgPack}
+)
+
+// formatAliases maps the other media types clients use for the same formats.
+var formatAliases = map[string]string{
+	"application/problem+json": MIMEJSON,
+	"text/xml":                 MIMEXML,
+	"application/x-yaml":       MIMEYAML,
+	"text/yaml":                MIMEYAML,
+	"application/x-msgpack":    MIMEMsgPack,
+}
+
+type acceptedRange struct {
+	mediaType   string
+	quality     float64
+	specificity int
+}
+
+// NegotiateFormat picks the offered format that best matches the Accept header.
+// An empty header means the client accepts anything and gets the first offer.
+func NegotiateFormat(accept string, offered []string) (format string, err error) {
+	if strings.TrimSpace(accept) == "" {
+		return offered[0], nil
+	}
+
+	var ranges []acceptedRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			quality, err = strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+		}
+		if quality <= 0 {
+			continue
+		}
+
+		specificity := 2
+		if mediaType == "*/*" {
+			specificity = 0
+		} else if strings.HasSuffix(mediaType, "/*") {
+			specificity = 1
+		}
+
+		ranges = append(ranges, acceptedRange{mediaType: mediaType, quality: quality, specificity: specificity})
+	}
+
+	// Higher quality first and, on ties, the most specific range first.
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].quality != ranges[j].quality {
+			return ranges[i].quality > ranges[j].quality
+		}
+
+		return ranges[i].specificity > ranges[j].specificity
+	})
+
+	for _, r := range ranges {
+		mediaType := r.mediaType
+		if alias, ok := formatAliases[mediaType]; ok {
+			mediaType = alias
+		}
+
+		for _, candidate := range offered {
+			if matchMediaRange(mediaType, candidate) {
+				return candidate, nil
+			}
+		}
+	}
+
+	return format, ErrNotAcceptable
+}
+
+func matchMediaRange(mediaRange string, offered string) bool {
+	if mediaRange == "*/*" || mediaRange == offered {
+		return true
+	}
+
+	if strings.HasSuffix(mediaRange, "/*") {
+		return strings.HasPrefix(offered, strings.TrimSuffix(mediaRange, "*"))
+	}
+
+	return false
+}
+
+// Marshal encodes the value in the given format. CSV only encodes structs and
+// lists of structs, other values return ErrNotAcceptable.
+func Marshal(format string, v interface{}) (data []byte, err error) {
+	switch format {
+	case MIMEXML:
+		var buf bytes.Buffer
+		buf.WriteString(xml.Header)
+		err = xml.NewEncoder(&buf).EncodeElement(v, xml.StartElement{Name: xml.Name{Local: "response"}})
+		return buf.Bytes(), err
+	case MIMEYAML:
+		return yaml.Marshal(v)
+	case MIMECSV:
+		return MarshalCSV(v)
+	case MIMEJSON:
+		return json.Marshal(v)
+	case MIMEMsgPack:
+		err = codec.NewEncoderBytes(&data, &codec.MsgpackHandle{}).Encode(v)
+		return data, err
+	}
+
+	return nil, ErrNotAcceptable
+}
+
+// ContentType returns the Content-Type header sent for a format.
+func ContentType(format string) string {
+	if format == MIMEMsgPack {
+		return format
+	}
+
+	return format + "; charset=utf-8"
+}
+
+// BodyBinding returns the binding that decodes request bodies sent with the
+// given content type, defaulting to JSON.
+func BodyBinding(contentType string) binding.BindingBody {
+	if alias, ok := formatAliases[contentType]; ok {
+		contentType = alias
+	}
+
+	switch contentType {
+	case MIMEXML:
+		return binding.XML
+	case MIMEYAML:
+		return binding.YAML
+	case MIMECSV:
+		return CSV
+	case MIMEMsgPack:
+		return binding.MsgPack
+	}
+
+	return binding.JSON
+}
+
+// BodyMediaTypes lists the content types accepted on request bodies.
+func BodyMediaTypes() (mediaTypes []string) {
+	mediaTypes = append(mediaTypes, ListFormats...)
+	for alias, format := range formatAliases {
+		if format != MIMEJSON {
+			mediaTypes = append(mediaTypes, alias)
+		}
+	}
+	sort.Strings(mediaTypes)
+
+	return mediaTypes
+}
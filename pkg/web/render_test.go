@@ -0,0 +1,58 @@
+package web
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type csvRow struct {
+	Id     int64   `json:"id"`
+	Nombre string  `json:"nombre"`
+	Altura float64 `json:"altura"`
+	Activo bool    `json:"activo"`
+}
+
+type csvRows struct {
+	Rows []csvRow `json:"rows"`
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	format, err := NegotiateFormat("", ListFormats)
+	assert.Nil(t, err)
+	assert.Equal(t, MIMEJSON, format)
+
+	format, err = NegotiateFormat("text/csv;q=0.4, application/x-yaml;q=0.8", ListFormats)
+	assert.Nil(t, err)
+	assert.Equal(t, MIMEYAML, format)
+
+	format, err = NegotiateFormat("*/*, application/xml", ListFormats)
+	assert.Nil(t, err)
+	assert.Equal(t, MIMEXML, format)
+
+	// Testea que csv no se ofrezca en las rutas de un solo registro
+	_, err = NegotiateFormat("text/csv", RecordFormats)
+	assert.Equal(t, ErrNotAcceptable, err)
+}
+
+func TestMarshalCSV(t *testing.T) {
+	rows := &csvRows{Rows: []csvRow{{1, "user1", 1.8, true}, {2, "user, two", 1.65, false}}}
+	expectedCSV := "id,nombre,altura,activo\n1,user1,1.8,true\n2,\"user, two\",1.65,false\n"
+
+	data, err := MarshalCSV(rows)
+	assert.Nil(t, err)
+	assert.Equal(t, expectedCSV, string(data))
+
+	_, err = MarshalCSV("el usuario fue eliminado satisfactoriamente")
+	assert.Equal(t, ErrNotAcceptable, err)
+}
+
+func TestDecodeCSVRecord(t *testing.T) {
+	var row csvRow
+	err := DecodeCSVRecord([]string{"nombre", "activo", "altura"}, []string{"user1", "true", "1.8"}, &row)
+	assert.Nil(t, err)
+	assert.Equal(t, csvRow{Nombre: "user1", Altura: 1.8, Activo: true}, row)
+
+	err = DecodeCSVRecord([]string{"id"}, []string{"uno"}, &row)
+	assert.Error(t, err)
+}
@@ -3,9 +3,9 @@ package web
 import "strconv"
 
 type Response struct {
-	Code  string      `json:"code"`
-	Data  interface{} `json:"data,omitempty"`
-	Error string      `json:"error,omitempty"`
+	Code  string      `json:"code" xml:"code" yaml:"code"`
+	Data  interface{} `json:"data,omitempty" xml:"data,omitempty" yaml:"data,omitempty"`
+	Error string      `json:"error,omitempty" xml:"error,omitempty" yaml:"error,omitempty"`
 }
 
 func NewResponse(code int, data interface{}, err string) (r Response) {
@@ -0,0 +1,141 @@
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+const (
+	MIMENDJSON = "application/x-ndjson"
+	MIMEJSONL  = "application/jsonl"
+)
+
+// RowMediaTypes are the formats read by DecodeRows.
+var RowMediaTypes = []string{MIMECSV, MIMENDJSON}
+
+// IsRowMediaType tells whether DecodeRows reads the media type.
+func IsRowMediaType(mediaType string) bool {
+	return mediaType == MIMECSV || mediaType == MIMENDJSON || mediaType == MIMEJSONL
+}
+
+var ErrUnknownColumns = errors.New("el encabezado tiene columnas desconocidas")
+
+// RowFunc receives each row read by DecodeRows with the line it starts on.
+// err is set when the row could not be decoded into value.
+type RowFunc func(line int, value interface{}, err error)
+
+// DecodeRows reads a CSV body (header row plus one row per record) or an
+// NDJSON body (one JSON object per line) and decodes every row into a new
+// value returned by newValue. mapping renames CSV header columns to the json
+// names of the fields, columns that match no field are rejected.
+func DecodeRows(mediaType string, r io.Reader, mapping map[string]string, newValue func() interface{}, visit RowFunc) (err error) {
+	switch mediaType {
+	case MIMECSV:
+		return decodeCSVRows(r, mapping, newValue, visit)
+	case MIMENDJSON, MIMEJSONL:
+		return decodeNDJSONRows(r, newValue, visit)
+	}
+
+	return fmt.Errorf("el tipo de contenido %s no es soportado, use %s", mediaType, strings.Join(RowMediaTypes, " o "))
+}
+
+func decodeCSVRows(r io.Reader, mapping map[string]string, newValue func() interface{}, visit RowFunc) (err error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("el cuerpo csv debe tener una fila de encabezado: %w", err)
+	}
+
+	known := map[string]bool{}
+	for _, name := range CSVHeader(reflect.TypeOf(newValue()).Elem()) {
+		known[name] = true
+	}
+
+	var unknown []string
+	for idx, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		if mapped, ok := mapping[name]; ok {
+			name = mapped
+		}
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+		header[idx] = name
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownColumns, strings.Join(unknown, ", "))
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			visit(parseErr.StartLine, nil, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		value := newValue()
+		err = DecodeCSVRecord(header, record, value)
+		visit(line, value, err)
+	}
+}
+
+func decodeNDJSONRows(r io.Reader, newValue func() interface{}, visit RowFunc) (err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+
+		value := newValue()
+		err = decoder.Decode(value)
+		visit(line, value, err)
+	}
+
+	return scanner.Err()
+}
+
+// ParseHeaderMapping parses mappings like "First Name=nombre,Surname=apellido".
+func ParseHeaderMapping(value string) (mapping map[string]string, err error) {
+	mapping = map[string]string{}
+	if strings.TrimSpace(value) == "" {
+		return mapping, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("el mapeo de columnas debe tener la forma columna=campo(recibido: %s)", pair)
+		}
+		mapping[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	return mapping, nil
+}
@@ -0,0 +1,53 @@
+package web
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type rowUser struct {
+	Nombre string  `json:"nombre"`
+	Edad   int64   `json:"edad"`
+	Altura float64 `json:"altura"`
+}
+
+func TestDecodeRows(t *testing.T) {
+	type row struct {
+		Line  int
+		Value *rowUser
+		Err   bool
+	}
+	var rows []row
+	visit := func(line int, value interface{}, err error) {
+		user, _ := value.(*rowUser)
+		rows = append(rows, row{Line: line, Value: user, Err: err != nil})
+	}
+	newValue := func() interface{} {
+		return &rowUser{}
+	}
+
+	// Testea el mapeo de columnas y los numeros de linea
+	body := "Nombre Completo,edad,altura\nuser1,25,1.8\n\nuser2,x,1.6\n\"user\n3\",30,1.7\n"
+	err := DecodeRows(MIMECSV, strings.NewReader(body), map[string]string{"Nombre Completo": "nombre"}, newValue, visit)
+	assert.Nil(t, err)
+	assert.Equal(t, []row{
+		{Line: 2, Value: &rowUser{Nombre: "user1", Edad: 25, Altura: 1.8}},
+		{Line: 4, Value: &rowUser{Nombre: "user2"}, Err: true},
+		{Line: 5, Value: &rowUser{Nombre: "user\n3", Edad: 30, Altura: 1.7}},
+	}, rows)
+
+	// Testea columnas desconocidas
+	err = DecodeRows(MIMECSV, strings.NewReader("nombre,apodo\n"), nil, newValue, visit)
+	assert.ErrorIs(t, err, ErrUnknownColumns)
+
+	rows = nil
+	body = "{\"nombre\":\"user1\",\"edad\":25}\n\n{\"nombre\":\"user2\",\"apodo\":\"x\"}\n"
+	err = DecodeRows(MIMENDJSON, strings.NewReader(body), nil, newValue, visit)
+	assert.Nil(t, err)
+	assert.Len(t, rows, 2)
+	assert.Equal(t, row{Line: 1, Value: &rowUser{Nombre: "user1", Edad: 25}}, rows[0])
+	assert.Equal(t, 3, rows[1].Line)
+	assert.True(t, rows[1].Err)
+}
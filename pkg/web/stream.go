@@ -0,0 +1,211 @@
+package web
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// StreamFormats are the formats written by RowEncoder, keyed by the name used
+// in the format query param.
+var StreamFormats = map[string]string{
+	"csv":    MIMECSV,
+	"ndjson": MIMENDJSON,
+	"json":   MIMEJSON,
+}
+
+var ErrUnknownFields = errors.New("los campos solicitados no existen")
+
+// RowEncoder writes one struct at a time as CSV rows, NDJSON lines or the
+// elements of a JSON array, so that lists are never marshaled in one piece.
+// Only the selected fields are written, in the order of the struct.
+type RowEncoder struct {
+	w         io.Writer
+	mediaType string
+	fields    []string
+	csv       *csv.Writer
+	rows      int
+}
+
+// NewRowEncoder returns an encoder for values of type t. fields are json
+// names of t, all of them are written when it is empty.
+func NewRowEncoder(w io.Writer, mediaType string, t reflect.Type, fields []string) (encoder *RowEncoder, err error) {
+	header := CSVHeader(t)
+
+	selected := map[string]bool{}
+	var unknown []string
+	for _, field := range fields {
+		selected[field] = true
+		if !contains(header, field) {
+			unknown = append(unknown, field)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("%w: %s, use %s", ErrUnknownFields, strings.Join(unknown, ", "), strings.Join(header, ", "))
+	}
+
+	encoder = &RowEncoder{w: w, mediaType: mediaType}
+	for _, name := range header {
+		if len(fields) == 0 || selected[name] {
+			encoder.fields = append(encoder.fields, name)
+		}
+	}
+
+	switch mediaType {
+	case MIMECSV:
+		encoder.csv = csv.NewWriter(w)
+	case MIMENDJSON, MIMEJSON:
+	default:
+		return nil, ErrNotAcceptable
+	}
+
+	return encoder, nil
+}
+
+// Encode writes a value, the CSV header or the opening bracket of the JSON
+// array go out before the first one.
+func (e *RowEncoder) Encode(v interface{}) (err error) {
+	row := reflect.Indirect(reflect.ValueOf(v))
+	if row.Kind() != reflect.Struct {
+		return ErrNotAcceptable
+	}
+
+	switch e.mediaType {
+	case MIMECSV:
+		if e.rows == 0 {
+			err = e.csv.Write(e.fields)
+			if err != nil {
+				return err
+			}
+		}
+		err = e.csv.Write(e.csvRecord(row))
+	case MIMENDJSON:
+		err = e.writeJSON(row, "", "\n")
+	case MIMEJSON:
+		separator := ","
+		if e.rows == 0 {
+			separator = "["
+		}
+		err = e.writeJSON(row, separator, "")
+	}
+	if err != nil {
+		return err
+	}
+
+	e.rows++
+
+	return nil
+}
+
+// Flush writes the buffered CSV rows.
+func (e *RowEncoder) Flush() (err error) {
+	if e.csv != nil {
+		e.csv.Flush()
+		return e.csv.Error()
+	}
+
+	return nil
+}
+
+// Close writes what is left: the header of an empty CSV or the end of the
+// JSON array.
+func (e *RowEncoder) Close() (err error) {
+	switch e.mediaType {
+	case MIMECSV:
+		if e.rows == 0 {
+			err = e.csv.Write(e.fields)
+			if err != nil {
+				return err
+			}
+		}
+	case MIMEJSON:
+		closing := "]"
+		if e.rows == 0 {
+			closing = "[]"
+		}
+		_, err = io.WriteString(e.w, closing)
+		if err != nil {
+			return err
+		}
+	}
+
+	return e.Flush()
+}
+
+func (e *RowEncoder) csvRecord(row reflect.Value) (record []string) {
+	values := fieldValues(row)
+	for _, name := range e.fields {
+		record = append(record, formatCSVValue(values[name]))
+	}
+
+	return record
+}
+
+// writeJSON writes the selected fields as a JSON object, keeping the order
+// of the struct.
+func (e *RowEncoder) writeJSON(row reflect.Value, prefix string, suffix string) (err error) {
+	var buf bytes.Buffer
+	buf.WriteString(prefix)
+	buf.WriteByte('{')
+
+	values := fieldValues(row)
+	for idx, name := range e.fields {
+		if idx > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, _ := json.Marshal(name)
+		value, err := json.Marshal(values[name].Interface())
+		if err != nil {
+			return err
+		}
+
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+
+	buf.WriteByte('}')
+	buf.WriteString(suffix)
+
+	_, err = e.w.Write(buf.Bytes())
+
+	return err
+}
+
+func fieldValues(row reflect.Value) (values map[string]reflect.Value) {
+	values = map[string]reflect.Value{}
+	for idx := 0; idx < row.NumField(); idx++ {
+		if name, ok := csvFieldName(row.Type().Field(idx)); ok {
+			values[name] = row.Field(idx)
+		}
+	}
+
+	return values
+}
+
+// ParseFields parses field selections like "id,email,nombre".
+func ParseFields(value string) (fields []string) {
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+
+	return fields
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,34 @@
+package web
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRowEncoder(t *testing.T) {
+	rows := []rowUser{{Nombre: "user1", Edad: 25, Altura: 1.8}, {Nombre: "user, 2", Edad: 30, Altura: 1.6}}
+	rowType := reflect.TypeOf(rowUser{})
+
+	encode := func(mediaType string, fields []string, rows []rowUser) string {
+		var buf bytes.Buffer
+		encoder, err := NewRowEncoder(&buf, mediaType, rowType, fields)
+		assert.Nil(t, err)
+		for _, row := range rows {
+			assert.Nil(t, encoder.Encode(row))
+		}
+		assert.Nil(t, encoder.Close())
+		return buf.String()
+	}
+
+	assert.Equal(t, "nombre,edad,altura\nuser1,25,1.8\n\"user, 2\",30,1.6\n", encode(MIMECSV, nil, rows))
+	assert.Equal(t, "nombre,altura\n", encode(MIMECSV, []string{"altura", "nombre"}, nil))
+	assert.Equal(t, "{\"nombre\":\"user1\",\"altura\":1.8}\n{\"nombre\":\"user, 2\",\"altura\":1.6}\n", encode(MIMENDJSON, []string{"altura", "nombre"}, rows))
+	assert.Equal(t, "[{\"edad\":25},{\"edad\":30}]", encode(MIMEJSON, []string{"edad"}, rows))
+	assert.Equal(t, "[]", encode(MIMEJSON, nil, nil))
+
+	_, err := NewRowEncoder(&bytes.Buffer{}, MIMECSV, rowType, []string{"nombre", "apodo"})
+	assert.ErrorIs(t, err, ErrUnknownFields)
+}
@@ -0,0 +1,51 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// UseJSONFieldNames makes the binding validator report fields by their json
+// name ("nombre") instead of the Go one ("Nombre").
+func UseJSONFieldNames() {
+	engine, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	engine.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+
+		return name
+	})
+}
+
+// FieldErrors extracts the field level problems from a binding error.
+func FieldErrors(err error) (fieldErrors []FieldError) {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return nil
+	}
+
+	for _, fe := range validationErrors {
+		message := fmt.Sprintf("no cumple la validacion %s", fe.Tag())
+		if fe.Tag() == "required" {
+			message = "el campo es requerido"
+		}
+
+		fieldErrors = append(fieldErrors, FieldError{Field: fe.Field(), Message: message})
+	}
+
+	return fieldErrors
+}